@@ -0,0 +1,149 @@
+// Command arena plays a match between two registered bots and reports which
+// is stronger.
+//
+// Usage:
+//
+//	go run ./cmd/arena -a BOT_EXPERT -b BOT_MCTS -games 200 -records games.txt
+//
+// Bots are looked up in the default registry plus any engines listed in the
+// EXTERNAL_ENGINES environment variable, as for the server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/arena"
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	nameA := flag.String("a", bot.NameFor(bot.Expert), "first bot")
+	nameB := flag.String("b", bot.NameFor(bot.MCTS), "second bot")
+	games := flag.Int("games", 100, "number of games")
+	opening := flag.Int("opening", 2, "random plies at the start of each game")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "games played at once")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "random seed")
+	rows := flag.Int("rows", game.Rows, "board rows")
+	columns := flag.Int("columns", game.Columns, "board columns")
+	connect := flag.Int("connect", game.ConnectLength, "discs in a row needed to win")
+	popOut := flag.Bool("popout", false, "play the PopOut variant")
+	recordsPath := flag.String("records", "", "write game records to this file")
+	lossesOnly := flag.Bool("losses", false, "only record games the first bot lost")
+	elo0 := flag.Float64("elo0", 0, "SPRT null hypothesis Elo difference")
+	elo1 := flag.Float64("elo1", 10, "SPRT alternative hypothesis Elo difference")
+	alpha := flag.Float64("alpha", 0.05, "SPRT false positive rate")
+	beta := flag.Float64("beta", 0.05, "SPRT false negative rate")
+	flag.Parse()
+
+	bots := bot.DefaultRegistry()
+	engines, err := bot.RegisterEngines(bots, os.Getenv("EXTERNAL_ENGINES"))
+	if err != nil {
+		log.Fatalf("external engines: %v", err)
+	}
+	defer func() {
+		for _, engine := range engines {
+			_ = engine.Close()
+		}
+	}()
+
+	a, err := lookup(bots, *nameA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := lookup(bots, *nameB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	records := io.Discard
+	if *recordsPath != "" {
+		f, err := os.Create(*recordsPath)
+		if err != nil {
+			log.Fatalf("create records file: %v", err)
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				log.Printf("close records file: %v", err)
+			}
+		}()
+		records = f
+	}
+
+	cfg := arena.Config{
+		Rules:        game.Rules{Rows: *rows, Columns: *columns, Connect: *connect, PopOut: *popOut},
+		Games:        *games,
+		OpeningPlies: *opening,
+		Concurrency:  *concurrency,
+		Seed:         *seed,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("%s vs %s, %d games, %s, seed %d\n", a.Name, b.Name, cfg.Games, cfg.Rules, cfg.Seed)
+	var summary arena.Summary
+	played, err := arena.Run(ctx, a, b, cfg, func(r arena.Record) {
+		switch r.WinnerName() {
+		case "":
+			summary.Draws++
+		case a.Name:
+			summary.Wins++
+		default:
+			summary.Losses++
+		}
+		if !*lossesOnly || (r.Winner != 0 && r.WinnerName() != a.Name) {
+			if err := arena.WriteRecord(records, r); err != nil {
+				log.Printf("write record: %v", err)
+			}
+		}
+		fmt.Printf("\rgames %d/%d  +%d -%d =%d", summary.Games(), cfg.Games, summary.Wins, summary.Losses, summary.Draws)
+	})
+	fmt.Println()
+	if err != nil {
+		log.Printf("match stopped after %d games: %v", len(played), err)
+	}
+
+	report(a.Name, summary, arena.SPRT{Elo0: *elo0, Elo1: *elo1, Alpha: *alpha, Beta: *beta})
+}
+
+func lookup(bots *bot.Registry, name string) (arena.Player, error) {
+	id, ok := bots.Lookup(name)
+	if !ok {
+		return arena.Player{}, fmt.Errorf("unknown bot %q, choose from %v", name, bots.Names())
+	}
+	return arena.Player{Name: name, Strategy: id.Strategy}, nil
+}
+
+func report(name string, summary arena.Summary, test arena.SPRT) {
+	diff, lower, upper := summary.Elo()
+	fmt.Printf("%s: %d wins, %d losses, %d draws (score %.1f%%)\n",
+		name, summary.Wins, summary.Losses, summary.Draws, 100*summary.Score())
+	fmt.Printf("elo difference: %s [%s, %s] at 95%% confidence\n", formatElo(diff), formatElo(lower), formatElo(upper))
+
+	llrLower, llrUpper := test.Bounds()
+	fmt.Printf("sprt elo0=%g elo1=%g: llr %.2f [%.2f, %.2f], %s\n",
+		test.Elo0, test.Elo1, test.LLR(summary), llrLower, llrUpper, test.Verdict(summary))
+}
+
+func formatElo(elo float64) string {
+	if math.IsInf(elo, 0) {
+		if elo > 0 {
+			return "+inf"
+		}
+		return "-inf"
+	}
+	return fmt.Sprintf("%+.1f", elo)
+}
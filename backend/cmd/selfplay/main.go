@@ -0,0 +1,143 @@
+// Command selfplay generates labelled Connect Four positions for training by
+// playing registered bots against each other from random openings.
+//
+// Usage:
+//
+//	go run ./cmd/selfplay -a BOT_HARD -b BOT_CLUB -games 1000 -format jsonl -out data/selfplay
+//
+// Every position a bot moved in is written with the move it chose, as a
+// 0-based column, and the final result for the player to move: 1 for a win, 0 for a draw and -1 for
+// a loss. With -solve, classic positions with at least -solve-min-plies discs
+// also get the exact solver score. Positions are deduplicated by canonical
+// hash, so a position and its mirror image are written once, labelled from
+// the first game that reached it. Output is split into shards of
+// -shard-size positions named <out>-00000.jsonl and so on.
+//
+// The same seed gives the same files whatever the concurrency, as long as
+// neither bot plays to a time limit.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/arena"
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/selfplay"
+)
+
+func main() {
+	log.SetFlags(0)
+
+	nameA := flag.String("a", bot.NameFor(bot.Hard), "first bot")
+	nameB := flag.String("b", "", "second bot, the first bot again if empty")
+	games := flag.Int("games", 100, "number of games")
+	opening := flag.Int("opening", 4, "random plies at the start of each game, not written")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "games played and labelled at once")
+	seed := flag.Int64("seed", 1, "random seed")
+	rows := flag.Int("rows", game.Rows, "board rows")
+	columns := flag.Int("columns", game.Columns, "board columns")
+	connect := flag.Int("connect", game.ConnectLength, "discs in a row needed to win")
+	popOut := flag.Bool("popout", false, "play the PopOut variant")
+	format := flag.String("format", "jsonl", "output format: jsonl or csv")
+	out := flag.String("out", "selfplay", "output path prefix for the shards")
+	shardSize := flag.Int("shard-size", 100000, "positions per output file")
+	dedupe := flag.Bool("dedupe", true, "write each canonical position once")
+	solve := flag.Bool("solve", false, "add the exact solver score; classic board only")
+	solveMinPlies := flag.Int("solve-min-plies", 12, "only solve positions with at least this many discs")
+	flag.Parse()
+
+	if *nameB == "" {
+		*nameB = *nameA
+	}
+	w, err := selfplay.NewShardWriter(*out, *format, *shardSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rules := game.Rules{Rows: *rows, Columns: *columns, Connect: *connect, PopOut: *popOut}
+	if err := rules.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *solve && rules != game.DefaultRules() {
+		log.Fatal("the solver only handles the classic 6x7 board")
+	}
+
+	bots := bot.DefaultRegistry()
+	engines, err := bot.RegisterEngines(bots, os.Getenv("EXTERNAL_ENGINES"))
+	if err != nil {
+		log.Fatalf("external engines: %v", err)
+	}
+	defer func() {
+		for _, engine := range engines {
+			_ = engine.Close()
+		}
+	}()
+
+	a, err := lookup(bots, *nameA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := lookup(bots, *nameB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := arena.Config{
+		Rules:        rules,
+		Games:        *games,
+		OpeningPlies: *opening,
+		Concurrency:  *concurrency,
+		Seed:         *seed,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("%s vs %s, %d games, %s, seed %d\n", a.Name, b.Name, cfg.Games, cfg.Rules, cfg.Seed)
+	started := time.Now()
+
+	records := make(chan arena.Record, max(1, *concurrency))
+	go func() {
+		defer close(records)
+		played, err := arena.Run(ctx, a, b, cfg, func(r arena.Record) { records <- r })
+		if err != nil {
+			log.Printf("self-play stopped after %d games: %v", len(played), err)
+		}
+	}()
+
+	var scores *selfplay.ScoreCache
+	if *solve {
+		scores = selfplay.NewScoreCache(*solveMinPlies)
+	}
+	labelled := selfplay.Label(records, scores, max(1, *concurrency))
+
+	stats, err := selfplay.Write(labelled, w, *dedupe, func(s selfplay.Stats) {
+		fmt.Printf("\rgames %d  positions %d  duplicates %d", s.Games, s.Written, s.Duplicates)
+	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	fmt.Println()
+	if err != nil {
+		log.Fatalf("write samples: %v", err)
+	}
+	fmt.Printf("%d games, %d positions written to %d shards, %d duplicates skipped in %s\n",
+		stats.Games, stats.Written, w.Shards(), stats.Duplicates, time.Since(started).Round(time.Second))
+}
+
+func lookup(bots *bot.Registry, name string) (arena.Player, error) {
+	id, ok := bots.Lookup(name)
+	if !ok {
+		return arena.Player{}, fmt.Errorf("unknown bot %q, choose from %v", name, bots.Names())
+	}
+	return arena.Player{Name: name, Strategy: id.Strategy}, nil
+}
@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"     // ← correct position here
 
+	"github.com/example/connect-four/backend/internal/analysis"
 	"github.com/example/connect-four/backend/internal/api"
 	"github.com/example/connect-four/backend/internal/bot"
 	"github.com/example/connect-four/backend/internal/game"
@@ -53,15 +56,41 @@ func main() {
 	}()
 
 	repo := store.NewRepository(db)
-	apiHandlers := api.New(repo)
+	analyses := analysis.NewService(repo, analysis.DefaultOptions)
+	apiHandlers := api.New(repo, analyses)
 
 	manager := ws.NewManager()
 	gameManager := game.NewManager()
-	matchmaker := matchmaking.NewMatchmaker(gameManager, manager, "BOT")
-	botEngine := bot.New(gameManager)
+	bots := bot.DefaultRegistry()
+	engines, err := bot.RegisterEngines(bots, os.Getenv("EXTERNAL_ENGINES"))
+	if err != nil {
+		log.Fatalf("external engines: %v", err)
+	}
+	defer func() {
+		for _, engine := range engines {
+			if err := engine.Close(); err != nil {
+				log.Printf("engine close error: %v", err)
+			}
+		}
+	}()
+	ladder := bot.NewLadder(repo)
+	matchmaker := matchmaking.NewMatchmaker(gameManager, manager, bots, bot.DefaultBot)
+	matchmaker.SetBotChooser(ladder)
+	matchmaker.SetRatings(repo)
+	botEngine := bot.New(gameManager, bots)
 	handler := ws.NewHandler(manager, gameManager, matchmaker, botEngine, repo)
+	handler.Analyzer = analyses
+	handler.Ladder = ladder
+	handler.Ratings = repo
+	if err := configureHints(handler); err != nil {
+		log.Fatalf("hints: %v", err)
+	}
+	if err := configureTakebacks(handler); err != nil {
+		log.Fatalf("takebacks: %v", err)
+	}
 	handler.RegisterRoutes(r)
 	r.GET("/leaderboard", apiHandlers.GetLeaderboard)
+	r.GET("/games/:id/analysis", apiHandlers.GetGameAnalysis)
 
 	srv := newHTTPServer(r)
 
@@ -69,6 +98,7 @@ func main() {
 	defer stop()
 
 	go matchmaker.Start(ctx)
+	go analyses.Start(ctx)
 
 	go func() {
 		if err := srv.run(); err != nil && err != http.ErrServerClosed {
@@ -89,6 +119,47 @@ func main() {
 	manager.Shutdown(shutdownCtx)
 }
 
+// configureHints applies HINTS_PER_GAME and HINT_TIME, e.g. 3 and 1s, to
+// the handler's hint allowance and search time when they are set.
+func configureHints(handler *ws.Handler) error {
+	if raw := os.Getenv("HINTS_PER_GAME"); raw != "" {
+		hints, err := strconv.Atoi(raw)
+		if err != nil || hints < 0 {
+			return fmt.Errorf("invalid HINTS_PER_GAME %q", raw)
+		}
+		handler.HintsPerGame = hints
+	}
+	if raw := os.Getenv("HINT_TIME"); raw != "" {
+		hintTime, err := time.ParseDuration(raw)
+		if err != nil || hintTime <= 0 {
+			return fmt.Errorf("invalid HINT_TIME %q", raw)
+		}
+		handler.HintTime = hintTime
+	}
+	return nil
+}
+
+// configureTakebacks applies UNDO_TIMEOUT and BOT_TAKEBACK_LIMIT, e.g. 15s
+// and 3, to how long a takeback request waits for an answer and how many
+// takebacks the bot grants each player, when they are set.
+func configureTakebacks(handler *ws.Handler) error {
+	if raw := os.Getenv("UNDO_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid UNDO_TIMEOUT %q", raw)
+		}
+		handler.UndoTimeout = timeout
+	}
+	if raw := os.Getenv("BOT_TAKEBACK_LIMIT"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid BOT_TAKEBACK_LIMIT %q", raw)
+		}
+		handler.BotTakebackLimit = limit
+	}
+	return nil
+}
+
 type httpServer struct {
 	engine *gin.Engine
 	server *http.Server
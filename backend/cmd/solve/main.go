@@ -0,0 +1,70 @@
+// Command solve prints the perfect-play value of a classic Connect Four
+// position and of every column the player to move can choose.
+//
+// Usage:
+//
+//	go run ./cmd/solve 4453
+//
+// The position is given in the game's move notation: one 1-based column per
+// disc, player 1 first. An empty argument solves the starting position.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/solver"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) > 2 {
+		log.Fatalf("usage: %s [moves]", os.Args[0])
+	}
+
+	var moves string
+	if len(os.Args) == 2 {
+		moves = os.Args[1]
+	}
+
+	plies, err := game.ParseMoves(game.DefaultRules(), moves)
+	if err != nil {
+		log.Fatalf("invalid moves %q: %v", moves, err)
+	}
+	for _, ply := range plies {
+		if ply.Kind != game.DROP {
+			log.Fatalf("invalid moves %q: pops are not supported", moves)
+		}
+	}
+	pos, err := game.PositionFromMoves(game.DefaultRules(), moves)
+	if err != nil {
+		log.Fatalf("invalid moves %q: %v", moves, err)
+	}
+
+	s := solver.New()
+	started := time.Now()
+	results, err := s.Analyze(pos)
+	if err != nil {
+		log.Fatalf("solve %q: %v", moves, err)
+	}
+
+	fmt.Printf("position %q, player %d to move\n", moves, pos.Turn())
+	best := -1
+	for col, result := range results {
+		if result == nil {
+			fmt.Printf("column %d: full\n", col+1)
+			continue
+		}
+		fmt.Printf("column %d: %+d (%s in %d plies)\n", col+1, result.Score, result.Outcome, result.Plies)
+		if best < 0 || result.Score > results[best].Score {
+			best = col
+		}
+	}
+	if best >= 0 {
+		fmt.Printf("value: %+d (%s in %d plies)\n", results[best].Score, results[best].Outcome, results[best].Plies)
+	}
+	fmt.Printf("searched %d nodes in %s\n", s.Nodes(), time.Since(started).Round(time.Millisecond))
+}
@@ -0,0 +1,128 @@
+// Package analysis reviews completed games move by move with the bot engine.
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/store"
+)
+
+// Class grades a move by how much of the expected score it gave away.
+type Class string
+
+const (
+	Best       Class = "BEST"
+	Good       Class = "GOOD"
+	Inaccuracy Class = "INACCURACY"
+	Mistake    Class = "MISTAKE"
+	Blunder    Class = "BLUNDER"
+)
+
+// classLimits are the largest losses, in expected score, of each class short
+// of a blunder.
+var classLimits = []struct {
+	loss  float64
+	class Class
+}{
+	{0, Best},
+	{0.05, Good},
+	{0.15, Inaccuracy},
+	{0.30, Mistake},
+}
+
+// Options tune how hard the engine looks at each position.
+type Options struct {
+	// Depth caps the search in plies and MoveTime its duration per position.
+	Depth    int
+	MoveTime time.Duration
+}
+
+// DefaultOptions searches each position for up to 200ms.
+var DefaultOptions = Options{Depth: 12, MoveTime: 200 * time.Millisecond}
+
+// Game replays the moves still on the board at the end of record and grades
+// each against the engine's best move in its position. Accuracy is the mean
+// expected score each player kept, as a percentage.
+func Game(ctx context.Context, record *store.CompletedGame, opts Options) (*store.GameAnalysis, error) {
+	rules := record.Rules
+	if rules == (game.Rules{}) {
+		rules = game.DefaultRules()
+	}
+
+	result := &store.GameAnalysis{GameID: record.ID, Moves: []store.AnalyzedMove{}, Accuracy: make(map[string]float64)}
+	kept := make(map[string][]float64)
+	pos := game.NewPosition(rules)
+	for i, mv := range game.ActiveMoves(store.GameMoves(record.Moves)) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		moveCtx, cancel := context.WithTimeout(ctx, opts.MoveTime)
+		evaluation, err := bot.Analyze(moveCtx, pos, opts.Depth)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("ply %d: %w", i+1, err)
+		}
+
+		ply := game.Ply{Kind: mv.Kind, Column: mv.Column}
+		played, ok := find(evaluation.Moves, ply)
+		if !ok {
+			return nil, fmt.Errorf("ply %d: %s in column %d is not a legal move", i+1, mv.Kind, mv.Column+1)
+		}
+
+		loss := max(0, evaluation.Best.ExpectedScore()-played.ExpectedScore())
+		result.Moves = append(result.Moves, store.AnalyzedMove{
+			Ply:        i + 1,
+			Player:     mv.Player,
+			Kind:       string(mv.Kind),
+			Column:     mv.Column,
+			Class:      string(classify(loss)),
+			Loss:       round(loss, 3),
+			BestKind:   string(evaluation.Best.Ply.Kind),
+			BestColumn: evaluation.Best.Ply.Column,
+		})
+		kept[mv.Player] = append(kept[mv.Player], 1-loss)
+
+		if err := pos.Apply(ply); err != nil {
+			return nil, fmt.Errorf("ply %d: %w", i+1, err)
+		}
+	}
+
+	for player, scores := range kept {
+		sum := 0.0
+		for _, score := range scores {
+			sum += score
+		}
+		result.Accuracy[player] = round(100*sum/float64(len(scores)), 1)
+	}
+	result.AnalyzedAt = time.Now().UTC()
+	return result, nil
+}
+
+func find(evals []bot.MoveEval, ply game.Ply) (bot.MoveEval, bool) {
+	for _, eval := range evals {
+		if eval.Ply == ply {
+			return eval, true
+		}
+	}
+	return bot.MoveEval{}, false
+}
+
+func classify(loss float64) Class {
+	for _, limit := range classLimits {
+		if loss <= limit.loss {
+			return limit.class
+		}
+	}
+	return Blunder
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,78 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/store"
+)
+
+var testOptions = Options{Depth: 6, MoveTime: 100 * time.Millisecond}
+
+// missedWin is a game in which alice ignores their win on the bottom row and
+// bob completes their column instead.
+func missedWin() *store.CompletedGame {
+	record := &store.CompletedGame{ID: "game-1", Player1: "alice", Player2: "bob", Rules: game.DefaultRules()}
+	for i, col := range []int{0, 4, 1, 4, 2, 4, 6, 4} {
+		player := "alice"
+		if i%2 == 1 {
+			player = "bob"
+		}
+		record.Moves = append(record.Moves, store.CompletedMove{Player: player, Kind: "DROP", Column: col, MoveNumber: i + 1})
+	}
+	return record
+}
+
+func TestGameGradesMoves(t *testing.T) {
+	result, err := Game(context.Background(), missedWin(), testOptions)
+	if err != nil {
+		t.Fatalf("analyse: %v", err)
+	}
+	if len(result.Moves) != 8 {
+		t.Fatalf("expected every ply graded, got %d", len(result.Moves))
+	}
+
+	missed := result.Moves[6]
+	if missed.Class != string(Blunder) || missed.BestColumn != 3 || missed.Loss != 1 {
+		t.Fatalf("expected the missed win to be a blunder, got %+v", missed)
+	}
+	if winning := result.Moves[7]; winning.Class != string(Best) || winning.Loss != 0 {
+		t.Fatalf("expected bob's winning move to be best, got %+v", winning)
+	}
+	if result.Accuracy["alice"] >= result.Accuracy["bob"] || result.Accuracy["bob"] > 100 {
+		t.Fatalf("expected alice to be less accurate than bob, got %v", result.Accuracy)
+	}
+}
+
+func TestGameTreatsMovesWithoutKindsAsDrops(t *testing.T) {
+	record := missedWin()
+	for i := range record.Moves {
+		record.Moves[i].Kind = ""
+	}
+
+	result, err := Game(context.Background(), record, testOptions)
+	if err != nil {
+		t.Fatalf("analyse: %v", err)
+	}
+	if len(result.Moves) != 8 || result.Moves[0].Kind != string(game.DROP) {
+		t.Fatalf("expected every ply graded as a drop, got %+v", result.Moves)
+	}
+}
+
+func TestGameSkipsTakenBackMoves(t *testing.T) {
+	record := missedWin()
+	record.Moves = append(record.Moves[:7:7],
+		store.CompletedMove{Player: "alice", Kind: "TAKEBACK", MoveNumber: 8, Undone: 1},
+		store.CompletedMove{Player: "alice", Kind: "DROP", Column: 3, MoveNumber: 9},
+	)
+
+	result, err := Game(context.Background(), record, testOptions)
+	if err != nil {
+		t.Fatalf("analyse: %v", err)
+	}
+	if len(result.Moves) != 7 || result.Moves[6].Column != 3 || result.Moves[6].Class != string(Best) {
+		t.Fatalf("expected the replacement move to be graded, got %+v", result.Moves)
+	}
+}
@@ -0,0 +1,136 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/example/connect-four/backend/internal/store"
+)
+
+// queueSize bounds how many finished games may wait for background analysis.
+const queueSize = 64
+
+// Repository is the storage the Service reads games from and keeps analyses in.
+type Repository interface {
+	GetCompletedGame(id string) (*store.CompletedGame, error)
+	GetAnalysis(gameID string) (*store.GameAnalysis, error)
+	SaveAnalysis(analysis *store.GameAnalysis) error
+}
+
+// Service analyses completed games on demand and in the background, and
+// stores the results. A game is analysed once however many callers and queue
+// entries ask for it while the analysis runs.
+type Service struct {
+	repo    Repository
+	options Options
+	queue   chan *store.CompletedGame
+
+	mu       sync.Mutex
+	inflight map[string]*pending
+}
+
+// pending is an analysis under way, shared by everyone asking for the game.
+type pending struct {
+	done     chan struct{}
+	analysis *store.GameAnalysis
+	err      error
+}
+
+// NewService builds a Service searching with opts.
+func NewService(repo Repository, opts Options) *Service {
+	return &Service{
+		repo:     repo,
+		options:  opts,
+		queue:    make(chan *store.CompletedGame, queueSize),
+		inflight: make(map[string]*pending),
+	}
+}
+
+// Analysis returns the stored analysis of a game, analysing the game first if
+// it has not been yet. It returns store.ErrNotFound for unknown games. The
+// analysis carries on and is stored even if ctx ends first.
+func (s *Service) Analysis(ctx context.Context, gameID string) (*store.GameAnalysis, error) {
+	stored, err := s.repo.GetAnalysis(gameID)
+	if err == nil {
+		return stored, nil
+	}
+	if !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+
+	record, err := s.repo.GetCompletedGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+	return s.analyse(ctx, context.WithoutCancel(ctx), record)
+}
+
+// Enqueue schedules a finished game for background analysis. While the
+// queue is full the game is skipped; it can still be analysed on demand.
+func (s *Service) Enqueue(record *store.CompletedGame) {
+	select {
+	case s.queue <- record:
+	default:
+		log.Printf("analysis: queue full, skipping game %s", record.ID)
+	}
+}
+
+// Start analyses queued games one at a time until ctx ends.
+func (s *Service) Start(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case record := <-s.queue:
+			if _, err := s.analyse(ctx, ctx, record); err != nil {
+				log.Printf("analysis: game %s failed: %v", record.ID, err)
+			}
+		}
+	}
+}
+
+// analyse returns the analysis of record, joining the one under way for the
+// game if there is one and starting it under run otherwise. It stops waiting
+// once ctx is done.
+func (s *Service) analyse(ctx, run context.Context, record *store.CompletedGame) (*store.GameAnalysis, error) {
+	s.mu.Lock()
+	p, ok := s.inflight[record.ID]
+	if !ok {
+		p = &pending{done: make(chan struct{})}
+		s.inflight[record.ID] = p
+		go s.run(run, record, p)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-p.done:
+		return p.analysis, p.err
+	}
+}
+
+// run analyses record and stores the result, unless an analysis finished
+// while p was being set up.
+func (s *Service) run(ctx context.Context, record *store.CompletedGame, p *pending) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.inflight, record.ID)
+		s.mu.Unlock()
+		close(p.done)
+	}()
+
+	if stored, err := s.repo.GetAnalysis(record.ID); err == nil {
+		p.analysis = stored
+		return
+	}
+	if p.analysis, p.err = Game(ctx, record, s.options); p.err != nil {
+		p.analysis = nil
+		return
+	}
+	if p.err = s.repo.SaveAnalysis(p.analysis); p.err != nil {
+		p.analysis = nil
+	}
+}
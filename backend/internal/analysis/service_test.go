@@ -0,0 +1,137 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/store"
+)
+
+type stubRepository struct {
+	mu       sync.Mutex
+	games    map[string]*store.CompletedGame
+	analyses map[string]*store.GameAnalysis
+	saved    chan string
+}
+
+func newStubRepository(games ...*store.CompletedGame) *stubRepository {
+	repo := &stubRepository{games: make(map[string]*store.CompletedGame), analyses: make(map[string]*store.GameAnalysis), saved: make(chan string, 4)}
+	for _, g := range games {
+		repo.games[g.ID] = g
+	}
+	return repo
+}
+
+func (r *stubRepository) GetCompletedGame(id string) (*store.CompletedGame, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if g, ok := r.games[id]; ok {
+		return g, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func (r *stubRepository) GetAnalysis(gameID string) (*store.GameAnalysis, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if a, ok := r.analyses[gameID]; ok {
+		return a, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func (r *stubRepository) SaveAnalysis(analysis *store.GameAnalysis) error {
+	r.mu.Lock()
+	r.analyses[analysis.GameID] = analysis
+	r.mu.Unlock()
+
+	r.saved <- analysis.GameID
+	return nil
+}
+
+func TestServiceAnalysesOnDemandOnce(t *testing.T) {
+	repo := newStubRepository(missedWin())
+	service := NewService(repo, testOptions)
+
+	first, err := service.Analysis(context.Background(), "game-1")
+	if err != nil {
+		t.Fatalf("analysis: %v", err)
+	}
+	second, err := service.Analysis(context.Background(), "game-1")
+	if err != nil || second != first {
+		t.Fatalf("expected the stored analysis to be served, got %v", err)
+	}
+	if len(repo.saved) != 1 {
+		t.Fatalf("expected one analysis to be saved, got %d", len(repo.saved))
+	}
+
+	if _, err := service.Analysis(context.Background(), "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an unknown game, got %v", err)
+	}
+}
+
+func TestServiceAnalysesQueuedGames(t *testing.T) {
+	repo := newStubRepository()
+	service := NewService(repo, testOptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.Start(ctx)
+
+	service.Enqueue(missedWin())
+
+	select {
+	case id := <-repo.saved:
+		if id != "game-1" {
+			t.Fatalf("expected game-1 to be analysed, got %s", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("queued game was not analysed")
+	}
+}
+
+func TestServiceSharesConcurrentAnalyses(t *testing.T) {
+	repo := newStubRepository(missedWin())
+	service := NewService(repo, testOptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.Start(ctx)
+	service.Enqueue(missedWin())
+
+	results := make([]*store.GameAnalysis, 8)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			analysis, err := service.Analysis(context.Background(), "game-1")
+			if err != nil {
+				t.Errorf("analysis: %v", err)
+			}
+			results[i] = analysis
+		}()
+	}
+	wg.Wait()
+
+	for _, analysis := range results {
+		if analysis != results[0] {
+			t.Fatalf("expected every caller to get the same analysis")
+		}
+	}
+	select {
+	case <-repo.saved:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("analysis was not saved")
+	}
+	select {
+	case id := <-repo.saved:
+		t.Fatalf("expected game %s to be analysed once", id)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
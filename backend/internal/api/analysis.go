@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/example/connect-four/backend/internal/store"
+)
+
+// AnalysisProvider returns the analysis of a completed game, running it first
+// if needed. Unknown games yield store.ErrNotFound.
+type AnalysisProvider interface {
+	Analysis(ctx context.Context, gameID string) (*store.GameAnalysis, error)
+}
+
+// GetGameAnalysis responds with the move-by-move analysis of a completed game.
+func (a *API) GetGameAnalysis(c *gin.Context) {
+	if a == nil || a.analyses == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "analysis unavailable"})
+		return
+	}
+
+	gameID := c.Param("id")
+	analysis, err := a.analyses.Analysis(c.Request.Context(), gameID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "game not found"})
+		return
+	case err != nil:
+		log.Printf("api: analysis of game %s failed: %v", gameID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "analysis failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/example/connect-four/backend/internal/analysis"
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/store"
+)
+
+type stubAnalyses map[string]*store.GameAnalysis
+
+func (s stubAnalyses) Analysis(ctx context.Context, gameID string) (*store.GameAnalysis, error) {
+	if analysis, ok := s[gameID]; ok {
+		return analysis, nil
+	}
+	return nil, store.ErrNotFound
+}
+
+func TestGetGameAnalysis(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	analyses := stubAnalyses{"game-1": {GameID: "game-1", Accuracy: map[string]float64{"alice": 87.5}}}
+	r := gin.New()
+	r.GET("/games/:id/analysis", New(&stubRepo{}, analyses).GetGameAnalysis)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/game-1/analysis", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var body store.GameAnalysis
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Accuracy["alice"] != 87.5 {
+		t.Fatalf("unexpected body %s (err %v)", w.Body.String(), err)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/missing/analysis", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+type failingAnalyses struct{ err error }
+
+func (f failingAnalyses) Analysis(ctx context.Context, gameID string) (*store.GameAnalysis, error) {
+	return nil, f.err
+}
+
+func TestGetGameAnalysisHidesInternalErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/games/:id/analysis", New(&stubRepo{}, failingAnalyses{errors.New("pq: password authentication failed")}).GetGameAnalysis)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/game-1/analysis", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Fatalf("expected a generic error, got %s", w.Body.String())
+	}
+}
+
+// countingRepository holds one completed game and counts the analyses saved.
+type countingRepository struct {
+	mu       sync.Mutex
+	record   *store.CompletedGame
+	analysis *store.GameAnalysis
+	saves    int
+}
+
+func (r *countingRepository) GetCompletedGame(id string) (*store.CompletedGame, error) {
+	if id != r.record.ID {
+		return nil, store.ErrNotFound
+	}
+	return r.record, nil
+}
+
+func (r *countingRepository) GetAnalysis(gameID string) (*store.GameAnalysis, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.analysis == nil || r.analysis.GameID != gameID {
+		return nil, store.ErrNotFound
+	}
+	return r.analysis, nil
+}
+
+func (r *countingRepository) SaveAnalysis(analysis *store.GameAnalysis) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.analysis = analysis
+	r.saves++
+	return nil
+}
+
+func TestGetGameAnalysisSharesConcurrentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	record := &store.CompletedGame{ID: "game-1", Player1: "alice", Player2: "bob", Rules: game.DefaultRules()}
+	for i, col := range []int{3, 3, 4, 4, 2, 2} {
+		player := "alice"
+		if i%2 == 1 {
+			player = "bob"
+		}
+		record.Moves = append(record.Moves, store.CompletedMove{Player: player, Kind: "DROP", Column: col, MoveNumber: i + 1})
+	}
+	repo := &countingRepository{record: record}
+	service := analysis.NewService(repo, analysis.Options{Depth: 4, MoveTime: 50 * time.Millisecond})
+
+	r := gin.New()
+	r.GET("/games/:id/analysis", New(&stubRepo{}, service).GetGameAnalysis)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/games/game-1/analysis", nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status 200, got %d", w.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if repo.saves != 1 {
+		t.Fatalf("expected concurrent requests to share one analysis, got %d", repo.saves)
+	}
+}
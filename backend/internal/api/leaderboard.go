@@ -14,14 +14,16 @@ type LeaderboardRepository interface {
 	GetLeaderboard(limit int) ([]store.LeaderboardEntry, error)
 }
 
-// API bundles HTTP handlers that depend on the leaderboard repository.
+// API bundles HTTP handlers that depend on the leaderboard repository and
+// the game analysis service.
 type API struct {
-	repo LeaderboardRepository
+	repo     LeaderboardRepository
+	analyses AnalysisProvider
 }
 
 // New constructs a new API surface.
-func New(repo LeaderboardRepository) *API {
-	return &API{repo: repo}
+func New(repo LeaderboardRepository, analyses AnalysisProvider) *API {
+	return &API{repo: repo, analyses: analyses}
 }
 
 // GetLeaderboard responds with the leaderboard in JSON.
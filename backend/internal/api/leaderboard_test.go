@@ -25,7 +25,7 @@ func TestGetLeaderboardSuccess(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
 	repo := &stubRepo{entries: []store.LeaderboardEntry{{Username: "alice", Wins: 5}}}
-	handler := New(repo)
+	handler := New(repo, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -47,7 +47,7 @@ func TestGetLeaderboardSuccess(t *testing.T) {
 func TestGetLeaderboardBadLimit(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
-	handler := New(&stubRepo{})
+	handler := New(&stubRepo{}, nil)
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
@@ -0,0 +1,197 @@
+// Package arena plays bots against each other to measure their relative
+// strength.
+package arena
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Player is a bot taking part in a match.
+type Player struct {
+	Name     string
+	Strategy bot.Strategy
+}
+
+// Config describes a match.
+type Config struct {
+	Rules game.Rules
+	// Games is the number of games to play. Each random opening is played
+	// twice with colours swapped, so an even number is fairest.
+	Games int
+	// OpeningPlies is the number of random drops made before the bots take
+	// over, so that deterministic bots do not replay the same game.
+	OpeningPlies int
+	// Concurrency is the number of games played at once.
+	Concurrency int
+	// Seed makes openings and bot randomness reproducible.
+	Seed int64
+}
+
+const (
+	// TerminationForfeit marks a game lost because a bot failed to move.
+	TerminationForfeit = game.TerminationForfeit
+	// TerminationPlyLimit marks a game stopped as drawn because it ran past
+	// the arena's ply cap.
+	TerminationPlyLimit game.Termination = "PLY_LIMIT"
+)
+
+// Record is a finished arena game.
+type Record struct {
+	Index   int
+	Player1 string
+	Player2 string
+	Rules   game.Rules
+	// Opening is the number of leading plies that were chosen at random.
+	Opening int
+	Plies   []game.Ply
+	// Winner is 1 or 2, or 0 for a draw.
+	Winner      int
+	Termination game.Termination
+}
+
+// WinnerName returns the name of the winning bot, or "" for a draw.
+func (r Record) WinnerName() string {
+	switch r.Winner {
+	case 1:
+		return r.Player1
+	case 2:
+		return r.Player2
+	}
+	return ""
+}
+
+// Run plays cfg.Games games between a and b. a moves first in even-numbered
+// games and b in odd-numbered ones. Every finished game is passed to onGame,
+// which is called from one goroutine at a time; records are returned in game
+// order. Run stops early, returning the games played so far, when ctx ends.
+func Run(ctx context.Context, a, b Player, cfg Config, onGame func(Record)) ([]Record, error) {
+	if err := cfg.Rules.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Games <= 0 {
+		return nil, errors.New("games must be positive")
+	}
+	workers := max(1, cfg.Concurrency)
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := 0; i < cfg.Games; i++ {
+			select {
+			case indices <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		records = make([]*Record, cfg.Games)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				record := playGame(i, a, b, cfg)
+
+				mu.Lock()
+				records[i] = &record
+				if onGame != nil {
+					onGame(record)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	played := make([]Record, 0, cfg.Games)
+	for _, record := range records {
+		if record != nil {
+			played = append(played, *record)
+		}
+	}
+	return played, ctx.Err()
+}
+
+// playGame plays game i. Both games of an opening pair share the opening.
+func playGame(i int, a, b Player, cfg Config) Record {
+	players := [2]Player{a, b}
+	if i%2 == 1 {
+		players = [2]Player{b, a}
+	}
+
+	record := Record{Index: i, Player1: players[0].Name, Player2: players[1].Name, Rules: cfg.Rules}
+	pos := game.NewPosition(cfg.Rules)
+	seen := map[game.Position]int{pos: 1}
+
+	opening := rand.New(rand.NewSource(cfg.Seed + int64(i/2)))
+	for record.Opening < cfg.OpeningPlies {
+		ply, ok := randomOpeningPly(&pos, opening)
+		if !ok {
+			break
+		}
+		_ = pos.Apply(ply)
+		seen[pos]++
+		record.Plies = append(record.Plies, ply)
+		record.Opening++
+	}
+
+	intn := rand.New(rand.NewSource(cfg.Seed ^ int64(i+1)<<32)).Intn
+	for len(record.Plies) < 4*cfg.Rules.Cells() {
+		mover := pos.Turn()
+		ply, err := players[mover-1].Strategy.ChooseMove(context.Background(), pos, intn)
+		if err == nil {
+			err = pos.Apply(ply)
+		}
+		if err != nil {
+			record.Winner = 3 - mover
+			record.Termination = TerminationForfeit
+			return record
+		}
+		record.Plies = append(record.Plies, ply)
+		seen[pos]++
+
+		switch {
+		case pos.HasWon(mover):
+			record.Winner, record.Termination = mover, game.TerminationConnect
+		case pos.HasWon(3 - mover):
+			record.Winner, record.Termination = 3-mover, game.TerminationConnect
+		case cfg.Rules.PopOut && seen[pos] >= game.RepetitionLimit:
+			record.Termination = game.TerminationRepetition
+		case !pos.HasLegalMove():
+			record.Termination = game.TerminationBoardFull
+		default:
+			continue
+		}
+		return record
+	}
+
+	// Only PopOut games can run this long; call them drawn.
+	record.Termination = TerminationPlyLimit
+	return record
+}
+
+// randomOpeningPly picks a random drop that does not win on the spot, so the
+// bots always inherit a game in progress.
+func randomOpeningPly(pos *game.Position, rng *rand.Rand) (game.Ply, bool) {
+	var cols []int
+	for col := 0; col < pos.Rules().Columns; col++ {
+		if pos.CanPlay(col) && !pos.IsWinningMove(col, pos.Turn()) {
+			cols = append(cols, col)
+		}
+	}
+	if len(cols) == 0 {
+		return game.Ply{}, false
+	}
+	return game.Ply{Kind: game.DROP, Column: cols[rng.Intn(len(cols))]}, true
+}
@@ -0,0 +1,125 @@
+package arena
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// firstColumn always drops into the leftmost column with room.
+type firstColumn struct{}
+
+func (firstColumn) ChooseMove(_ context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	for col := 0; col < pos.Rules().Columns; col++ {
+		if pos.CanPlay(col) {
+			return game.Ply{Kind: game.DROP, Column: col}, nil
+		}
+	}
+	return game.Ply{}, errors.New("no move")
+}
+
+type failing struct{}
+
+func (failing) ChooseMove(context.Context, game.Position, func(int) int) (game.Ply, error) {
+	return game.Ply{}, errors.New("engine crashed")
+}
+
+func TestRunAlternatesColoursAndPlaysEveryGame(t *testing.T) {
+	a := Player{Name: "BOT_HARD", Strategy: bot.Hard.Strategy()}
+	b := Player{Name: "FIRST", Strategy: firstColumn{}}
+	cfg := Config{Rules: game.DefaultRules(), Games: 8, OpeningPlies: 2, Concurrency: 4, Seed: 7}
+
+	seen := 0
+	records, err := Run(context.Background(), a, b, cfg, func(Record) { seen++ })
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(records) != cfg.Games || seen != cfg.Games {
+		t.Fatalf("expected %d games, got %d records and %d callbacks", cfg.Games, len(records), seen)
+	}
+
+	for i, record := range records {
+		if record.Index != i {
+			t.Fatalf("expected records in game order, got index %d at %d", record.Index, i)
+		}
+		if (i%2 == 0) != (record.Player1 == a.Name) {
+			t.Fatalf("game %d: expected colours to alternate, player 1 is %s", i, record.Player1)
+		}
+		if record.Opening != cfg.OpeningPlies {
+			t.Fatalf("game %d: expected %d opening plies, got %d", i, cfg.OpeningPlies, record.Opening)
+		}
+		if i%2 == 1 && !samePlies(record.Plies[:2], records[i-1].Plies[:2]) {
+			t.Fatalf("game %d: expected the pair to share its opening", i)
+		}
+	}
+
+	if summary := Summarize(a.Name, records); summary.Wins != cfg.Games {
+		t.Fatalf("expected the search bot to win every game, got %+v", summary)
+	}
+}
+
+func TestRunForfeitsFailingBot(t *testing.T) {
+	a := Player{Name: "BROKEN", Strategy: failing{}}
+	b := Player{Name: "FIRST", Strategy: firstColumn{}}
+
+	records, err := Run(context.Background(), a, b, Config{Rules: game.DefaultRules(), Games: 2}, nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	for _, record := range records {
+		if record.WinnerName() != "FIRST" || record.Termination != TerminationForfeit {
+			t.Fatalf("expected the failing bot to forfeit, got %+v", record)
+		}
+	}
+}
+
+func TestRecordsRoundTrip(t *testing.T) {
+	a := Player{Name: "A", Strategy: firstColumn{}}
+	b := Player{Name: "B", Strategy: bot.Easy.Strategy()}
+	popOut := game.Rules{Rows: 5, Columns: 6, Connect: 4, PopOut: true}
+
+	records, err := Run(context.Background(), a, b, Config{Rules: popOut, Games: 4, OpeningPlies: 3, Seed: 1}, nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	records = append(records, Record{Index: 4, Player1: "BROKEN", Player2: "A", Rules: game.DefaultRules(), Winner: 2, Termination: TerminationForfeit})
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		if err := WriteRecord(&buf, record); err != nil {
+			t.Fatalf("write record: %v", err)
+		}
+	}
+
+	read, err := ReadRecords(&buf)
+	if err != nil {
+		t.Fatalf("read records: %v", err)
+	}
+	if len(read) != len(records) {
+		t.Fatalf("expected %d records, got %d", len(records), len(read))
+	}
+	for i := range records {
+		want, got := records[i], read[i]
+		if got.Index != want.Index || got.Player1 != want.Player1 || got.Player2 != want.Player2 ||
+			got.Rules != want.Rules || got.Opening != want.Opening || got.Winner != want.Winner ||
+			got.Termination != want.Termination || !samePlies(got.Plies, want.Plies) {
+			t.Fatalf("record %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func samePlies(a, b []game.Ply) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
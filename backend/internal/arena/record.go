@@ -0,0 +1,130 @@
+package arena
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Records are written as a block of tag lines followed by the moves in the
+// game's move notation and a blank line, e.g.
+//
+//	[Game "12"]
+//	[Player1 "BOT_HARD"]
+//	[Player2 "BOT_MCTS"]
+//	[Rules "6x7 connect 4"]
+//	[Opening "2"]
+//	[Result "0-1"]
+//	[Termination "CONNECT"]
+//	44536365
+//
+// The move string can be pasted into cmd/solve or replayed with
+// game.PositionFromMoves. A game without moves is written as "-".
+
+// WriteRecord writes r to w.
+func WriteRecord(w io.Writer, r Record) error {
+	moves, err := game.EncodeMoves(r.Rules, r.Plies)
+	if err != nil {
+		return fmt.Errorf("encode game %d: %w", r.Index, err)
+	}
+	if moves == "" {
+		moves = "-"
+	}
+
+	result := "1/2-1/2"
+	switch r.Winner {
+	case 1:
+		result = "1-0"
+	case 2:
+		result = "0-1"
+	}
+
+	_, err = fmt.Fprintf(w, "[Game %q]\n[Player1 %q]\n[Player2 %q]\n[Rules %q]\n[Opening %q]\n[Result %q]\n[Termination %q]\n%s\n\n",
+		strconv.Itoa(r.Index), r.Player1, r.Player2, r.Rules.String(), strconv.Itoa(r.Opening), result, r.Termination, moves)
+	return err
+}
+
+// ReadRecords parses records written by WriteRecord, checking that every move
+// string replays under its rules.
+func ReadRecords(rd io.Reader) ([]Record, error) {
+	var (
+		records []Record
+		tags    = make(map[string]string)
+	)
+
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "["):
+			name, value, ok := strings.Cut(strings.Trim(line, "[]"), " ")
+			if !ok {
+				return nil, fmt.Errorf("invalid tag line %q", line)
+			}
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid tag line %q", line)
+			}
+			tags[name] = unquoted
+		default:
+			record, err := recordFromTags(tags, line)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+			tags = make(map[string]string)
+		}
+	}
+	return records, scanner.Err()
+}
+
+func recordFromTags(tags map[string]string, moves string) (Record, error) {
+	rules, err := parseRules(tags["Rules"])
+	if err != nil {
+		return Record{}, err
+	}
+	plies, err := game.ParseMoves(rules, strings.TrimPrefix(moves, "-"))
+	if err != nil {
+		return Record{}, fmt.Errorf("game %s: %w", tags["Game"], err)
+	}
+
+	record := Record{
+		Player1:     tags["Player1"],
+		Player2:     tags["Player2"],
+		Rules:       rules,
+		Plies:       plies,
+		Termination: game.Termination(tags["Termination"]),
+	}
+	if record.Index, err = strconv.Atoi(tags["Game"]); err != nil {
+		return Record{}, fmt.Errorf("invalid game number %q", tags["Game"])
+	}
+	if record.Opening, err = strconv.Atoi(tags["Opening"]); err != nil {
+		return Record{}, fmt.Errorf("game %d: invalid opening %q", record.Index, tags["Opening"])
+	}
+	switch tags["Result"] {
+	case "1-0":
+		record.Winner = 1
+	case "0-1":
+		record.Winner = 2
+	case "1/2-1/2":
+	default:
+		return Record{}, fmt.Errorf("game %d: invalid result %q", record.Index, tags["Result"])
+	}
+	return record, nil
+}
+
+// parseRules reverses game.Rules.String.
+func parseRules(s string) (game.Rules, error) {
+	var rules game.Rules
+	if _, err := fmt.Sscanf(s, "%dx%d connect %d", &rules.Rows, &rules.Columns, &rules.Connect); err != nil {
+		return game.Rules{}, fmt.Errorf("invalid rules %q", s)
+	}
+	rules.PopOut = strings.HasSuffix(s, " popout")
+	return rules, rules.Validate()
+}
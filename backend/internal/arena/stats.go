@@ -0,0 +1,125 @@
+package arena
+
+import "math"
+
+// Summary counts the results of a match from one bot's point of view.
+type Summary struct {
+	Wins, Losses, Draws int
+}
+
+// Summarize counts the results for the bot called name.
+func Summarize(name string, records []Record) Summary {
+	var s Summary
+	for _, record := range records {
+		switch record.WinnerName() {
+		case "":
+			s.Draws++
+		case name:
+			s.Wins++
+		default:
+			s.Losses++
+		}
+	}
+	return s
+}
+
+// Games returns the number of games counted.
+func (s Summary) Games() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+// Score returns the points scored per game, counting a draw as half a win.
+func (s Summary) Score() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+// variance returns the per-game variance of the score.
+func (s Summary) variance() float64 {
+	n := float64(s.Games())
+	if n == 0 {
+		return 0
+	}
+	score := s.Score()
+	return (float64(s.Wins)*(1-score)*(1-score) +
+		float64(s.Draws)*(0.5-score)*(0.5-score) +
+		float64(s.Losses)*score*score) / n
+}
+
+// Elo returns the estimated Elo difference and the bounds of its 95%
+// confidence interval. A perfect or zero score gives infinite values.
+func (s Summary) Elo() (diff, lower, upper float64) {
+	score := s.Score()
+	margin := 1.959964 * math.Sqrt(s.variance()/float64(max(1, s.Games())))
+	return eloFromScore(score), eloFromScore(score - margin), eloFromScore(score + margin)
+}
+
+// eloFromScore converts an expected score into an Elo difference.
+func eloFromScore(score float64) float64 {
+	switch {
+	case score <= 0:
+		return math.Inf(-1)
+	case score >= 1:
+		return math.Inf(1)
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+// scoreFromElo is the expected score of a player rated elo points higher.
+func scoreFromElo(elo float64) float64 {
+	return 1 / (1 + math.Pow(10, -elo/400))
+}
+
+// SPRT is a sequential probability ratio test of H0, "the bot is elo0 points
+// stronger", against H1, "the bot is elo1 points stronger", with error
+// rates Alpha and Beta.
+type SPRT struct {
+	Elo0, Elo1  float64
+	Alpha, Beta float64
+}
+
+// Verdict is the outcome of an SPRT.
+type Verdict string
+
+const (
+	AcceptH0     Verdict = "H0 accepted"
+	AcceptH1     Verdict = "H1 accepted"
+	Inconclusive Verdict = "inconclusive"
+)
+
+// LLR returns the log-likelihood ratio of H1 over H0 for s, using the usual
+// normal approximation of the trinomial score distribution. When every game
+// had the same result the variance is zero, so one draw is added to let
+// one-sided matches still reach a verdict.
+func (t SPRT) LLR(s Summary) float64 {
+	if s.Games() == 0 {
+		return 0
+	}
+	variance := s.variance()
+	if variance == 0 {
+		s.Draws++
+		variance = s.variance()
+	}
+	s0, s1 := scoreFromElo(t.Elo0), scoreFromElo(t.Elo1)
+	return float64(s.Games()) * (s1 - s0) * (2*s.Score() - s0 - s1) / (2 * variance)
+}
+
+// Bounds returns the LLR values below which H0 and above which H1 is accepted.
+func (t SPRT) Bounds() (lower, upper float64) {
+	return math.Log(t.Beta / (1 - t.Alpha)), math.Log((1 - t.Beta) / t.Alpha)
+}
+
+// Verdict decides the test for s.
+func (t SPRT) Verdict(s Summary) Verdict {
+	llr := t.LLR(s)
+	lower, upper := t.Bounds()
+	switch {
+	case llr >= upper:
+		return AcceptH1
+	case llr <= lower:
+		return AcceptH0
+	}
+	return Inconclusive
+}
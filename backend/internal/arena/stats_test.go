@@ -0,0 +1,45 @@
+package arena
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummaryElo(t *testing.T) {
+	even := Summary{Wins: 40, Losses: 40, Draws: 20}
+	if diff, lower, upper := even.Elo(); diff != 0 || lower >= 0 || upper <= 0 || math.Abs(lower+upper) > 1e-9 {
+		t.Fatalf("expected a symmetric interval around 0, got %.1f [%.1f, %.1f]", diff, lower, upper)
+	}
+
+	// A 75% score is about 191 Elo.
+	strong := Summary{Wins: 70, Losses: 20, Draws: 10}
+	diff, lower, upper := strong.Elo()
+	if math.Abs(diff-190.8) > 0.5 || lower >= diff || upper <= diff {
+		t.Fatalf("unexpected Elo %.1f [%.1f, %.1f]", diff, lower, upper)
+	}
+
+	if diff, _, _ := (Summary{Wins: 10}).Elo(); !math.IsInf(diff, 1) {
+		t.Fatalf("expected a perfect score to be infinitely stronger, got %f", diff)
+	}
+}
+
+func TestSPRTVerdict(t *testing.T) {
+	test := SPRT{Elo0: 0, Elo1: 20, Alpha: 0.05, Beta: 0.05}
+
+	cases := []struct {
+		summary Summary
+		want    Verdict
+	}{
+		{Summary{Wins: 600, Losses: 300, Draws: 100}, AcceptH1},
+		{Summary{Wins: 300, Losses: 600, Draws: 100}, AcceptH0},
+		{Summary{Wins: 11, Losses: 10, Draws: 5}, Inconclusive},
+		{Summary{}, Inconclusive},
+		{Summary{Wins: 40}, AcceptH1},
+		{Summary{Losses: 40}, AcceptH0},
+	}
+	for _, c := range cases {
+		if got := test.Verdict(c.summary); got != c.want {
+			t.Fatalf("%+v: expected %s, got %s (LLR %.2f)", c.summary, c.want, got, test.LLR(c.summary))
+		}
+	}
+}
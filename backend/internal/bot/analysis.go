@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Outcome is what a move leads to with best play from both sides.
+type Outcome string
+
+const (
+	OutcomeWin  Outcome = "WIN"
+	OutcomeLoss Outcome = "LOSS"
+	OutcomeDraw Outcome = "DRAW"
+)
+
+// exactMargin makes searchRoot score every move exactly rather than only
+// those near the best.
+const exactMargin = 2 * (winScore + 1)
+
+// MoveEval is the value of a legal move for the player making it. Outcome is
+// empty when the search did not see how the game ends, leaving only the
+// heuristic Score. Plies counts the plies to a won or lost finish, this move
+// included.
+type MoveEval struct {
+	Ply     game.Ply
+	Outcome Outcome
+	Plies   int
+	Score   int
+}
+
+// scoreScale converts heuristic scores to expected scores: a position this
+// much better than even is worth about three quarters of a point.
+const scoreScale = 40
+
+// ExpectedScore is the share of a point the move is worth to its player: 1
+// for a win, 0 for a loss, a half for a draw and in between for heuristic
+// scores.
+func (e MoveEval) ExpectedScore() float64 {
+	switch e.Outcome {
+	case OutcomeWin:
+		return 1
+	case OutcomeLoss:
+		return 0
+	case OutcomeDraw:
+		return 0.5
+	}
+	return 1 / (1 + math.Exp(-float64(e.Score)*math.Log(3)/scoreScale))
+}
+
+// Analysis evaluates every legal move in a position. Moves are listed drops
+// first, from the centre outwards, then pops; Best is the strongest of them.
+type Analysis struct {
+	Moves []MoveEval
+	Best  MoveEval
+	// Depth is how many plies the search looked ahead.
+	Depth int
+}
+
+// Analyze evaluates every legal move in pos for the player to move, searching
+// up to depth plies and stopping early, with the deepest result so far, once
+// ctx is done.
+func Analyze(ctx context.Context, pos game.Position, depth int) (Analysis, error) {
+	moves, completed := deepen(ctx, pos, searchDepth(pos.Rules(), depth), exactMargin)
+	if len(moves) == 0 {
+		return Analysis{}, errors.New("no legal moves to analyse")
+	}
+
+	// Without pops, a search reaching the last empty cell sees every game
+	// out, so anything short of a win or loss is a draw.
+	exact := !pos.Rules().PopOut && completed >= pos.Rules().Cells()-pos.Moves()
+
+	analysis := Analysis{Moves: make([]MoveEval, len(moves)), Depth: completed}
+	for i, mv := range moves {
+		eval := MoveEval{Ply: mv.ply, Score: mv.score}
+		switch {
+		case mv.score > winScore/2:
+			eval.Outcome, eval.Plies = OutcomeWin, winScore-mv.score
+		case mv.score < -winScore/2:
+			eval.Outcome, eval.Plies = OutcomeLoss, winScore+mv.score
+		case exact:
+			eval.Outcome = OutcomeDraw
+		}
+		analysis.Moves[i] = eval
+		if i == 0 || eval.Score > analysis.Best.Score {
+			analysis.Best = eval
+		}
+	}
+	return analysis, nil
+}
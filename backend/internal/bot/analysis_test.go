@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func TestAnalyzeClassifiesMoves(t *testing.T) {
+	// Player 1 can finish the bottom row; player 2 threatens column 5.
+	pos, err := game.PositionFromMoves(game.DefaultRules(), "152535")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+
+	analysis, err := Analyze(context.Background(), pos, 6)
+	if err != nil {
+		t.Fatalf("analyse: %v", err)
+	}
+	if len(analysis.Moves) != game.Columns {
+		t.Fatalf("expected every column evaluated, got %d", len(analysis.Moves))
+	}
+
+	want := game.Ply{Kind: game.DROP, Column: 3}
+	if analysis.Best.Ply != want || analysis.Best.Outcome != OutcomeWin || analysis.Best.Plies != 1 {
+		t.Fatalf("expected the winning drop suggested, got %+v", analysis.Best)
+	}
+	for _, eval := range analysis.Moves {
+		if eval.Ply.Column == 6 && (eval.Outcome != OutcomeLoss || eval.Plies != 2) {
+			t.Fatalf("expected column 7 to lose at once, got %+v", eval)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// DefaultBookPlies is how deep into a game cmd/bookgen builds the opening
+// book by default.
+const DefaultBookPlies = 8
+
+// The book file starts with bookMagic, a version byte and the entry count as
+// a little-endian uint32, followed by the entries sorted by key. Each entry
+// is the position's canonical hash as a little-endian uint64 and the column
+// to play in the canonical orientation as one byte.
+const (
+	bookMagic     = "C4BK"
+	bookVersion   = 1
+	bookHeaderLen = len(bookMagic) + 1 + 4
+	bookEntryLen  = 8 + 1
+)
+
+//go:embed book.bin
+var embeddedBook []byte
+
+var loadDefaultBook = sync.OnceValue(func() *Book {
+	book, err := UnmarshalBook(embeddedBook)
+	if err != nil {
+		log.Printf("bot: embedded opening book unusable: %v", err)
+		return NewBook()
+	}
+	return book
+})
+
+// DefaultBook returns the opening book embedded in the binary, built by
+// cmd/bookgen: the expert search's moves for the first two plies and the
+// solver's after that.
+func DefaultBook() *Book {
+	return loadDefaultBook()
+}
+
+// Book maps positions to the move to play in them. Positions are keyed by
+// their canonical hash, so a position and its mirror image share an entry.
+// A Book must not be modified while it is being read.
+type Book struct {
+	moves map[uint64]uint8
+}
+
+// NewBook returns an empty book.
+func NewBook() *Book {
+	return &Book{moves: make(map[uint64]uint8)}
+}
+
+// Len returns the number of positions in the book.
+func (b *Book) Len() int {
+	return len(b.moves)
+}
+
+// Add records col as the move to play in pos, replacing any earlier entry.
+func (b *Book) Add(pos game.Position, col int) {
+	if mirrored(pos) {
+		col = pos.Rules().Columns - 1 - col
+	}
+	b.moves[pos.CanonicalHash()] = uint8(col)
+}
+
+// Lookup returns the book move for pos, if the book has one.
+func (b *Book) Lookup(pos game.Position) (game.Ply, bool) {
+	col, ok := b.moves[pos.CanonicalHash()]
+	if !ok {
+		return game.Ply{}, false
+	}
+
+	ply := game.Ply{Kind: game.DROP, Column: int(col)}
+	if mirrored(pos) {
+		ply.Column = pos.Rules().Columns - 1 - ply.Column
+	}
+	if !pos.CanPlay(ply.Column) {
+		return game.Ply{}, false
+	}
+	return ply, true
+}
+
+// mirrored reports whether the canonical form of pos is its mirror image.
+func mirrored(pos game.Position) bool {
+	return pos.MirrorHash() < pos.Hash()
+}
+
+// MarshalBinary encodes the book in the format read by UnmarshalBook.
+func (b *Book) MarshalBinary() ([]byte, error) {
+	keys := make([]uint64, 0, len(b.moves))
+	for key := range b.moves {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	data := make([]byte, bookHeaderLen, bookHeaderLen+len(keys)*bookEntryLen)
+	copy(data, bookMagic)
+	data[len(bookMagic)] = bookVersion
+	binary.LittleEndian.PutUint32(data[len(bookMagic)+1:], uint32(len(keys)))
+	for _, key := range keys {
+		data = binary.LittleEndian.AppendUint64(data, key)
+		data = append(data, b.moves[key])
+	}
+	return data, nil
+}
+
+// UnmarshalBook decodes a book written by MarshalBinary.
+func UnmarshalBook(data []byte) (*Book, error) {
+	if len(data) < bookHeaderLen || string(data[:len(bookMagic)]) != bookMagic {
+		return nil, errors.New("not an opening book")
+	}
+	if version := data[len(bookMagic)]; version != bookVersion {
+		return nil, fmt.Errorf("unsupported opening book version %d", version)
+	}
+
+	count := int(binary.LittleEndian.Uint32(data[len(bookMagic)+1:]))
+	entries := data[bookHeaderLen:]
+	if len(entries) != count*bookEntryLen {
+		return nil, fmt.Errorf("opening book has %d bytes of entries, want %d", len(entries), count*bookEntryLen)
+	}
+
+	book := &Book{moves: make(map[uint64]uint8, count)}
+	for i := 0; i < count; i++ {
+		entry := entries[i*bookEntryLen:]
+		book.moves[binary.LittleEndian.Uint64(entry)] = entry[8]
+	}
+	return book, nil
+}
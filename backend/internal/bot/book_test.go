@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func TestBookRoundTripAndMirror(t *testing.T) {
+	pos := game.NewPosition(game.DefaultRules())
+	pos.Play(1)
+
+	book := NewBook()
+	book.Add(pos, 2)
+
+	data, err := book.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	loaded, err := UnmarshalBook(data)
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if loaded.Len() != 1 {
+		t.Fatalf("expected one entry, got %d", loaded.Len())
+	}
+
+	if ply, ok := loaded.Lookup(pos); !ok || ply != (game.Ply{Kind: game.DROP, Column: 2}) {
+		t.Fatalf("expected book move in column 2, got %v %v", ply, ok)
+	}
+
+	mirror := game.NewPosition(game.DefaultRules())
+	mirror.Play(5)
+	if ply, ok := loaded.Lookup(mirror); !ok || ply.Column != 4 {
+		t.Fatalf("expected mirrored book move in column 4, got %v %v", ply, ok)
+	}
+
+	if _, ok := loaded.Lookup(game.NewPosition(game.DefaultRules())); ok {
+		t.Fatalf("expected no entry for the empty board")
+	}
+}
+
+func TestUnmarshalBookRejectsCorruptData(t *testing.T) {
+	book := NewBook()
+	book.Add(game.NewPosition(game.DefaultRules()), 3)
+	data, _ := book.MarshalBinary()
+
+	for name, corrupt := range map[string][]byte{
+		"magic":     append([]byte("XXXX"), data[4:]...),
+		"version":   append(append([]byte(bookMagic), 9), data[5:]...),
+		"truncated": data[:len(data)-1],
+	} {
+		if _, err := UnmarshalBook(corrupt); err == nil {
+			t.Fatalf("%s: expected corrupt book to be rejected", name)
+		}
+	}
+}
+
+func TestEmbeddedBookDecodes(t *testing.T) {
+	book, err := UnmarshalBook(embeddedBook)
+	if err != nil {
+		t.Fatalf("expected the embedded book to decode: %v", err)
+	}
+	if book.Len() == 0 {
+		t.Fatalf("expected the embedded book to hold positions")
+	}
+}
+
+// askedStrategy records that it was asked for a move and finds none.
+type askedStrategy struct{ asked *bool }
+
+func (s askedStrategy) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	*s.asked = true
+	return game.Ply{}, errors.New("not expected to be asked")
+}
+
+func TestExpertPlaysFromShippedBook(t *testing.T) {
+	expert, _ := DefaultRegistry().Lookup(NameFor(Expert))
+	asked := false
+	registry := NewRegistry()
+	if err := registry.Register(Identity{Name: expert.Name, Strategy: askedStrategy{&asked}, BookPlies: expert.BookPlies}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	gm := game.NewManager()
+	g := createBotGame(t, gm, expert.Name)
+	engine := New(gm, registry)
+
+	// The reply to the first disc is from the search, the one to the third
+	// from the solver.
+	for ply := 1; ply <= 3; ply += 2 {
+		if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 0); err != nil {
+			t.Fatalf("human move: %v", err)
+		}
+		pos, _ := gm.PositionOf(g.ID)
+		want, ok := DefaultBook().Lookup(pos)
+		if !ok {
+			t.Fatalf("expected the shipped book to know the position after %d plies", ply)
+		}
+		if _, _, col, err := engine.TakeTurn(context.Background(), g.ID); err != nil || col != want.Column {
+			t.Fatalf("expected the book move in column %d, got %d err %v", want.Column, col, err)
+		}
+	}
+	if asked {
+		t.Fatalf("expected the book to answer without asking the strategy")
+	}
+}
+
+func TestTakeTurnPlaysBookMoves(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(Identity{Name: "BOT_BOOK", Strategy: Minimax{Depth: 1}, BookPlies: 2}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	gm := game.NewManager()
+	g := createBotGame(t, gm, "BOT_BOOK")
+	if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 3); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+
+	afterOpening, _ := gm.PositionOf(g.ID)
+	book := NewBook()
+	book.Add(afterOpening, 0)
+
+	engine := New(gm, registry)
+	engine.SetBook(book)
+	if _, _, col, err := engine.TakeTurn(context.Background(), g.ID); err != nil || col != 0 {
+		t.Fatalf("expected book move in column 0, got %d err %v", col, err)
+	}
+
+	if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 3); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+	later, _ := gm.PositionOf(g.ID)
+	book.Add(later, 6)
+	if _, _, col, err := engine.TakeTurn(context.Background(), g.ID); err != nil || col == 6 {
+		t.Fatalf("expected the book to be ignored past BookPlies, got column %d err %v", col, err)
+	}
+}
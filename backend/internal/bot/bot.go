@@ -1,76 +1,175 @@
 package bot
 
 import (
+	"context"
 	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
 
 	"github.com/example/connect-four/backend/internal/game"
 )
 
-var preferenceOrder = []int{3, 2, 4, 1, 5, 0, 6}
+// ErrNoMove is returned by TakeTurn when neither the bot's strategy nor the
+// fallback found a move to play.
+var ErrNoMove = errors.New("bot found no move")
 
-// Bot encapsulates simple heuristics to play as the second player.
+// fallbackMoveTime bounds the fallback search after a strategy fails.
+const fallbackMoveTime = 250 * time.Millisecond
+
+// Bot plays the bot's side of games against any bot in its registry, first or
+// second, using that bot's strategy.
 type Bot struct {
-	gm *game.GameManager
+	gm       *game.GameManager
+	registry *Registry
+	book     *Book
+
+	mu  sync.Mutex
+	rng *rand.Rand
 }
 
-// New creates a new bot helper bound to the provided game manager.
-func New(gm *game.GameManager) *Bot {
-	return &Bot{gm: gm}
+// New creates a new bot helper bound to the provided game manager, playing
+// as the bots in registry.
+func New(gm *game.GameManager, registry *Registry) *Bot {
+	return NewWithSeed(gm, registry, time.Now().UnixNano())
 }
 
-// TakeTurn selects a column and applies the move for the bot. It returns the updated game, the move result, and the column played.
-func (b *Bot) TakeTurn(gameID string) (*game.Game, game.MoveResult, int, error) {
-	if b == nil || b.gm == nil {
+// NewWithSeed is New with a fixed random seed, for reproducible play.
+func NewWithSeed(gm *game.GameManager, registry *Registry, seed int64) *Bot {
+	return &Bot{gm: gm, registry: registry, book: DefaultBook(), rng: rand.New(rand.NewSource(seed))}
+}
+
+// SetBook replaces the opening book, which defaults to DefaultBook. A nil
+// book disables it.
+func (b *Bot) SetBook(book *Book) {
+	b.book = book
+}
+
+// Registry returns the bots this Bot plays as.
+func (b *Bot) Registry() *Registry {
+	return b.registry
+}
+
+// TakeTurn selects a move for the game's bot and applies it: from the opening
+// book for the bot's first plies when the book knows the position, and with
+// the bot's strategy otherwise. It returns the updated game, the move result,
+// and the column played. The bot plays as the identity registered under its
+// username; the game's BotLevel only reports that identity's level.
+//
+// Strategies that implement PacedStrategy have their move held back until
+// their think time has passed since the turn began, or ctx is done. The
+// strategy stops thinking once ctx is done and plays the best move it has
+// found by then. If ctx was cancelled rather than timed out, or the game
+// moved on while the bot was thinking, no move is played and the error says
+// why. If the strategy fails for any other reason the bot plays the
+// DefaultLevel search's move instead, or its first legal move, and returns
+// ErrNoMove only when there is none.
+func (b *Bot) TakeTurn(ctx context.Context, gameID string) (*game.Game, game.MoveResult, int, error) {
+	if b == nil || b.gm == nil || b.registry == nil {
 		return nil, game.INVALID, -1, errors.New("bot not configured")
 	}
 
-	current, ok := b.gm.GetGame(gameID)
+	// The game is shared with the players' read loops and clock timers, so
+	// only the manager's locked snapshot is read here.
+	player, pos, ok := b.gm.PlayerToMove(gameID)
 	if !ok {
 		return nil, game.INVALID, -1, errors.New("game not found")
 	}
+	id, ok := b.registry.Lookup(player)
+	if !ok {
+		return nil, game.INVALID, -1, errors.New("no bot to move in this game")
+	}
 
-	if current.Player2 != "BOT" {
-		return current, game.INVALID, -1, errors.New("game is not against bot")
+	start := time.Now()
+	ply, ok := b.bookMove(pos, id)
+	if !ok {
+		var err error
+		if paced, ok := id.Strategy.(PacedStrategy); ok {
+			var delay time.Duration
+			if ply, delay, err = paced.ChoosePacedMove(ctx, pos, b.intn); err == nil {
+				waitUntil(ctx, start.Add(delay))
+			}
+		} else {
+			ply, err = id.Strategy.ChooseMove(ctx, pos, b.intn)
+		}
+		if errors.Is(err, context.Canceled) {
+			return nil, game.INVALID, -1, err
+		}
+		if err != nil {
+			log.Printf("bot: %s strategy failed in game %s, falling back: %v", id.Name, gameID, err)
+			if ply, err = b.fallbackMove(ctx, pos); err != nil {
+				return nil, game.INVALID, -1, err
+			}
+		}
 	}
 
-	col, err := chooseColumn(current.Board)
-	if err != nil {
-		return current, game.INVALID, -1, err
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return nil, game.INVALID, -1, ctx.Err()
+	}
+	if now, ok := b.gm.PositionOf(gameID); !ok || now != pos {
+		return nil, game.INVALID, -1, errors.New("game changed while the bot was thinking")
 	}
 
-	updated, result, err := b.gm.ApplyMove(gameID, current.Player2, col)
-	return updated, result, col, err
+	updated, result, err := b.gm.ApplyMove(gameID, player, ply.Kind, ply.Column)
+	return updated, result, ply.Column, err
 }
 
-func chooseColumn(board [][]int) (int, error) {
-	// Winning move.
-	for col := 0; col < game.Columns; col++ {
-		newBoard, _, err := game.DropDisc(board, col, 2)
-		if err != nil {
-			continue
-		}
-		if game.CheckWin(newBoard, 2) {
-			return col, nil
-		}
+// bookMove looks pos up in the opening book if id still plays from it.
+func (b *Bot) bookMove(pos game.Position, id Identity) (game.Ply, bool) {
+	if b.book == nil || pos.Moves() >= id.BookPlies {
+		return game.Ply{}, false
 	}
+	return b.book.Lookup(pos)
+}
 
-	// Block opponent win.
-	for col := 0; col < game.Columns; col++ {
-		newBoard, _, err := game.DropDisc(board, col, 1)
-		if err != nil {
-			continue
-		}
-		if game.CheckWin(newBoard, 1) {
-			return col, nil
-		}
+// fallbackMove chooses a move for pos when the bot's own strategy failed:
+// the DefaultLevel search's, given a short budget of its own since the
+// strategy may have used up ctx, or else the first legal move.
+func (b *Bot) fallbackMove(ctx context.Context, pos game.Position) (game.Ply, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fallbackMoveTime)
+	defer cancel()
+
+	if ply, err := DefaultLevel.Strategy().ChooseMove(ctx, pos, b.intn); err == nil {
+		return ply, nil
+	}
+	if moves := legalMoves(&pos); len(moves) > 0 {
+		return moves[0], nil
 	}
+	return game.Ply{}, ErrNoMove
+}
 
-	// Preference order.
-	for _, col := range preferenceOrder {
-		if _, _, err := game.DropDisc(board, col, 2); err == nil {
-			return col, nil
-		}
+// waitUntil returns at deadline, or earlier once ctx is done.
+func waitUntil(ctx context.Context, deadline time.Time) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
 	}
+}
+
+// intn draws from the bot's shared random source.
+func (b *Bot) intn(n int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.rng.Intn(n)
+}
 
-	return 0, errors.New("bot has no valid moves")
+// preferenceOrder lists columns from the centre outwards, left before right.
+func preferenceOrder(columns int) []int {
+	order := make([]int, 0, columns)
+	for offset := 0; len(order) < columns; offset++ {
+		left := (columns-1)/2 - offset
+		right := columns/2 + offset
+		if left >= 0 {
+			order = append(order, left)
+		}
+		if right != left && right < columns {
+			order = append(order, right)
+		}
+	}
+	return order
 }
@@ -1,28 +1,43 @@
 package bot
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/example/connect-four/backend/internal/game"
 )
 
+var expertBot = NameFor(Expert)
+
+func createBotGame(t *testing.T, gm *game.GameManager, botName string) *game.Game {
+	t.Helper()
+
+	g, err := gm.CreateGame("human", botName, game.DefaultRules(), game.TimeControl{})
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	return g
+}
+
 func TestBotTakesWinningMove(t *testing.T) {
 	gm := game.NewManager()
-	g := gm.CreateGame("human", "BOT")
+	g := createBotGame(t, gm, expertBot)
 
-	board := make([][]int, game.Rows)
-	for r := range board {
-		board[r] = make([]int, game.Columns)
-	}
+	board := g.Rules.NewBoard()
 	board[game.Rows-1][0] = 2
 	board[game.Rows-1][1] = 2
 	board[game.Rows-1][2] = 2
 
-	g.Board = board
-	g.CurrentTurn = 2
+	pos, err := game.PositionFromBoard(g.Rules, board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	g.SetPosition(pos)
 
-	botEngine := New(gm)
-	updated, result, col, err := botEngine.TakeTurn(g.ID)
+	botEngine := New(gm, DefaultRegistry())
+	updated, result, col, err := botEngine.TakeTurn(context.Background(), g.ID)
 	if err != nil {
 		t.Fatalf("bot move failed: %v", err)
 	}
@@ -35,28 +50,28 @@ func TestBotTakesWinningMove(t *testing.T) {
 	if updated.Board[game.Rows-1][3] != 2 {
 		t.Fatalf("expected bot disc at winning slot")
 	}
-	if updated.Winner == nil || *updated.Winner != "BOT" {
-		t.Fatalf("expected BOT to be recorded as winner")
+	if updated.Winner == nil || *updated.Winner != expertBot {
+		t.Fatalf("expected the bot to be recorded as winner")
 	}
 }
 
 func TestBotBlocksOpponentWin(t *testing.T) {
 	gm := game.NewManager()
-	g := gm.CreateGame("human", "BOT")
+	g := createBotGame(t, gm, expertBot)
 
-	board := make([][]int, game.Rows)
-	for r := range board {
-		board[r] = make([]int, game.Columns)
-	}
+	board := g.Rules.NewBoard()
 	board[game.Rows-1][0] = 1
 	board[game.Rows-1][1] = 1
 	board[game.Rows-1][2] = 1
 
-	g.Board = board
-	g.CurrentTurn = 2
+	pos, err := game.PositionFromBoard(g.Rules, board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	g.SetPosition(pos)
 
-	botEngine := New(gm)
-	updated, result, col, err := botEngine.TakeTurn(g.ID)
+	botEngine := New(gm, DefaultRegistry())
+	updated, result, col, err := botEngine.TakeTurn(context.Background(), g.ID)
 	if err != nil {
 		t.Fatalf("bot move failed: %v", err)
 	}
@@ -76,11 +91,16 @@ func TestBotBlocksOpponentWin(t *testing.T) {
 
 func TestBotPrefersCenter(t *testing.T) {
 	gm := game.NewManager()
-	g := gm.CreateGame("human", "BOT")
-	g.CurrentTurn = 2
+	g := createBotGame(t, gm, expertBot)
+
+	pos, err := game.PositionFromBoard(g.Rules, g.Board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	g.SetPosition(pos)
 
-	botEngine := New(gm)
-	updated, result, col, err := botEngine.TakeTurn(g.ID)
+	botEngine := New(gm, DefaultRegistry())
+	updated, result, col, err := botEngine.TakeTurn(context.Background(), g.ID)
 	if err != nil {
 		t.Fatalf("bot move failed: %v", err)
 	}
@@ -97,3 +117,345 @@ func TestBotPrefersCenter(t *testing.T) {
 		t.Fatalf("expected turn to return to player 1")
 	}
 }
+
+func TestPreferenceOrder(t *testing.T) {
+	cases := map[int][]int{
+		7: {3, 2, 4, 1, 5, 0, 6},
+		8: {3, 4, 2, 5, 1, 6, 0, 7},
+	}
+	for columns, want := range cases {
+		got := preferenceOrder(columns)
+		if len(got) != len(want) {
+			t.Fatalf("columns %d: expected %v, got %v", columns, want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("columns %d: expected %v, got %v", columns, want, got)
+			}
+		}
+	}
+}
+
+func TestChoosePopPrefersWinningPop(t *testing.T) {
+	rules := game.DefaultRules()
+	rules.PopOut = true
+
+	board := rules.NewBoard()
+	copy(board[game.Rows-1], []int{1, 2, 2, 2, 1, 0, 0})
+	copy(board[game.Rows-2], []int{2, 1, 1, 1, 2, 0, 0})
+	board[game.Rows-3][0] = 1
+
+	pos, err := game.PositionFromBoard(rules, board, 1)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+
+	// Popping column 0 completes both players' bottom rows; the popper wins.
+	ply, err := Expert.Strategy().ChooseMove(context.Background(), pos, nil)
+	if err != nil {
+		t.Fatalf("choose move failed: %v", err)
+	}
+	if ply != (game.Ply{Kind: game.POP, Column: 0}) {
+		t.Fatalf("expected winning pop in column 0, got %v", ply)
+	}
+}
+
+func BenchmarkChooseMove(b *testing.B) {
+	pos := game.NewPosition(game.DefaultRules())
+	for _, col := range []int{3, 3, 2, 4, 4, 2, 1} {
+		pos.Play(col)
+	}
+
+	for _, level := range Levels() {
+		b.Run(string(level), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := level.Strategy().ChooseMove(context.Background(), pos, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkChooseMoveOpening(b *testing.B) {
+	pos := game.NewPosition(game.DefaultRules())
+	for i := 0; i < b.N; i++ {
+		if _, err := Expert.Strategy().ChooseMove(context.Background(), pos, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel(""); err != nil || level != DefaultLevel {
+		t.Fatalf("expected empty level to select the default, got %q err %v", level, err)
+	}
+	for _, level := range append(Levels(), MCTS) {
+		if parsed, err := ParseLevel(string(level)); err != nil || parsed != level {
+			t.Fatalf("expected %q to parse, got %q err %v", level, parsed, err)
+		}
+	}
+	if _, err := ParseLevel("grandmaster"); err == nil {
+		t.Fatalf("expected unknown level to be rejected")
+	}
+}
+
+func TestHardLevelStopsOpenThree(t *testing.T) {
+	board := game.DefaultRules().NewBoard()
+	board[game.Rows-1][2] = 1
+	board[game.Rows-1][3] = 1
+	board[game.Rows-1][6] = 2
+
+	pos, err := game.PositionFromBoard(game.DefaultRules(), board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+
+	// Without a disc at 1 or 4, player 1 makes an open three and wins.
+	ply, err := Hard.Strategy().ChooseMove(context.Background(), pos, nil)
+	if err != nil {
+		t.Fatalf("choose move failed: %v", err)
+	}
+	if ply.Column != 1 && ply.Column != 4 {
+		t.Fatalf("expected the bot to stop the open three, got column %d", ply.Column)
+	}
+}
+
+func TestTakeTurnUsesBotStrategy(t *testing.T) {
+	openings := make(map[Level]map[int]bool)
+	for level, seeds := range map[Level]int64{Easy: 20, Expert: 3} {
+		openings[level] = make(map[int]bool)
+		for seed := int64(0); seed < seeds; seed++ {
+			gm := game.NewManager()
+			g := createBotGame(t, gm, NameFor(level))
+			if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 0); err != nil {
+				t.Fatalf("human move: %v", err)
+			}
+
+			_, _, col, err := NewWithSeed(gm, DefaultRegistry(), seed).TakeTurn(context.Background(), g.ID)
+			if err != nil {
+				t.Fatalf("bot move failed: %v", err)
+			}
+			openings[level][col] = true
+		}
+	}
+
+	if len(openings[Expert]) != 1 {
+		t.Fatalf("expected expert to reply deterministically, got %v", openings[Expert])
+	}
+	if len(openings[Easy]) < 2 {
+		t.Fatalf("expected easy to vary its reply, got %v", openings[Easy])
+	}
+}
+
+func TestTakeTurnOpensAsFirstPlayer(t *testing.T) {
+	gm := game.NewManager()
+	g, err := gm.CreateGame("BOT_EXPERT", "human", game.DefaultRules(), game.TimeControl{})
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+
+	engine := New(gm, DefaultRegistry())
+	updated, result, col, err := engine.TakeTurn(context.Background(), g.ID)
+	if err != nil {
+		t.Fatalf("bot opening failed: %v", err)
+	}
+	if result != game.CONTINUE || updated.CurrentTurn != 2 || updated.Moves[0].Player != "BOT_EXPERT" || col < 0 {
+		t.Fatalf("expected the bot to open as player 1, got result %v turn %d moves %+v", result, updated.CurrentTurn, updated.Moves)
+	}
+
+	if _, _, _, err := engine.TakeTurn(context.Background(), g.ID); err == nil {
+		t.Fatalf("expected the bot not to move on the human's turn")
+	}
+}
+
+func TestTakeTurnRejectsUnregisteredBot(t *testing.T) {
+	gm := game.NewManager()
+	g := createBotGame(t, gm, "BOT_UNKNOWN")
+	if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 3); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+
+	if _, result, _, err := New(gm, DefaultRegistry()).TakeTurn(context.Background(), g.ID); err == nil || result != game.INVALID {
+		t.Fatalf("expected unregistered bot to be rejected, got result %v err %v", result, err)
+	}
+}
+
+func TestMinimaxPlaysBestSoFarAtDeadline(t *testing.T) {
+	board := game.DefaultRules().NewBoard()
+	board[game.Rows-1][0] = 2
+	board[game.Rows-1][1] = 2
+	board[game.Rows-1][2] = 2
+
+	pos, err := game.PositionFromBoard(game.DefaultRules(), board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	ply, err := Minimax{Depth: 40}.ChooseMove(ctx, pos, nil)
+	if err != nil {
+		t.Fatalf("choose move failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the search to stop at its deadline, took %s", elapsed)
+	}
+	if ply.Column != 3 {
+		t.Fatalf("expected the shallow search's winning move, got column %d", ply.Column)
+	}
+}
+
+func TestTakeTurnCancelledDoesNotMove(t *testing.T) {
+	gm := game.NewManager()
+	g := createBotGame(t, gm, expertBot)
+	if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 3); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	engine := New(gm, DefaultRegistry())
+	engine.SetBook(nil)
+	if _, _, _, err := engine.TakeTurn(ctx, g.ID); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancelled turn, got %v", err)
+	}
+	if current, _ := gm.GetGame(g.ID); current.CurrentTurn != 2 || len(current.Moves) != 1 {
+		t.Fatalf("expected no bot move after cancellation, got %d moves", len(current.Moves))
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := DefaultRegistry()
+	for _, name := range []string{DefaultBot, "BOT_EASY", "BOT_MEDIUM", "BOT_HARD", "BOT_EXPERT", "BOT_MCTS", "BOT_ROOKIE", "BOT_CLUB", "BOT_SHARK"} {
+		if !r.IsBot(name) {
+			t.Fatalf("expected %s to be registered", name)
+		}
+	}
+	if r.IsBot("alice") {
+		t.Fatalf("expected players not to be bots")
+	}
+	if r.LevelOf("BOT_HARD") != string(Hard) || r.LevelOf(DefaultBot) != string(DefaultLevel) || r.LevelOf("BOT_SHARK") != "" {
+		t.Fatalf("expected level bots to report their level and personalities none")
+	}
+	for _, name := range r.Names() {
+		id, _ := r.Lookup(name)
+		if opens := name == "BOT_HARD" || name == "BOT_EXPERT"; opens != (id.BookPlies > 0) {
+			t.Fatalf("expected only the hard and expert bots to open from the book, %s has BookPlies %d", name, id.BookPlies)
+		}
+	}
+
+	if err := r.Register(Identity{Name: "BOT_CUSTOM", Strategy: Minimax{Depth: 2}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if id, ok := r.Lookup("BOT_CUSTOM"); !ok || id.Strategy != (Minimax{Depth: 2}) {
+		t.Fatalf("expected custom bot strategy, got %+v", id)
+	}
+	if err := r.Register(Identity{Name: "BOT_CUSTOM", Strategy: Minimax{Depth: 4}}); err == nil {
+		t.Fatalf("expected duplicate bot names to be rejected")
+	}
+	if err := r.Register(Identity{Name: "BOT_NIL"}); err == nil {
+		t.Fatalf("expected a strategy to be required")
+	}
+	if names := r.Names(); len(names) != 10 || names[0] != DefaultBot {
+		t.Fatalf("unexpected bot names %v", names)
+	}
+}
+
+// failingStrategy never finds a move.
+type failingStrategy struct{}
+
+func (failingStrategy) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	return game.Ply{}, errors.New("strategy failed")
+}
+
+func TestTakeTurnFallsBackWhenStrategyFails(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(Identity{Name: "BOT_BROKEN", Strategy: failingStrategy{}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	gm := game.NewManager()
+	g := createBotGame(t, gm, "BOT_BROKEN")
+	for i, col := range []int{0, 6, 1, 6, 2} {
+		player := "human"
+		if i%2 == 1 {
+			player = "BOT_BROKEN"
+		}
+		if _, _, err := gm.ApplyMove(g.ID, player, game.DROP, col); err != nil {
+			t.Fatalf("move %d: %v", i+1, err)
+		}
+	}
+
+	updated, result, col, err := New(gm, registry).TakeTurn(context.Background(), g.ID)
+	if err != nil || result != game.CONTINUE {
+		t.Fatalf("expected the fallback to move, got result %v err %v", result, err)
+	}
+	if col != 3 || updated.CurrentTurn != 1 {
+		t.Fatalf("expected the fallback to block the open three in column 3, got column %d", col)
+	}
+}
+
+func TestFallbackMoveWithoutLegalMoves(t *testing.T) {
+	board := game.DefaultRules().NewBoard()
+	for row := range board {
+		for col := range board[row] {
+			board[row][col] = 1 + (col/2+row)%2
+		}
+	}
+	pos, err := game.PositionFromBoard(game.DefaultRules(), board, 1)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+
+	if _, err := New(game.NewManager(), NewRegistry()).fallbackMove(context.Background(), pos); !errors.Is(err, ErrNoMove) {
+		t.Fatalf("expected ErrNoMove on a full board, got %v", err)
+	}
+}
+
+// pacedStrategy drops into column 0 after asking for delay.
+type pacedStrategy struct{ delay time.Duration }
+
+func (s pacedStrategy) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	return game.Ply{Kind: game.DROP, Column: 0}, nil
+}
+
+func (s pacedStrategy) ChoosePacedMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, time.Duration, error) {
+	return game.Ply{Kind: game.DROP, Column: 0}, s.delay, nil
+}
+
+func TestTakeTurnWaitsForPacedStrategies(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(Identity{Name: "BOT_SLOW", Strategy: pacedStrategy{delay: 50 * time.Millisecond}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := registry.Register(Identity{Name: "BOT_SLEEPY", Strategy: pacedStrategy{delay: time.Hour}}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	gm := game.NewManager()
+	slow := createBotGame(t, gm, "BOT_SLOW")
+	if _, _, err := gm.ApplyMove(slow.ID, "human", game.DROP, 3); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+	start := time.Now()
+	if _, _, _, err := New(gm, registry).TakeTurn(context.Background(), slow.ID); err != nil {
+		t.Fatalf("bot move: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected the bot to wait out its think time, moved after %v", elapsed)
+	}
+
+	sleepy := createBotGame(t, gm, "BOT_SLEEPY")
+	if _, _, err := gm.ApplyMove(sleepy.ID, "human", game.DROP, 3); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, result, _, err := New(gm, registry).TakeTurn(ctx, sleepy.ID); err != nil || result != game.CONTINUE {
+		t.Fatalf("expected the bot to move at its deadline, got result %v err %v", result, err)
+	}
+}
@@ -0,0 +1,484 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// ExternalEngine is a Strategy backed by a separate engine process, so that
+// engines written in any language can play on the server. The server and the
+// engine exchange newline-terminated text commands over the engine's stdin
+// and stdout, in the spirit of UCI:
+//
+//	server: c4e 1                    protocol handshake, version 1
+//	engine: id name <name>           optional, any number of id lines
+//	engine: c4eok                    handshake complete
+//
+//	server: position <rows> <columns> <connect> <classic|popout> <turn> <board>
+//	server: go <milliseconds>        think for at most this long
+//	engine: bestmove <move>          e.g. "4", or "p4" to pop column 4
+//
+//	server: quit                     the engine should exit
+//
+// The board lists rows from top to bottom separated by "/", one digit per
+// cell: 0 for empty, 1 or 2 for a player's disc. Columns are 1-based. Engines
+// may print "info ..." lines at any time; they are ignored. Anything the
+// engine writes to stderr is logged.
+//
+// Each copy of the engine thinks about one move at a time, so up to Processes
+// copies are started as games ask for moves at once; further requests wait
+// for a copy to come free. A copy that crashes, hangs past its budget or
+// breaks the protocol is stopped and a fresh one started for the next move.
+type ExternalEngine struct {
+	// Path and Args describe the command that starts the engine.
+	Path string
+	Args []string
+
+	// MoveTime is the thinking budget sent with every go command. It is
+	// shortened when the caller's context has an earlier deadline.
+	MoveTime time.Duration
+	// Grace is how much longer than MoveTime the engine may take to answer.
+	Grace time.Duration
+	// HandshakeTimeout bounds how long the engine may take to start up.
+	HandshakeTimeout time.Duration
+	// Processes caps how many copies of the engine run at once.
+	Processes int
+
+	mu       sync.Mutex
+	slots    chan struct{}
+	idle     []*engineProcess
+	closed   bool
+	name     string
+	restarts int
+}
+
+const (
+	defaultEngineMoveTime  = time.Second
+	defaultEngineGrace     = 500 * time.Millisecond
+	defaultEngineHandshake = 5 * time.Second
+	minEngineMoveTime      = 10 * time.Millisecond
+	defaultEngineProcesses = 4
+)
+
+// RegisterEngines adds an ExternalEngine to r for every entry of spec, a
+// semicolon-separated list of name=command entries such as
+// "BOT_RUST=/opt/engines/c4 --threads 2". The engines are returned so the
+// caller can close them on shutdown.
+func RegisterEngines(r *Registry, spec string) ([]*ExternalEngine, error) {
+	var engines []*ExternalEngine
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, command, ok := strings.Cut(entry, "=")
+		fields := strings.Fields(command)
+		if !ok || len(fields) == 0 {
+			return nil, fmt.Errorf("engine entry %q must look like NAME=command", entry)
+		}
+
+		engine := NewExternalEngine(fields[0], fields[1:]...)
+		if err := r.Register(Identity{Name: strings.TrimSpace(name), Strategy: engine}); err != nil {
+			return nil, err
+		}
+		engines = append(engines, engine)
+	}
+	return engines, nil
+}
+
+// errEngineTimeout is returned when the engine does not answer in time.
+var errEngineTimeout = errors.New("engine did not answer in time")
+
+// EngineError reports that an external engine gave no usable move: it could
+// not be started, crashed, broke the protocol, played an illegal move or ran
+// out of time. Callers can fall back to a built-in strategy.
+type EngineError struct {
+	Path string
+	Err  error
+}
+
+func (e *EngineError) Error() string {
+	return fmt.Sprintf("engine %s: %v", e.Path, e.Err)
+}
+
+func (e *EngineError) Unwrap() error {
+	return e.Err
+}
+
+// NewExternalEngine returns an engine that runs path with args. The process
+// is started on first use.
+func NewExternalEngine(path string, args ...string) *ExternalEngine {
+	return &ExternalEngine{
+		Path:             path,
+		Args:             args,
+		MoveTime:         defaultEngineMoveTime,
+		Grace:            defaultEngineGrace,
+		HandshakeTimeout: defaultEngineHandshake,
+		Processes:        defaultEngineProcesses,
+	}
+}
+
+// engineProcess is one run of the engine. Lines read from stdout arrive on
+// lines, which is closed when the engine exits. done is closed when the
+// server stops the engine.
+type engineProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+	done  chan struct{}
+}
+
+// Start launches a copy of the engine and completes the handshake if none is
+// idle yet.
+func (e *ExternalEngine) Start() error {
+	e.mu.Lock()
+	running := len(e.idle) > 0
+	e.mu.Unlock()
+	if running {
+		return nil
+	}
+
+	proc, err := e.launch(context.Background())
+	if err != nil {
+		return err
+	}
+	e.putIdle(proc)
+	return nil
+}
+
+// Name returns the name the engine gave in its handshake, if any.
+func (e *ExternalEngine) Name() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.name
+}
+
+// Restarts returns how many times the engine had to be restarted after
+// crashing or misbehaving.
+func (e *ExternalEngine) Restarts() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.restarts
+}
+
+// Close asks the idle copies of the engine to quit and stops them. Copies
+// busy with a move are stopped once they answer.
+func (e *ExternalEngine) Close() error {
+	e.mu.Lock()
+	idle := e.idle
+	e.idle = nil
+	e.closed = true
+	e.mu.Unlock()
+
+	for _, proc := range idle {
+		_ = proc.send("quit")
+		proc.stop()
+	}
+	return nil
+}
+
+// ChooseMove implements Strategy. The engine gets one retry after a restart
+// if it fails and ctx leaves time for one; intn is unused. Failures are
+// returned as an *EngineError, except that a cancelled ctx returns its error.
+func (e *ExternalEngine) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	ply, err := e.chooseMove(ctx, pos)
+	if err != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		return game.Ply{}, &EngineError{Path: e.Path, Err: err}
+	}
+	return ply, err
+}
+
+func (e *ExternalEngine) chooseMove(ctx context.Context, pos game.Position) (game.Ply, error) {
+	slots, err := e.acquire(ctx)
+	if err != nil {
+		return game.Ply{}, err
+	}
+	defer func() { <-slots }()
+
+	proc, err := e.takeIdle(ctx)
+	if err == nil {
+		var ply game.Ply
+		if ply, err = e.requestMove(ctx, proc, pos); err == nil {
+			e.putIdle(proc)
+			return ply, nil
+		}
+		// The engine may still answer the failed request; start afresh
+		// rather than read a stale bestmove.
+		proc.stop()
+	}
+	if ctx.Err() != nil {
+		return game.Ply{}, err
+	}
+
+	log.Printf("bot: engine %s failed, restarting: %v", e.Path, err)
+	e.mu.Lock()
+	e.restarts++
+	e.mu.Unlock()
+
+	if proc, err = e.launch(ctx); err != nil {
+		return game.Ply{}, err
+	}
+	ply, err := e.requestMove(ctx, proc, pos)
+	if err != nil {
+		proc.stop()
+		return game.Ply{}, err
+	}
+	e.putIdle(proc)
+	return ply, nil
+}
+
+// acquire waits until fewer than Processes moves are in progress and returns
+// the channel to receive from once the move is done.
+func (e *ExternalEngine) acquire(ctx context.Context) (chan struct{}, error) {
+	e.mu.Lock()
+	if e.slots == nil {
+		e.slots = make(chan struct{}, max(e.Processes, 1))
+	}
+	slots := e.slots
+	e.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return slots, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// takeIdle returns an idle copy of the engine, launching one if there is none.
+func (e *ExternalEngine) takeIdle(ctx context.Context) (*engineProcess, error) {
+	e.mu.Lock()
+	if n := len(e.idle); n > 0 {
+		proc := e.idle[n-1]
+		e.idle = e.idle[:n-1]
+		e.mu.Unlock()
+		return proc, nil
+	}
+	e.mu.Unlock()
+
+	return e.launch(ctx)
+}
+
+// putIdle keeps proc for the next move, or stops it if the engine was closed.
+func (e *ExternalEngine) putIdle(proc *engineProcess) {
+	e.mu.Lock()
+	if !e.closed {
+		e.idle = append(e.idle, proc)
+		e.mu.Unlock()
+		return
+	}
+	e.mu.Unlock()
+
+	_ = proc.send("quit")
+	proc.stop()
+}
+
+// requestMove asks proc for a move in pos and checks that the move is legal.
+// The engine's budget and grace are cut short to answer before ctx's
+// deadline; when that is nearer than Grace, the time left is split between
+// them.
+func (e *ExternalEngine) requestMove(ctx context.Context, proc *engineProcess, pos game.Position) (game.Ply, error) {
+	budget, grace := e.MoveTime, e.Grace
+	if deadline, ok := ctx.Deadline(); ok {
+		left := time.Until(deadline)
+		grace = min(grace, left/2)
+		budget = min(budget, max(left-grace, minEngineMoveTime))
+	}
+	if err := proc.send(positionCommand(pos)); err != nil {
+		return game.Ply{}, err
+	}
+	if err := proc.send(fmt.Sprintf("go %d", budget.Milliseconds())); err != nil {
+		return game.Ply{}, err
+	}
+
+	line, err := proc.expect(ctx, "bestmove", budget+grace)
+	if err != nil {
+		return game.Ply{}, err
+	}
+	ply, err := parseEngineMove(strings.TrimSpace(strings.TrimPrefix(line, "bestmove")))
+	if err != nil {
+		return game.Ply{}, err
+	}
+
+	if (ply.Kind == game.DROP && !pos.CanPlay(ply.Column)) || (ply.Kind == game.POP && !pos.CanPop(ply.Column)) {
+		return game.Ply{}, fmt.Errorf("engine played illegal move %q", line)
+	}
+	return ply, nil
+}
+
+// launch starts a new copy of the engine and runs the handshake, giving up
+// once ctx is done.
+func (e *ExternalEngine) launch(ctx context.Context) (*engineProcess, error) {
+	cmd := exec.Command(e.Path, e.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start engine %s: %w", e.Path, err)
+	}
+
+	proc := &engineProcess{cmd: cmd, stdin: stdin, lines: make(chan string, 16), done: make(chan struct{})}
+	go proc.readLines(stdout)
+	go logEngineStderr(e.Path, stderr)
+
+	name, err := proc.handshake(ctx, e.HandshakeTimeout)
+	if err != nil {
+		proc.stop()
+		return nil, err
+	}
+	if name != "" {
+		e.mu.Lock()
+		e.name = name
+		e.mu.Unlock()
+	}
+	return proc, nil
+}
+
+// handshake runs the protocol handshake and returns the name the engine
+// gave, if any.
+func (p *engineProcess) handshake(ctx context.Context, timeout time.Duration) (string, error) {
+	if err := p.send("c4e 1"); err != nil {
+		return "", err
+	}
+
+	var name string
+	deadline := time.Now().Add(timeout)
+	for {
+		line, err := p.next(ctx, time.Until(deadline))
+		if err != nil {
+			return "", fmt.Errorf("engine handshake: %w", err)
+		}
+		switch {
+		case line == "c4eok":
+			return name, nil
+		case strings.HasPrefix(line, "id name "):
+			name = strings.TrimPrefix(line, "id name ")
+		}
+	}
+}
+
+// stop kills the engine process.
+func (p *engineProcess) stop() {
+	close(p.done)
+	_ = p.stdin.Close()
+	_ = p.cmd.Process.Kill()
+	_ = p.cmd.Wait()
+}
+
+func (p *engineProcess) send(command string) error {
+	if _, err := io.WriteString(p.stdin, command+"\n"); err != nil {
+		return fmt.Errorf("write to engine: %w", err)
+	}
+	return nil
+}
+
+// next waits up to timeout for the next line from the engine, giving up early
+// once ctx is done.
+func (p *engineProcess) next(ctx context.Context, timeout time.Duration) (string, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			return "", errors.New("engine exited")
+		}
+		return line, nil
+	case <-timer.C:
+		return "", errEngineTimeout
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// expect skips lines until one starts with keyword, timeout passes or ctx is
+// done.
+func (p *engineProcess) expect(ctx context.Context, keyword string, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		line, err := p.next(ctx, time.Until(deadline))
+		if err != nil {
+			return "", err
+		}
+		if line == keyword || strings.HasPrefix(line, keyword+" ") {
+			return line, nil
+		}
+	}
+}
+
+func (p *engineProcess) readLines(stdout io.Reader) {
+	defer close(p.lines)
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		select {
+		case p.lines <- strings.TrimSpace(scanner.Text()):
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func logEngineStderr(path string, stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("bot: engine %s stderr: %s", path, scanner.Text())
+	}
+}
+
+// positionCommand renders pos as a position command.
+func positionCommand(pos game.Position) string {
+	rules := pos.Rules()
+	variant := "classic"
+	if rules.PopOut {
+		variant = "popout"
+	}
+
+	rows := make([]string, rules.Rows)
+	for row := range rows {
+		var cells strings.Builder
+		for col := 0; col < rules.Columns; col++ {
+			cells.WriteByte(byte('0' + pos.Cell(row, col)))
+		}
+		rows[row] = cells.String()
+	}
+
+	return fmt.Sprintf("position %d %d %d %s %d %s", rules.Rows, rules.Columns, rules.Connect, variant, pos.Turn(), strings.Join(rows, "/"))
+}
+
+// parseEngineMove decodes a 1-based column, prefixed with "p" for a pop.
+func parseEngineMove(move string) (game.Ply, error) {
+	ply := game.Ply{Kind: game.DROP}
+	if rest, ok := strings.CutPrefix(move, "p"); ok {
+		ply.Kind = game.POP
+		move = rest
+	}
+
+	col, err := strconv.Atoi(move)
+	if err != nil {
+		return game.Ply{}, fmt.Errorf("engine sent invalid move %q", move)
+	}
+	ply.Column = col - 1
+	return ply, nil
+}
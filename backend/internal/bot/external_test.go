@@ -0,0 +1,242 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// fakeEnginePath is the fake engine built from testdata/fakeengine, or empty
+// if it could not be built.
+var fakeEnginePath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "fakeengine")
+	if err != nil {
+		log.Fatalf("create temp dir: %v", err)
+	}
+
+	path := filepath.Join(dir, "fakeengine")
+	if out, err := exec.Command("go", "build", "-o", path, "./testdata/fakeengine").CombinedOutput(); err != nil {
+		log.Printf("build fake engine: %v: %s", err, out)
+	} else {
+		fakeEnginePath = path
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestEngine(t *testing.T, args ...string) *ExternalEngine {
+	t.Helper()
+
+	if fakeEnginePath == "" {
+		t.Skip("fake engine unavailable")
+	}
+	engine := NewExternalEngine(fakeEnginePath, args...)
+	engine.MoveTime = 100 * time.Millisecond
+	engine.Grace = 100 * time.Millisecond
+	engine.HandshakeTimeout = time.Second
+	t.Cleanup(func() { _ = engine.Close() })
+	return engine
+}
+
+func TestExternalEnginePlaysMove(t *testing.T) {
+	engine := newTestEngine(t)
+
+	pos := game.NewPosition(game.DefaultRules())
+	for i := 0; i < game.Rows; i++ {
+		pos.Play(0)
+	}
+
+	ply, err := engine.ChooseMove(context.Background(), pos, nil)
+	if err != nil {
+		t.Fatalf("choose move: %v", err)
+	}
+	if ply != (game.Ply{Kind: game.DROP, Column: 1}) {
+		t.Fatalf("expected the engine to drop into column 1, got %v", ply)
+	}
+	if engine.Name() != "fake" {
+		t.Fatalf("expected engine name from handshake, got %q", engine.Name())
+	}
+	if engine.Restarts() != 0 {
+		t.Fatalf("expected no restarts, got %d", engine.Restarts())
+	}
+}
+
+func TestExternalEngineRestartsAfterCrash(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "crashed")
+	engine := newTestEngine(t, "-crash-once", marker)
+
+	ply, err := engine.ChooseMove(context.Background(), game.NewPosition(game.DefaultRules()), nil)
+	if err != nil {
+		t.Fatalf("expected the restarted engine to answer, got %v", err)
+	}
+	if ply.Column != 0 {
+		t.Fatalf("expected column 0, got %v", ply)
+	}
+	if engine.Restarts() != 1 {
+		t.Fatalf("expected one restart, got %d", engine.Restarts())
+	}
+}
+
+func TestExternalEngineTimesOut(t *testing.T) {
+	engine := newTestEngine(t, "-mode", "hang")
+
+	started := time.Now()
+	if _, err := engine.ChooseMove(context.Background(), game.NewPosition(game.DefaultRules()), nil); err == nil {
+		t.Fatalf("expected a hanging engine to fail")
+	}
+	// One try and one retry, each bounded by MoveTime plus Grace.
+	if elapsed := time.Since(started); elapsed > 2*time.Second {
+		t.Fatalf("expected the move timeout to stop the engine, took %s", elapsed)
+	}
+}
+
+func TestExternalEngineThinksAboutMovesConcurrently(t *testing.T) {
+	engine := newTestEngine(t, "-delay", "300ms")
+	engine.MoveTime = time.Second
+	engine.Processes = 3
+
+	started := time.Now()
+	var wg sync.WaitGroup
+	errs := make(chan error, engine.Processes)
+	for i := 0; i < engine.Processes; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := engine.ChooseMove(context.Background(), game.NewPosition(game.DefaultRules()), nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("choose move: %v", err)
+		}
+	}
+	// Three moves one after another would take at least 900ms.
+	if elapsed := time.Since(started); elapsed > 800*time.Millisecond {
+		t.Fatalf("expected the moves to be thought about at once, took %s", elapsed)
+	}
+	if engine.Restarts() != 0 {
+		t.Fatalf("expected no restarts, got %d", engine.Restarts())
+	}
+}
+
+func TestExternalEngineKeepsToShortDeadlines(t *testing.T) {
+	// Both deadlines are shorter than the engine's Grace.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+	if _, err := newTestEngine(t).ChooseMove(ctx, game.NewPosition(game.DefaultRules()), nil); err != nil {
+		t.Fatalf("expected a quick engine to answer within the deadline, got %v", err)
+	}
+
+	silent := newTestEngine(t, "-mode", "silent")
+	silent.HandshakeTimeout = 5 * time.Second
+	ctx, cancel = context.WithTimeout(context.Background(), 60*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := silent.ChooseMove(ctx, game.NewPosition(game.DefaultRules()), nil)
+	var engineErr *EngineError
+	if !errors.As(err, &engineErr) {
+		t.Fatalf("expected an EngineError, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected the handshake to give up at the move deadline, took %s", elapsed)
+	}
+}
+
+func TestExternalEngineRejectsBadEngines(t *testing.T) {
+	if err := newTestEngine(t, "-mode", "silent").Start(); err == nil {
+		t.Fatalf("expected an engine without handshake to fail to start")
+	}
+	if _, err := newTestEngine(t, "-mode", "illegal").ChooseMove(context.Background(), game.NewPosition(game.DefaultRules()), nil); err == nil {
+		t.Fatalf("expected an illegal move to be rejected")
+	}
+
+	missing := NewExternalEngine(filepath.Join(t.TempDir(), "missing"))
+	if err := missing.Start(); err == nil {
+		t.Fatalf("expected a missing engine binary to fail to start")
+	}
+}
+
+func TestExternalEngineLogsStderr(t *testing.T) {
+	var buf bytes.Buffer
+	var mu sync.Mutex
+	log.SetOutput(writerFunc(func(p []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return buf.Write(p)
+	}))
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	if err := newTestEngine(t).Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		logged := strings.Contains(buf.String(), "fake engine ready")
+		mu.Unlock()
+		if logged {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected engine stderr to be logged")
+}
+
+func TestPositionCommand(t *testing.T) {
+	rules := game.Rules{Rows: 4, Columns: 5, Connect: 3, PopOut: true}
+	pos := game.NewPosition(rules)
+	pos.Play(2)
+	pos.Play(2)
+	pos.Play(4)
+
+	want := "position 4 5 3 popout 2 00000/00000/00200/00101"
+	if got := positionCommand(pos); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRegisterEngines(t *testing.T) {
+	r := NewRegistry()
+	engines, err := RegisterEngines(r, "BOT_RUST=/opt/engines/c4 --threads 2; BOT_PY=python3 engine.py;")
+	if err != nil {
+		t.Fatalf("register engines: %v", err)
+	}
+	if len(engines) != 2 || !r.IsBot("BOT_RUST") || !r.IsBot("BOT_PY") {
+		t.Fatalf("expected two engines to be registered, got %v", r.Names())
+	}
+	if engines[0].Path != "/opt/engines/c4" || strings.Join(engines[0].Args, " ") != "--threads 2" {
+		t.Fatalf("unexpected engine command %s %v", engines[0].Path, engines[0].Args)
+	}
+
+	for _, spec := range []string{"BOT_RUST", "BOT_RUST=", "=engine", "BOT_PY=a;BOT_PY=b"} {
+		if _, err := RegisterEngines(NewRegistry(), spec); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+type writerFunc func([]byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	return f(p)
+}
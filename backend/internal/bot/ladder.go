@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"log"
+	"sync"
+)
+
+// LevelStore keeps each player's level on the Ladder between restarts.
+// GetBotLevel returns an empty level for players without one, and an error
+// only when the store cannot say.
+type LevelStore interface {
+	GetBotLevel(username string) (string, error)
+	SetBotLevel(username, level string) error
+}
+
+// Ladder picks the alpha-beta level each player meets when they leave the
+// choice of bot to the server, and moves it after every decisive game against
+// that level: up a level when the player wins and down one when they lose.
+// This staircase settles where the player wins about half their games. It is
+// safe for concurrent use, and never holds its lock while waiting on the
+// store.
+type Ladder struct {
+	store LevelStore
+
+	mu     sync.Mutex
+	levels map[string]Level
+
+	// saveMu orders writes to the store, so the last one holds the latest
+	// level.
+	saveMu sync.Mutex
+}
+
+// NewLadder returns a Ladder keeping levels in store, or only in memory if
+// store is nil. Players start at DefaultLevel.
+func NewLadder(store LevelStore) *Ladder {
+	return &Ladder{store: store, levels: make(map[string]Level)}
+}
+
+// Level returns the level player currently meets.
+func (l *Ladder) Level(player string) Level {
+	level, _ := l.load(player)
+	return level
+}
+
+// BotFor returns the name and level of the bot player currently meets.
+func (l *Ladder) BotFor(player string) (string, string) {
+	level := l.Level(player)
+	return NameFor(level), string(level)
+}
+
+// Record moves player along the ladder after a game against botName that
+// scored score for the player: 1 for a win, a half for a draw and 0 for a
+// loss. Games against any bot other than the player's current level leave
+// the ladder alone, as do games while the store cannot say what that level
+// is. It returns the player's level afterwards.
+func (l *Ladder) Record(player, botName string, score float64) Level {
+	current, known := l.load(player)
+	if !known || botName != NameFor(current) {
+		return current
+	}
+
+	ladder := Levels()
+	rung := 0
+	for i, level := range ladder {
+		if level == current {
+			rung = i
+		}
+	}
+	switch {
+	case score > 0.5:
+		rung = min(rung+1, len(ladder)-1)
+	case score < 0.5:
+		rung = max(rung-1, 0)
+	}
+
+	next := ladder[rung]
+	l.mu.Lock()
+	if latest := l.levels[player]; latest != current || next == current {
+		// Unchanged, or another game moved the player meanwhile.
+		l.mu.Unlock()
+		return latest
+	}
+	l.levels[player] = next
+	l.mu.Unlock()
+
+	l.save(player)
+	return next
+}
+
+// load returns player's level, reading it from the store the first time.
+// known is false when the store could not say; the player then meets
+// DefaultLevel for now and is looked up again next time.
+func (l *Ladder) load(player string) (level Level, known bool) {
+	l.mu.Lock()
+	level, ok := l.levels[player]
+	l.mu.Unlock()
+	if ok {
+		return level, true
+	}
+
+	level = DefaultLevel
+	if l.store != nil {
+		stored, err := l.store.GetBotLevel(player)
+		if err != nil {
+			return DefaultLevel, false
+		}
+		if parsed, err := ParseLevel(stored); err == nil && parsed != MCTS {
+			level = parsed
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cached, ok := l.levels[player]; ok {
+		return cached, true
+	}
+	l.levels[player] = level
+	return level, true
+}
+
+// save writes player's current level to the store.
+func (l *Ladder) save(player string) {
+	if l.store == nil {
+		return
+	}
+
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	l.mu.Lock()
+	level := l.levels[player]
+	l.mu.Unlock()
+	if err := l.store.SetBotLevel(player, string(level)); err != nil {
+		log.Printf("bot: store level for %s failed: %v", player, err)
+	}
+}
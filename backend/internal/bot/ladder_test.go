@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+)
+
+type memoryLevels map[string]string
+
+func (m memoryLevels) GetBotLevel(username string) (string, error) {
+	return m[username], nil
+}
+
+func (m memoryLevels) SetBotLevel(username, level string) error {
+	m[username] = level
+	return nil
+}
+
+func TestLadderFollowsResults(t *testing.T) {
+	store := memoryLevels{"veteran": string(Expert)}
+	ladder := NewLadder(store)
+
+	if name, level := ladder.BotFor("newcomer"); name != NameFor(DefaultLevel) || level != string(DefaultLevel) {
+		t.Fatalf("expected newcomers to start at %s, got %s (%s)", DefaultLevel, name, level)
+	}
+	if level := ladder.Level("veteran"); level != Expert {
+		t.Fatalf("expected the stored level, got %s", level)
+	}
+
+	steps := []struct {
+		bot   string
+		score float64
+		want  Level
+	}{
+		{NameFor(Medium), 1, Hard},
+		{NameFor(Hard), 0.5, Hard},
+		{NameFor(Hard), 1, Expert},
+		{NameFor(Expert), 1, Expert},
+		{NameFor(Easy), 0, Expert}, // not the player's level
+		{NameFor(Expert), 0, Hard},
+		{NameFor(Hard), 0, Medium},
+		{NameFor(Medium), 0, Easy},
+		{NameFor(Easy), 0, Easy},
+	}
+	for i, step := range steps {
+		if got := ladder.Record("newcomer", step.bot, step.score); got != step.want {
+			t.Fatalf("step %d: expected %s, got %s", i+1, step.want, got)
+		}
+	}
+	if store["newcomer"] != string(Easy) {
+		t.Fatalf("expected the level to be stored, got %q", store["newcomer"])
+	}
+
+	if level := NewLadder(store).Level("newcomer"); level != Easy {
+		t.Fatalf("expected a new ladder to load the stored level, got %s", level)
+	}
+}
+
+// countingLevels counts lookups and fails them while down is set.
+type countingLevels struct {
+	memoryLevels
+	down    bool
+	lookups int
+}
+
+func (c *countingLevels) GetBotLevel(username string) (string, error) {
+	c.lookups++
+	if c.down {
+		return "", errors.New("store unavailable")
+	}
+	return c.memoryLevels.GetBotLevel(username)
+}
+
+func TestLadderCachesLevelsButNotFailures(t *testing.T) {
+	store := &countingLevels{memoryLevels: memoryLevels{"veteran": string(Expert)}, down: true}
+	ladder := NewLadder(store)
+
+	if level := ladder.Level("veteran"); level != DefaultLevel {
+		t.Fatalf("expected the default level while the store is down, got %s", level)
+	}
+	if level := ladder.Record("veteran", NameFor(DefaultLevel), 1); level != DefaultLevel {
+		t.Fatalf("expected no move while the level is unknown, got %s", level)
+	}
+	if store.memoryLevels["veteran"] != string(Expert) {
+		t.Fatalf("expected the stored level to survive, got %q", store.memoryLevels["veteran"])
+	}
+
+	store.down = false
+	for i := 0; i < 3; i++ {
+		ladder.Level("veteran")
+		ladder.Level("newcomer")
+	}
+	if level := ladder.Level("veteran"); level != Expert {
+		t.Fatalf("expected the stored level once the store is back, got %s", level)
+	}
+	if store.lookups != 4 {
+		t.Fatalf("expected each player looked up once after the outage, got %d lookups", store.lookups)
+	}
+}
@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+)
+
+// Level is a bot difficulty a player can choose.
+type Level string
+
+const (
+	Easy   Level = "easy"
+	Medium Level = "medium"
+	Hard   Level = "hard"
+	Expert Level = "expert"
+	// MCTS plays by Monte Carlo Tree Search rather than alpha-beta. It needs
+	// no evaluation function, so it holds up on non-standard boards.
+	MCTS Level = "mcts"
+)
+
+// DefaultLevel is used for games that did not ask for a level.
+const DefaultLevel = Medium
+
+var levels = map[Level]Strategy{
+	Easy:   Minimax{Depth: 1, Noise: 40},
+	Medium: Minimax{Depth: 3, Noise: 12},
+	Hard:   Minimax{Depth: 6, Noise: 3},
+	Expert: Minimax{Depth: 10},
+	MCTS:   MonteCarlo{Iterations: 20000, TimeLimit: 500 * time.Millisecond},
+}
+
+// bookPlies is how far into a game each level plays from the opening book.
+// The weaker levels stay off it so that their openings remain beatable.
+var bookPlies = map[Level]int{Hard: DefaultBookPlies, Expert: DefaultBookPlies}
+
+// Levels lists the alpha-beta levels from weakest to strongest. MCTS is not
+// part of the ladder.
+func Levels() []Level {
+	return []Level{Easy, Medium, Hard, Expert}
+}
+
+// ParseLevel validates a level name. An empty name selects DefaultLevel.
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return DefaultLevel, nil
+	}
+	if _, ok := levels[Level(name)]; !ok {
+		return "", fmt.Errorf("unknown bot level %q", name)
+	}
+	return Level(name), nil
+}
+
+// Strategy returns the strategy for l, falling back to DefaultLevel.
+func (l Level) Strategy() Strategy {
+	if s, ok := levels[l]; ok {
+		return s
+	}
+	return levels[DefaultLevel]
+}
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// exploration is the UCT constant balancing well-scoring moves against
+// rarely tried ones.
+const exploration = 1.4
+
+// mctsNode is a position in the search tree, reached by ply.
+type mctsNode struct {
+	ply      game.Ply
+	parent   *mctsNode
+	children []*mctsNode
+	untried  []game.Ply
+
+	// mover made ply; wins counts results for mover, a draw as half a win.
+	mover  int
+	visits int
+	wins   float64
+
+	terminal bool
+	winner   int
+}
+
+// chooseMCTSMove runs Monte Carlo Tree Search from pos and plays the most
+// visited move. The search stops after iterations playouts, once timeLimit
+// has passed or once ctx is done, whichever comes first; a zero iterations or
+// timeLimit disables that limit.
+func chooseMCTSMove(ctx context.Context, pos game.Position, iterations int, timeLimit time.Duration, intn func(int) int) (game.Ply, error) {
+	if iterations <= 0 && timeLimit <= 0 {
+		return game.Ply{}, errors.New("search needs an iteration or time limit")
+	}
+	root := &mctsNode{mover: 3 - pos.Turn(), untried: legalMoves(&pos)}
+	if len(root.untried) == 0 {
+		return game.Ply{}, errors.New("bot has no valid moves")
+	}
+
+	var deadline time.Time
+	if timeLimit > 0 {
+		deadline = time.Now().Add(timeLimit)
+	}
+	for i := 0; iterations <= 0 || i < iterations; i++ {
+		if i > 0 && (ctx.Err() != nil || (!deadline.IsZero() && time.Now().After(deadline))) {
+			break
+		}
+
+		node, state := root, pos
+		for len(node.untried) == 0 && len(node.children) > 0 {
+			node = node.selectChild()
+			_ = state.Apply(node.ply)
+		}
+		if len(node.untried) > 0 && !node.terminal {
+			node = node.expand(&state, intn)
+		}
+
+		winner := node.winner
+		if !node.terminal {
+			winner = playout(state, intn)
+		}
+		for ; node != nil; node = node.parent {
+			node.visits++
+			switch winner {
+			case node.mover:
+				node.wins++
+			case 0:
+				node.wins += 0.5
+			}
+		}
+	}
+
+	best := root.children[0]
+	for _, child := range root.children[1:] {
+		if child.visits > best.visits {
+			best = child
+		}
+	}
+	return best.ply, nil
+}
+
+// selectChild picks the child with the highest UCT value.
+func (n *mctsNode) selectChild() *mctsNode {
+	logVisits := math.Log(float64(n.visits))
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	for _, child := range n.children {
+		value := child.wins/float64(child.visits) + exploration*math.Sqrt(logVisits/float64(child.visits))
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
+
+// expand plays a random untried move on state and adds its node.
+func (n *mctsNode) expand(state *game.Position, intn func(int) int) *mctsNode {
+	i := intn(len(n.untried))
+	ply := n.untried[i]
+	n.untried[i] = n.untried[len(n.untried)-1]
+	n.untried = n.untried[:len(n.untried)-1]
+
+	mover := state.Turn()
+	_ = state.Apply(ply)
+	child := &mctsNode{ply: ply, parent: n, mover: mover}
+	child.winner, child.terminal = gameOver(state, mover)
+	if !child.terminal {
+		child.untried = legalMoves(state)
+	}
+	n.children = append(n.children, child)
+	return child
+}
+
+// playout finishes the game from pos with lightly guided random moves: take
+// an immediate win, otherwise block the opponent's, otherwise play at random.
+// It returns the winner, or 0 for a draw. PopOut games that run on past the
+// number of cells on the board count as draws.
+func playout(pos game.Position, intn func(int) int) int {
+	columns := pos.Rules().Columns
+	for steps := 0; steps < 2*pos.Rules().Cells(); steps++ {
+		mover := pos.Turn()
+		ply, found := game.Ply{}, false
+		for _, player := range []int{mover, 3 - mover} {
+			for col := 0; col < columns && !found; col++ {
+				if pos.IsWinningMove(col, player) {
+					ply, found = game.Ply{Kind: game.DROP, Column: col}, true
+				}
+			}
+		}
+		if !found {
+			var moves [2 * game.MaxColumns]game.Ply
+			n := 0
+			for col := 0; col < columns; col++ {
+				if pos.CanPlay(col) {
+					moves[n] = game.Ply{Kind: game.DROP, Column: col}
+					n++
+				}
+				if pos.CanPop(col) {
+					moves[n] = game.Ply{Kind: game.POP, Column: col}
+					n++
+				}
+			}
+			ply = moves[intn(n)]
+		}
+
+		_ = pos.Apply(ply)
+		if winner, over := gameOver(&pos, mover); over {
+			return winner
+		}
+	}
+	return 0
+}
+
+// gameOver reports whether the move just made by mover ended the game and
+// who won. A pop completing lines for both players wins for the mover.
+func gameOver(pos *game.Position, mover int) (int, bool) {
+	switch {
+	case pos.HasWon(mover):
+		return mover, true
+	case pos.HasWon(3 - mover):
+		return 3 - mover, true
+	case !pos.HasLegalMove():
+		return 0, true
+	}
+	return 0, false
+}
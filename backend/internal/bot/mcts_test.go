@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func seededIntn(seed int64) func(int) int {
+	return rand.New(rand.NewSource(seed)).Intn
+}
+
+func TestMCTSTakesWinningMove(t *testing.T) {
+	board := game.DefaultRules().NewBoard()
+	copy(board[game.Rows-1], []int{2, 2, 2, 0, 1, 1, 0})
+	board[game.Rows-2][4] = 1
+
+	pos, err := game.PositionFromBoard(game.DefaultRules(), board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+
+	ply, err := chooseMCTSMove(context.Background(), pos, 2000, 0, seededIntn(1))
+	if err != nil {
+		t.Fatalf("choose move failed: %v", err)
+	}
+	if ply != (game.Ply{Kind: game.DROP, Column: 3}) {
+		t.Fatalf("expected winning drop in column 3, got %v", ply)
+	}
+}
+
+func TestMCTSBlocksOpponentWin(t *testing.T) {
+	board := game.DefaultRules().NewBoard()
+	copy(board[game.Rows-1], []int{1, 1, 1, 0, 0, 0, 2})
+	board[game.Rows-2][6] = 2
+
+	pos, err := game.PositionFromBoard(game.DefaultRules(), board, 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+
+	ply, err := chooseMCTSMove(context.Background(), pos, 2000, 0, seededIntn(1))
+	if err != nil {
+		t.Fatalf("choose move failed: %v", err)
+	}
+	if ply.Column != 3 {
+		t.Fatalf("expected block in column 3, got %v", ply)
+	}
+}
+
+func TestMCTSPlaysLargePopOutBoard(t *testing.T) {
+	rules := game.Rules{Rows: 9, Columns: 10, Connect: 5, PopOut: true}
+	pos := game.NewPosition(rules)
+	for _, col := range []int{4, 5, 4, 5, 4, 5, 4} {
+		pos.Play(col)
+	}
+
+	started := time.Now()
+	ply, err := chooseMCTSMove(context.Background(), pos, 0, 50*time.Millisecond, seededIntn(1))
+	if err != nil {
+		t.Fatalf("choose move failed: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected the time limit to stop the search, took %s", elapsed)
+	}
+	if ply.Column != 4 {
+		t.Fatalf("expected player 2 to block five in column 4, got %v", ply)
+	}
+}
+
+func TestMCTSRequiresALimit(t *testing.T) {
+	if _, err := chooseMCTSMove(context.Background(), game.NewPosition(game.DefaultRules()), 0, 0, seededIntn(1)); err == nil {
+		t.Fatalf("expected a search without limits to be rejected")
+	}
+}
+
+func TestTakeTurnWithMCTSBot(t *testing.T) {
+	gm := game.NewManager()
+	g := createBotGame(t, gm, NameFor(MCTS))
+	if _, _, err := gm.ApplyMove(g.ID, "human", game.DROP, 0); err != nil {
+		t.Fatalf("human move: %v", err)
+	}
+
+	updated, result, col, err := NewWithSeed(gm, DefaultRegistry(), 1).TakeTurn(context.Background(), g.ID)
+	if err != nil {
+		t.Fatalf("bot move failed: %v", err)
+	}
+	if result != game.CONTINUE || col < 0 || col >= game.Columns {
+		t.Fatalf("unexpected bot move col %d result %v", col, result)
+	}
+	if updated.CurrentTurn != 1 {
+		t.Fatalf("expected turn to return to player 1")
+	}
+}
+
+func BenchmarkChooseMCTSMove(b *testing.B) {
+	pos := game.NewPosition(game.DefaultRules())
+	intn := seededIntn(1)
+	for i := 0; i < b.N; i++ {
+		if _, err := chooseMCTSMove(context.Background(), pos, 5000, 0, intn); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Personality plays like a person rather than an engine. It evaluates every
+// legal move Depth plies deep and samples one with softmax weights over the
+// moves' expected scores, so stronger moves are likelier without being
+// certain.
+//
+// Temperature is how far, in expected score, a move may trail the best and
+// still be a serious candidate; zero always plays the best move. BlunderRate
+// is the chance of ignoring the evaluation and playing any legal move.
+// Aggression adds value for each immediate threat a move creates. ThinkTime
+// is the typical delay before moving in a live game: ChoosePacedMove asks for
+// between half and one and a half times it, and a quarter of that when the
+// move is obvious.
+type Personality struct {
+	Depth       int
+	Temperature float64
+	BlunderRate float64
+	Aggression  float64
+	ThinkTime   time.Duration
+}
+
+// threatValue is the expected score one threat is worth at Aggression 1.
+const threatValue = 0.05
+
+// randomScale is the resolution of probabilities drawn from intn.
+const randomScale = 1 << 30
+
+// personalities are the default registry's human-like bots.
+var personalities = map[string]Personality{
+	"BOT_ROOKIE": {Depth: 3, Temperature: 0.12, BlunderRate: 0.08, Aggression: 0.2, ThinkTime: 1200 * time.Millisecond},
+	"BOT_CLUB":   {Depth: 6, Temperature: 0.05, BlunderRate: 0.03, Aggression: 0.5, ThinkTime: 900 * time.Millisecond},
+	"BOT_SHARK":  {Depth: 9, Temperature: 0.02, BlunderRate: 0.01, Aggression: 1, ThinkTime: 700 * time.Millisecond},
+}
+
+// ChooseMove implements Strategy. A nil intn uses a fixed seed, so the same
+// position always gets the same move.
+func (p Personality) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	if intn == nil {
+		intn = rand.New(rand.NewSource(1)).Intn
+	}
+	ply, _, err := p.choose(ctx, pos, intn)
+	return ply, err
+}
+
+// ChoosePacedMove implements PacedStrategy, returning the move ChooseMove
+// would play with the same intn and how long to wait before playing it.
+func (p Personality) ChoosePacedMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, time.Duration, error) {
+	if intn == nil {
+		intn = rand.New(rand.NewSource(1)).Intn
+	}
+	ply, analysis, err := p.choose(ctx, pos, intn)
+	if err != nil {
+		return game.Ply{}, 0, err
+	}
+	return ply, p.delay(obvious(analysis), intn), nil
+}
+
+func (p Personality) choose(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, Analysis, error) {
+	analysis, err := Analyze(ctx, pos, p.Depth)
+	if err != nil {
+		return game.Ply{}, Analysis{}, err
+	}
+
+	if p.BlunderRate > 0 && uniform(intn) < p.BlunderRate {
+		return analysis.Moves[intn(len(analysis.Moves))].Ply, analysis, nil
+	}
+	return p.sample(pos, analysis.Moves, intn), analysis, nil
+}
+
+// sample draws a move with probability proportional to
+// exp((utility-best)/Temperature).
+func (p Personality) sample(pos game.Position, moves []MoveEval, intn func(int) int) game.Ply {
+	utilities := make([]float64, len(moves))
+	best := math.Inf(-1)
+	for i, eval := range moves {
+		utilities[i] = eval.ExpectedScore() + p.Aggression*threatValue*float64(threats(pos, eval.Ply))
+		best = max(best, utilities[i])
+	}
+
+	weights := make([]float64, len(moves))
+	total := 0.0
+	for i, utility := range utilities {
+		switch {
+		case p.Temperature > 0:
+			weights[i] = math.Exp((utility - best) / p.Temperature)
+		case utility == best:
+			weights[i] = 1
+		}
+		total += weights[i]
+	}
+
+	r := uniform(intn) * total
+	for i, weight := range weights {
+		if r < weight {
+			return moves[i].Ply
+		}
+		r -= weight
+	}
+	return moves[len(moves)-1].Ply
+}
+
+// delay draws how long a person would take over a move.
+func (p Personality) delay(obvious bool, intn func(int) int) time.Duration {
+	if p.ThinkTime <= 0 {
+		return 0
+	}
+	delay := p.ThinkTime/2 + time.Duration(intn(int(p.ThinkTime)))
+	if obvious {
+		delay /= 4
+	}
+	return delay
+}
+
+// threats counts the columns in which the player making ply could win on
+// their next move.
+func threats(pos game.Position, ply game.Ply) int {
+	mover := pos.Turn()
+	if err := pos.Apply(ply); err != nil || pos.HasWon(mover) || pos.HasWon(3-mover) {
+		return 0
+	}
+
+	count := 0
+	for col := 0; col < pos.Rules().Columns; col++ {
+		if pos.IsWinningMove(col, mover) {
+			count++
+		}
+	}
+	return count
+}
+
+// obvious reports whether a position needs little thought: the move wins at
+// once, or it is the only one that does not lose.
+func obvious(analysis Analysis) bool {
+	if analysis.Best.Outcome == OutcomeWin && analysis.Best.Plies == 1 {
+		return true
+	}
+	alive := 0
+	for _, eval := range analysis.Moves {
+		if eval.Outcome != OutcomeLoss {
+			alive++
+		}
+	}
+	return alive <= 1
+}
+
+// uniform draws a number in [0, 1) from intn.
+func uniform(intn func(int) int) float64 {
+	return float64(intn(randomScale)) / randomScale
+}
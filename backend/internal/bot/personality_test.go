@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func TestPersonalityIsReproducibleFromSeed(t *testing.T) {
+	p := Personality{Depth: 2, Temperature: 0.2, BlunderRate: 0.2}
+	play := func(seed int64) string {
+		intn := rand.New(rand.NewSource(seed)).Intn
+		pos := game.NewPosition(game.DefaultRules())
+		moves := make([]byte, 0, 12)
+		for len(moves) < 12 && pos.HasLegalMove() {
+			ply, err := p.ChooseMove(context.Background(), pos, intn)
+			if err != nil {
+				t.Fatalf("choose move: %v", err)
+			}
+			if err := pos.Apply(ply); err != nil {
+				t.Fatalf("apply %+v: %v", ply, err)
+			}
+			moves = append(moves, byte('1'+ply.Column))
+		}
+		return string(moves)
+	}
+
+	if a, b := play(7), play(7); a != b {
+		t.Fatalf("expected the same seed to replay the same game, got %s and %s", a, b)
+	}
+	seen := make(map[string]bool)
+	for seed := int64(1); seed <= 5; seed++ {
+		seen[play(seed)] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected different seeds to vary the game, got %v", seen)
+	}
+}
+
+func TestPersonalityTakesWinsWhenCold(t *testing.T) {
+	pos, err := game.PositionFromMoves(game.DefaultRules(), "152535")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+
+	p := Personality{Depth: 4}
+	for seed := int64(1); seed <= 10; seed++ {
+		ply, err := p.ChooseMove(context.Background(), pos, rand.New(rand.NewSource(seed)).Intn)
+		if err != nil {
+			t.Fatalf("choose move: %v", err)
+		}
+		if ply.Column != 3 {
+			t.Fatalf("seed %d: expected the winning column 4, got %d", seed, ply.Column+1)
+		}
+	}
+}
+
+func TestPersonalityBlundersAtItsRate(t *testing.T) {
+	pos, err := game.PositionFromMoves(game.DefaultRules(), "152535")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+
+	p := Personality{Depth: 4, BlunderRate: 1}
+	intn := rand.New(rand.NewSource(3)).Intn
+	columns := make(map[int]bool)
+	for i := 0; i < 20; i++ {
+		ply, err := p.ChooseMove(context.Background(), pos, intn)
+		if err != nil {
+			t.Fatalf("choose move: %v", err)
+		}
+		columns[ply.Column] = true
+	}
+	if len(columns) < 3 {
+		t.Fatalf("expected blunders to spread over the board, got columns %v", columns)
+	}
+}
+
+func TestPersonalityAggressionPrefersThreats(t *testing.T) {
+	// Player 1 has two on the bottom row: extending to three opens a double
+	// threat. Depth 1 sees no forced win, so only aggression tells the moves
+	// apart beyond the heuristic.
+	pos, err := game.PositionFromMoves(game.DefaultRules(), "3747")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+
+	calm := Personality{Depth: 1, Temperature: 0.01}
+	fierce := Personality{Depth: 1, Temperature: 0.01, Aggression: 20}
+	threatening := func(p Personality) int {
+		count := 0
+		for seed := int64(1); seed <= 20; seed++ {
+			ply, err := p.ChooseMove(context.Background(), pos, rand.New(rand.NewSource(seed)).Intn)
+			if err != nil {
+				t.Fatalf("choose move: %v", err)
+			}
+			if threats(pos, ply) > 0 {
+				count++
+			}
+		}
+		return count
+	}
+
+	if c, f := threatening(calm), threatening(fierce); f != 20 || f <= c {
+		t.Fatalf("expected aggression to favour threats, calm %d fierce %d", c, f)
+	}
+}
+
+func TestPersonalityPacesOnlyOnRequest(t *testing.T) {
+	p := Personality{Depth: 2, Temperature: 0.2, ThinkTime: time.Hour}
+	pos := game.NewPosition(game.DefaultRules())
+
+	start := time.Now()
+	ply, err := p.ChooseMove(context.Background(), pos, rand.New(rand.NewSource(7)).Intn)
+	if err != nil {
+		t.Fatalf("choose move: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected ChooseMove not to wait, took %v", elapsed)
+	}
+
+	paced, delay, err := p.ChoosePacedMove(context.Background(), pos, rand.New(rand.NewSource(7)).Intn)
+	if err != nil {
+		t.Fatalf("choose paced move: %v", err)
+	}
+	if paced != ply {
+		t.Fatalf("expected the same move from the same seed, got %v and %v", ply, paced)
+	}
+	if delay < p.ThinkTime/2 || delay >= 3*p.ThinkTime/2 {
+		t.Fatalf("expected a delay around the think time, got %v", delay)
+	}
+}
+
+func TestPersonalityThinksLessAboutObviousMoves(t *testing.T) {
+	pos, err := game.PositionFromMoves(game.DefaultRules(), "151515")
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	p := Personality{Depth: 2, ThinkTime: time.Hour}
+
+	ply, delay, err := p.ChoosePacedMove(context.Background(), pos, nil)
+	if err != nil {
+		t.Fatalf("choose paced move: %v", err)
+	}
+	if ply.Column != 0 || delay >= 3*p.ThinkTime/8 {
+		t.Fatalf("expected a quick win in column 1, got %v after %v", ply, delay)
+	}
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultBot is the bot players are matched against when they do not ask
+// for a particular one.
+const DefaultBot = "BOT"
+
+// Identity is a named bot and the settings it plays with.
+type Identity struct {
+	Name     string
+	Strategy Strategy
+	// Level is the level Strategy was built for, or empty for bots that are
+	// not one of the levels, such as personalities and external engines.
+	Level Level
+	// BookPlies is how many plies into a game the bot plays from the opening
+	// book when it has the position. Zero never uses the book.
+	BookPlies int
+}
+
+// Registry maps bot usernames to their identities. It is safe for
+// concurrent use.
+type Registry struct {
+	mu   sync.RWMutex
+	bots map[string]Identity
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{bots: make(map[string]Identity)}
+}
+
+// DefaultRegistry registers DefaultBot at DefaultLevel plus one bot per
+// level, named by NameFor, and the human-like personalities BOT_ROOKIE,
+// BOT_CLUB and BOT_SHARK. The hard and expert bots open from the embedded
+// book; see cmd/bookgen.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	_ = r.Register(Identity{Name: DefaultBot, Strategy: DefaultLevel.Strategy(), Level: DefaultLevel})
+	for _, level := range append(Levels(), MCTS) {
+		_ = r.Register(Identity{Name: NameFor(level), Strategy: level.Strategy(), Level: level, BookPlies: bookPlies[level]})
+	}
+	for name, personality := range personalities {
+		_ = r.Register(Identity{Name: name, Strategy: personality})
+	}
+	return r
+}
+
+// NameFor returns the username of the default registry's bot for level,
+// e.g. BOT_EASY.
+func NameFor(level Level) string {
+	return "BOT_" + strings.ToUpper(string(level))
+}
+
+// Register adds a bot identity. Names must be unique.
+func (r *Registry) Register(id Identity) error {
+	if id.Name == "" {
+		return errors.New("bot name is required")
+	}
+	if id.Strategy == nil {
+		return errors.New("bot strategy is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.bots[id.Name]; exists {
+		return fmt.Errorf("bot %s already registered", id.Name)
+	}
+	r.bots[id.Name] = id
+	return nil
+}
+
+// Lookup returns the identity of the bot with the given username.
+func (r *Registry) Lookup(name string) (Identity, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.bots[name]
+	return id, ok
+}
+
+// LevelOf returns the level of the bot with the given username, or "" if it
+// has none or is not a bot.
+func (r *Registry) LevelOf(username string) string {
+	id, _ := r.Lookup(username)
+	return string(id.Level)
+}
+
+// IsBot reports whether username belongs to a registered bot.
+func (r *Registry) IsBot(username string) bool {
+	_, ok := r.Lookup(username)
+	return ok
+}
+
+// Names lists the registered bots in alphabetical order.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.bots))
+	for name := range r.bots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,278 @@
+package bot
+
+import (
+	"context"
+	"errors"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// winScore is the value of a won position. Wins found sooner score higher so
+// that the search prefers the quickest win and the slowest loss.
+const winScore = 1_000_000
+
+// scoredMove is a root move with its negamax score for the player to move.
+type scoredMove struct {
+	ply   game.Ply
+	score int
+}
+
+// chooseMove searches pos to depth and picks a move. With noise, any move
+// scoring within the noise margin of the best may be played; intn picks
+// among them. Once ctx is done the bot plays from the deepest search it
+// completed.
+func chooseMove(ctx context.Context, pos game.Position, depth, noise int, intn func(int) int) (game.Ply, error) {
+	moves, _ := deepen(ctx, pos, searchDepth(pos.Rules(), depth), noise)
+	if len(moves) == 0 {
+		return game.Ply{}, errors.New("bot has no valid moves")
+	}
+
+	best := moves[0].score
+	for _, mv := range moves[1:] {
+		best = max(best, mv.score)
+	}
+
+	candidates := make([]game.Ply, 0, len(moves))
+	for _, mv := range moves {
+		if mv.score >= best-noise {
+			candidates = append(candidates, mv.ply)
+		}
+	}
+	if len(candidates) == 1 || intn == nil {
+		return candidates[0], nil
+	}
+	return candidates[intn(len(candidates))], nil
+}
+
+// deepen searches pos one ply deeper at a time up to depth, returning the
+// scored moves of the deepest search completed and its depth. It stops early
+// once ctx is done or a move is proven to win. The one-ply search always runs
+// to completion, so there are moves to play.
+func deepen(ctx context.Context, pos game.Position, depth, margin int) ([]scoredMove, int) {
+	var moves []scoredMove
+	completed := 0
+	for d := 1; d <= depth; d++ {
+		s := &searcher{ctx: ctx}
+		if d == 1 {
+			s.ctx = context.Background()
+		}
+		scored := s.searchRoot(pos, d, margin)
+		if s.aborted {
+			break
+		}
+		moves, completed = scored, d
+		if provenWin(moves) {
+			// Deeper searches cannot find a quicker win.
+			break
+		}
+	}
+	return moves, completed
+}
+
+// provenWin reports whether one of moves forces a win. Heuristic scores stay
+// far below winScore.
+func provenWin(moves []scoredMove) bool {
+	for _, mv := range moves {
+		if mv.score > winScore/2 {
+			return true
+		}
+	}
+	return false
+}
+
+// searchDepth scales depth down on boards wider than the classic one so that
+// the larger branching factor does not blow up the search time.
+func searchDepth(rules game.Rules, depth int) int {
+	if rules.Columns <= game.Columns {
+		return depth
+	}
+	return max(1, depth*game.Columns/rules.Columns)
+}
+
+// abortCheckInterval is how many nodes the search visits between checks of
+// its context.
+const abortCheckInterval = 1024
+
+// searcher runs a single fixed-depth search that gives up once ctx is done.
+// The scores of an aborted search are meaningless.
+type searcher struct {
+	ctx     context.Context
+	nodes   int
+	aborted bool
+}
+
+// searchRoot scores the legal moves in pos. Scores are exact for moves within
+// margin of the best; the rest are only known to fall below that band.
+func (s *searcher) searchRoot(pos game.Position, depth, margin int) []scoredMove {
+	var moves []scoredMove
+	best := -winScore - 1
+	for _, ply := range legalMoves(&pos) {
+		alpha := -winScore - 1
+		if best > alpha+margin {
+			alpha = best - margin - 1
+		}
+		score := s.scoreMove(&pos, ply, depth, alpha, winScore+1, 0)
+		if s.aborted {
+			return nil
+		}
+		best = max(best, score)
+		moves = append(moves, scoredMove{ply: ply, score: score})
+	}
+	return moves
+}
+
+// negamax returns the value of pos for the player to move, searching depth
+// plies with alpha-beta pruning. ply counts the distance from the root.
+func (s *searcher) negamax(pos *game.Position, depth, alpha, beta, ply int) int {
+	s.nodes++
+	if s.nodes%abortCheckInterval == 0 && s.ctx.Err() != nil {
+		s.aborted = true
+	}
+	if s.aborted {
+		return 0
+	}
+
+	moves := legalMoves(pos)
+	if len(moves) == 0 {
+		return 0
+	}
+
+	// Take an immediate win, and when the opponent threatens one, only the
+	// blocking drop (or a pop, which reshapes the board) is worth searching.
+	me := pos.Turn()
+	threats := 0
+	for col := 0; col < pos.Rules().Columns; col++ {
+		if pos.IsWinningMove(col, me) {
+			return winScore - ply - 1
+		}
+		if pos.IsWinningMove(col, 3-me) {
+			threats++
+		}
+	}
+	if depth == 0 {
+		return evaluate(pos)
+	}
+	if threats > 0 {
+		moves = forcedMoves(pos, moves)
+	}
+
+	best := -winScore - 1
+	for _, mv := range moves {
+		score := s.scoreMove(pos, mv, depth, alpha, beta, ply)
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// scoreMove plays mv on a copy of pos and returns its value for the mover.
+func (s *searcher) scoreMove(pos *game.Position, mv game.Ply, depth, alpha, beta, ply int) int {
+	mover := pos.Turn()
+	if mv.Kind == game.DROP && pos.IsWinningMove(mv.Column, mover) {
+		return winScore - ply - 1
+	}
+
+	next := *pos
+	if err := next.Apply(mv); err != nil {
+		return -winScore - 1
+	}
+	switch {
+	case next.HasWon(mover):
+		return winScore - ply - 1
+	case next.HasWon(3 - mover):
+		return -winScore + ply + 1
+	case next.IsFull() && !next.Rules().PopOut:
+		return 0
+	}
+	return -s.negamax(&next, depth-1, -beta, -alpha, ply+1)
+}
+
+// forcedMoves keeps the moves that block an immediate threat by the opponent,
+// plus any pops. If nothing blocks, the first move stands in for the loss.
+func forcedMoves(pos *game.Position, moves []game.Ply) []game.Ply {
+	opponent := 3 - pos.Turn()
+	forced := moves[:0:0]
+	for _, mv := range moves {
+		if mv.Kind == game.POP || pos.IsWinningMove(mv.Column, opponent) {
+			forced = append(forced, mv)
+		}
+	}
+	if len(forced) == 0 {
+		return moves[:1]
+	}
+	return forced
+}
+
+// legalMoves lists drops from the centre outwards, then pops.
+func legalMoves(pos *game.Position) []game.Ply {
+	columns := pos.Rules().Columns
+	moves := make([]game.Ply, 0, 2*columns)
+	for _, col := range preferenceOrder(columns) {
+		if pos.CanPlay(col) {
+			moves = append(moves, game.Ply{Kind: game.DROP, Column: col})
+		}
+	}
+	for _, col := range preferenceOrder(columns) {
+		if pos.CanPop(col) {
+			moves = append(moves, game.Ply{Kind: game.POP, Column: col})
+		}
+	}
+	return moves
+}
+
+// evaluate scores pos for the player to move by counting every line of
+// Connect cells that only one player has discs in, weighted by how full it
+// is, plus a bonus for discs in the centre column.
+func evaluate(pos *game.Position) int {
+	rules := pos.Rules()
+	me := pos.Turn()
+	score := 0
+
+	for _, dir := range [][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		for row := 0; row < rules.Rows; row++ {
+			for col := 0; col < rules.Columns; col++ {
+				endRow := row + dir[0]*(rules.Connect-1)
+				endCol := col + dir[1]*(rules.Connect-1)
+				if endRow >= rules.Rows || endCol < 0 || endCol >= rules.Columns {
+					continue
+				}
+
+				var mine, theirs int
+				for k := 0; k < rules.Connect; k++ {
+					switch pos.Cell(row+k*dir[0], col+k*dir[1]) {
+					case 0:
+					case me:
+						mine++
+					default:
+						theirs++
+					}
+				}
+				switch {
+				case theirs == 0:
+					score += mine * mine
+				case mine == 0:
+					score -= theirs * theirs
+				}
+			}
+		}
+	}
+
+	centre := rules.Columns / 2
+	for row := 0; row < rules.Rows; row++ {
+		switch pos.Cell(row, centre) {
+		case 0:
+		case me:
+			score += 6
+		default:
+			score -= 6
+		}
+	}
+	return score
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Strategy picks the move for the player to move in a position: a column to
+// drop into, or under PopOut rules possibly a column to pop. intn is the
+// source of randomness for strategies that use one; nil makes them
+// deterministic. Once ctx is done a strategy should return the best move it
+// has found so far, or an error if it has none.
+type Strategy interface {
+	ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error)
+}
+
+// PacedStrategy is a Strategy that also says how long a person would take
+// over its move. Bot.TakeTurn waits that long in live games; ChooseMove never
+// waits, so arenas and analysis run at full speed.
+type PacedStrategy interface {
+	Strategy
+	ChoosePacedMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, time.Duration, error)
+}
+
+// Minimax searches Depth plies ahead with alpha-beta pruning and a heuristic
+// evaluation. Noise is the evaluation margin within which a weaker move may
+// be picked at random instead of the best one.
+type Minimax struct {
+	Depth int
+	Noise int
+}
+
+// ChooseMove implements Strategy.
+func (m Minimax) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	return chooseMove(ctx, pos, m.Depth, m.Noise, intn)
+}
+
+// MonteCarlo runs Monte Carlo Tree Search for up to Iterations playouts or
+// TimeLimit, whichever comes first. A zero value disables that limit.
+type MonteCarlo struct {
+	Iterations int
+	TimeLimit  time.Duration
+}
+
+// ChooseMove implements Strategy.
+func (m MonteCarlo) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	if intn == nil {
+		intn = rand.New(rand.NewSource(1)).Intn
+	}
+	return chooseMCTSMove(ctx, pos, m.Iterations, m.TimeLimit, intn)
+}
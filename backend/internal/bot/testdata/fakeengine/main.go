@@ -0,0 +1,61 @@
+// Command fakeengine is a minimal engine speaking the ExternalEngine protocol,
+// used by the bot tests. It drops into the leftmost column with room, and its
+// flags make it misbehave in the ways the server has to cope with.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	mode := flag.String("mode", "normal", "normal, silent (no handshake), hang (never moves) or illegal")
+	crashOnce := flag.String("crash-once", "", "exit on the first go command unless this file exists, then create it")
+	delay := flag.Duration("delay", 0, "how long to think before answering go")
+	flag.Parse()
+
+	var board string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "c4e":
+			if *mode == "silent" {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "fake engine ready")
+			fmt.Println("id name fake")
+			fmt.Println("c4eok")
+		case "position":
+			board = fields[len(fields)-1]
+		case "go":
+			if *crashOnce != "" {
+				if _, err := os.Stat(*crashOnce); err != nil {
+					_ = os.WriteFile(*crashOnce, nil, 0o600)
+					os.Exit(1)
+				}
+			}
+			switch *mode {
+			case "hang":
+				continue
+			case "illegal":
+				fmt.Println("bestmove 99")
+				continue
+			}
+			fmt.Println("info thinking")
+			time.Sleep(*delay)
+			top := strings.Split(board, "/")[0]
+			fmt.Printf("bestmove %d\n", strings.IndexByte(top, '0')+1)
+		case "quit":
+			return
+		}
+	}
+}
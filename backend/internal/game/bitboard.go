@@ -0,0 +1,56 @@
+package game
+
+// bitboard is a 128-bit set of board cells, large enough for every board
+// allowed by Rules.Validate.
+type bitboard struct {
+	lo, hi uint64
+}
+
+func bitAt(i int) bitboard {
+	if i < 64 {
+		return bitboard{lo: 1 << i}
+	}
+	return bitboard{hi: 1 << (i - 64)}
+}
+
+func (b bitboard) and(o bitboard) bitboard {
+	return bitboard{lo: b.lo & o.lo, hi: b.hi & o.hi}
+}
+
+func (b bitboard) or(o bitboard) bitboard {
+	return bitboard{lo: b.lo | o.lo, hi: b.hi | o.hi}
+}
+
+func (b bitboard) andNot(o bitboard) bitboard {
+	return bitboard{lo: b.lo &^ o.lo, hi: b.hi &^ o.hi}
+}
+
+func (b bitboard) has(i int) bool {
+	return !b.and(bitAt(i)).isZero()
+}
+
+func (b bitboard) isZero() bool {
+	return b.lo == 0 && b.hi == 0
+}
+
+func (b bitboard) shr(n int) bitboard {
+	switch {
+	case n == 0:
+		return b
+	case n >= 64:
+		return bitboard{lo: b.hi >> (n - 64)}
+	default:
+		return bitboard{lo: b.lo>>n | b.hi<<(64-n), hi: b.hi >> n}
+	}
+}
+
+func (b bitboard) shl(n int) bitboard {
+	switch {
+	case n == 0:
+		return b
+	case n >= 64:
+		return bitboard{hi: b.lo << (n - 64)}
+	default:
+		return bitboard{lo: b.lo << n, hi: b.hi<<n | b.lo>>(64-n)}
+	}
+}
@@ -0,0 +1,159 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxBaseTime bounds the starting time a player can be given.
+const MaxBaseTime = 2 * time.Hour
+
+// TIMEOUT marks the move log entry of a player who ran out of time.
+const TIMEOUT MoveKind = "TIMEOUT"
+
+// TerminationTimeout ends a game when the player to move runs out of time.
+const TerminationTimeout Termination = "TIMEOUT"
+
+// TimeControl gives each player Base time for the whole game, plus a Fischer
+// Increment added after every move they make. The zero value is untimed.
+type TimeControl struct {
+	Base      time.Duration `json:"base"`
+	Increment time.Duration `json:"increment"`
+}
+
+// Untimed reports whether games under tc run without a clock.
+func (tc TimeControl) Untimed() bool {
+	return tc.Base == 0
+}
+
+// Validate reports whether tc is usable.
+func (tc TimeControl) Validate() error {
+	switch {
+	case tc.Base < 0 || tc.Increment < 0:
+		return errors.New("time control cannot be negative")
+	case tc.Base > MaxBaseTime:
+		return fmt.Errorf("base time must be at most %s", MaxBaseTime)
+	case tc.Base == 0 && tc.Increment > 0:
+		return errors.New("increment requires a base time")
+	}
+	return nil
+}
+
+// String renders tc as e.g. "3m0s+2s", or "untimed".
+func (tc TimeControl) String() string {
+	if tc.Untimed() {
+		return "untimed"
+	}
+	return fmt.Sprintf("%s+%s", tc.Base, tc.Increment)
+}
+
+// TimeLeft returns the remaining time of both players at the given moment,
+// counting the running clock of the player to move. It is zero for untimed games.
+func (m *GameManager) TimeLeft(gameID string, at time.Time) ([2]time.Duration, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return [2]time.Duration{}, false
+	}
+	return game.timeLeft(at), true
+}
+
+// NotifyTimeouts registers fn to be called, outside the manager's lock, when
+// a player runs out of time without moving.
+func (m *GameManager) NotifyTimeouts(fn func(*Game)) {
+	m.mu.Lock()
+	m.onTimeout = fn
+	m.mu.Unlock()
+}
+
+// armClock restarts the flag timer for the player to move, or stops it once
+// the game is over. The caller must hold m.mu.
+func (m *GameManager) armClock(game *Game) {
+	if timer, ok := m.timers[game.ID]; ok {
+		timer.Stop()
+		delete(m.timers, game.ID)
+	}
+	if game.TimeControl.Untimed() || game.EndedAt != nil {
+		return
+	}
+
+	left := game.clock[game.CurrentTurn-1] - time.Since(game.turnStarted)
+	m.timers[game.ID] = time.AfterFunc(left, func() { m.flag(game.ID) })
+}
+
+// flag ends the game if the player to move is out of time and reports it.
+func (m *GameManager) flag(gameID string) {
+	m.mu.Lock()
+	game, ok := m.games[gameID]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	result := game.checkClock(time.Now().UTC())
+	if result == CONTINUE {
+		m.armClock(game)
+	} else {
+		delete(m.timers, gameID)
+	}
+	notify := m.onTimeout
+	m.mu.Unlock()
+
+	if result != CONTINUE && notify != nil {
+		notify(game)
+	}
+}
+
+// startClock gives both players the base time with player 1's clock running.
+func (g *Game) startClock(tc TimeControl, at time.Time) {
+	g.TimeControl = tc
+	g.clock = [2]time.Duration{tc.Base, tc.Base}
+	g.turnStarted = at
+}
+
+func (g *Game) timeLeft(at time.Time) [2]time.Duration {
+	left := g.clock
+	if g.TimeControl.Untimed() {
+		return left
+	}
+	if g.EndedAt == nil {
+		left[g.CurrentTurn-1] -= at.Sub(g.turnStarted)
+	}
+	for i := range left {
+		left[i] = max(left[i], 0)
+	}
+	return left
+}
+
+// checkClock flags the player to move if their time has run out by at.
+func (g *Game) checkClock(at time.Time) MoveResult {
+	if g.TimeControl.Untimed() || g.Winner != nil || g.EndedAt != nil {
+		return CONTINUE
+	}
+	if g.timeLeft(at)[g.CurrentTurn-1] > 0 {
+		return CONTINUE
+	}
+
+	loser := g.Player1
+	if g.CurrentTurn == 2 {
+		loser = g.Player2
+	}
+	g.clock[g.CurrentTurn-1] = 0
+	result, _ := g.end(loser, TIMEOUT, at)
+	return result
+}
+
+// spend charges the player to move for their thinking time, adding the
+// increment when they completed a move.
+func (g *Game) spend(at time.Time, moved bool) {
+	if g.TimeControl.Untimed() {
+		return
+	}
+	g.clock[g.CurrentTurn-1] -= at.Sub(g.turnStarted)
+	if moved {
+		g.clock[g.CurrentTurn-1] += g.TimeControl.Increment
+	}
+	g.turnStarted = at
+}
@@ -0,0 +1,90 @@
+package game
+
+import (
+	"testing"
+	"time"
+)
+
+func createTimedGame(t *testing.T, gm *GameManager, clock TimeControl) *Game {
+	t.Helper()
+	return createGameWith(t, gm, "alice", "bob", DefaultRules(), clock)
+}
+
+func TestTimeControlValidate(t *testing.T) {
+	valid := []TimeControl{{}, {Base: time.Minute}, {Base: 3 * time.Minute, Increment: 2 * time.Second}}
+	for _, tc := range valid {
+		if err := tc.Validate(); err != nil {
+			t.Fatalf("%s: unexpected error %v", tc, err)
+		}
+	}
+
+	invalid := []TimeControl{{Base: -time.Second}, {Increment: time.Second}, {Base: MaxBaseTime + time.Second}}
+	for _, tc := range invalid {
+		if err := tc.Validate(); err == nil {
+			t.Fatalf("%s: expected error", tc)
+		}
+	}
+
+	if _, err := NewManager().CreateGame("alice", "bob", DefaultRules(), TimeControl{Increment: time.Second}); err == nil {
+		t.Fatalf("expected invalid time control to be rejected")
+	}
+}
+
+func TestClockAddsIncrement(t *testing.T) {
+	gm := NewManager()
+	g := createTimedGame(t, gm, TimeControl{Base: time.Minute, Increment: 5 * time.Second})
+	playMoves(t, gm, g, 3)
+
+	left, ok := gm.TimeLeft(g.ID, time.Now().UTC())
+	if !ok {
+		t.Fatalf("expected game to exist")
+	}
+	if left[0] <= time.Minute || left[0] > time.Minute+5*time.Second {
+		t.Fatalf("expected alice to gain the increment, got %s", left[0])
+	}
+	if left[1] > time.Minute || left[1] < 50*time.Second {
+		t.Fatalf("expected bob's clock to be running from the base, got %s", left[1])
+	}
+}
+
+func TestMoveAfterFlagLosesOnTime(t *testing.T) {
+	gm := NewManager()
+	g := createTimedGame(t, gm, TimeControl{Base: time.Hour})
+
+	// Pretend alice has been thinking for longer than their base time.
+	g.turnStarted = g.turnStarted.Add(-2 * time.Hour)
+
+	updated, result, err := gm.ApplyMove(g.ID, "alice", DROP, 3)
+	if err != nil {
+		t.Fatalf("apply move: %v", err)
+	}
+	if result != WIN || updated.Winner == nil || *updated.Winner != "bob" {
+		t.Fatalf("expected bob to win on time, got %v", result)
+	}
+	if updated.Termination != TerminationTimeout || updated.Board[Rows-1][3] != 0 {
+		t.Fatalf("expected the late move to be discarded and the game lost on time")
+	}
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected timeout to replay: %v", err)
+	}
+}
+
+func TestFlagTimerNotifiesTimeout(t *testing.T) {
+	gm := NewManager()
+	flagged := make(chan *Game, 1)
+	gm.NotifyTimeouts(func(g *Game) { flagged <- g })
+
+	g := createTimedGame(t, gm, TimeControl{Base: 20 * time.Millisecond})
+
+	select {
+	case timedOut := <-flagged:
+		if timedOut.ID != g.ID || timedOut.Termination != TerminationTimeout {
+			t.Fatalf("unexpected timeout notification for %s (%s)", timedOut.ID, timedOut.Termination)
+		}
+		if timedOut.Winner == nil || *timedOut.Winner != "bob" {
+			t.Fatalf("expected bob to win when alice flags")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the flag timer to fire")
+	}
+}
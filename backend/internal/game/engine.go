@@ -2,30 +2,25 @@ package game
 
 import "errors"
 
-const (
-	Rows    = 6
-	Columns = 7
-)
-
 // DropDisc returns a new board state after placing a disc for player in the specified column.
-func DropDisc(board [][]int, col int, player int) ([][]int, int, error) {
-	if col < 0 || col >= Columns {
+func (r Rules) DropDisc(board [][]int, col int, player int) ([][]int, int, error) {
+	if col < 0 || col >= r.Columns {
 		return nil, -1, errors.New("invalid column")
 	}
 	if player != 1 && player != 2 {
 		return nil, -1, errors.New("invalid player")
 	}
 
-	newBoard := make([][]int, Rows)
-	for r := 0; r < Rows; r++ {
-		if len(board) > r {
-			newBoard[r] = append([]int(nil), board[r]...)
+	newBoard := make([][]int, r.Rows)
+	for row := 0; row < r.Rows; row++ {
+		if len(board) > row {
+			newBoard[row] = append([]int(nil), board[row]...)
 		} else {
-			newBoard[r] = make([]int, Columns)
+			newBoard[row] = make([]int, r.Columns)
 		}
 	}
 
-	for row := Rows - 1; row >= 0; row-- {
+	for row := r.Rows - 1; row >= 0; row-- {
 		if newBoard[row][col] == 0 {
 			newBoard[row][col] = player
 			return newBoard, row, nil
@@ -35,55 +30,51 @@ func DropDisc(board [][]int, col int, player int) ([][]int, int, error) {
 	return nil, -1, errors.New("column is full")
 }
 
-// CheckWin determines whether the specified player has a connect-four on the board.
-func CheckWin(board [][]int, player int) bool {
-	// Horizontal
-	for r := 0; r < Rows; r++ {
-		for c := 0; c <= Columns-4; c++ {
-			if board[r][c] == player && board[r][c+1] == player && board[r][c+2] == player && board[r][c+3] == player {
-				return true
-			}
-		}
+// PopDisc returns a new board state after removing player's disc from the bottom
+// of the specified column and shifting the discs above it down by one row.
+func (r Rules) PopDisc(board [][]int, col int, player int) ([][]int, error) {
+	if !r.PopOut {
+		return nil, errors.New("pop moves are not allowed")
 	}
-
-	// Vertical
-	for c := 0; c < Columns; c++ {
-		for r := 0; r <= Rows-4; r++ {
-			if board[r][c] == player && board[r+1][c] == player && board[r+2][c] == player && board[r+3][c] == player {
-				return true
-			}
-		}
+	if col < 0 || col >= r.Columns {
+		return nil, errors.New("invalid column")
+	}
+	if len(board) != r.Rows || len(board[r.Rows-1]) != r.Columns || board[r.Rows-1][col] != player {
+		return nil, errors.New("can only pop your own disc from the bottom row")
 	}
 
-	// Diagonal ↘
-	for r := 0; r <= Rows-4; r++ {
-		for c := 0; c <= Columns-4; c++ {
-			if board[r][c] == player && board[r+1][c+1] == player && board[r+2][c+2] == player && board[r+3][c+3] == player {
-				return true
-			}
-		}
+	newBoard := make([][]int, r.Rows)
+	for row := range newBoard {
+		newBoard[row] = append([]int(nil), board[row]...)
 	}
 
-	// Diagonal ↗
-	for r := 3; r < Rows; r++ {
-		for c := 0; c <= Columns-4; c++ {
-			if board[r][c] == player && board[r-1][c+1] == player && board[r-2][c+2] == player && board[r-3][c+3] == player {
-				return true
-			}
-		}
+	for row := r.Rows - 1; row > 0; row-- {
+		newBoard[row][col] = newBoard[row-1][col]
 	}
+	newBoard[0][col] = 0
+
+	return newBoard, nil
+}
+
+// CheckWin determines whether the specified player has a complete line on the board.
+func (r Rules) CheckWin(board [][]int, player int) bool {
+	return hasLine(boardBits(r, board, player), r)
+}
 
-	return false
+// WinningCells returns the coordinates of every cell in a complete line for player.
+// It returns nil when the player has no line.
+func (r Rules) WinningCells(board [][]int, player int) []Cell {
+	return lineCells(boardBits(r, board, player), r)
 }
 
 // IsBoardFull reports whether all board slots are occupied.
-func IsBoardFull(board [][]int) bool {
-	for r := 0; r < Rows; r++ {
-		if len(board) <= r {
+func (r Rules) IsBoardFull(board [][]int) bool {
+	for row := 0; row < r.Rows; row++ {
+		if len(board) <= row || len(board[row]) < r.Columns {
 			return false
 		}
-		for c := 0; c < Columns; c++ {
-			if board[r][c] == 0 {
+		for c := 0; c < r.Columns; c++ {
+			if board[row][c] == 0 {
 				return false
 			}
 		}
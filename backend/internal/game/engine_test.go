@@ -2,10 +2,12 @@ package game
 
 import "testing"
 
+var classic = DefaultRules()
+
 func TestDropDiscPlacesDisc(t *testing.T) {
-	board := newBoard()
+	board := classic.NewBoard()
 
-	updated, row, err := DropDisc(board, 3, 1)
+	updated, row, err := classic.DropDisc(board, 3, 1)
 	if err != nil {
 		t.Fatalf("drop disc failed: %v", err)
 	}
@@ -21,72 +23,196 @@ func TestDropDiscPlacesDisc(t *testing.T) {
 }
 
 func TestDropDiscFullColumnError(t *testing.T) {
-	board := newBoard()
+	board := classic.NewBoard()
 	for i := 0; i < Rows; i++ {
 		var err error
-		board, _, err = DropDisc(board, 0, 1+(i%2))
+		board, _, err = classic.DropDisc(board, 0, 1+(i%2))
 		if err != nil {
 			t.Fatalf("unexpected error filling column: %v", err)
 		}
 	}
-	if _, _, err := DropDisc(board, 0, 1); err == nil {
+	if _, _, err := classic.DropDisc(board, 0, 1); err == nil {
 		t.Fatalf("expected error when dropping into full column")
 	}
 }
 
 func TestCheckWinDetections(t *testing.T) {
-	board := newBoard()
+	board := classic.NewBoard()
 
 	// Horizontal win.
 	board[Rows-1][0], board[Rows-1][1], board[Rows-1][2], board[Rows-1][3] = 1, 1, 1, 1
-	if !CheckWin(board, 1) {
+	if !classic.CheckWin(board, 1) {
 		t.Fatalf("expected horizontal win")
 	}
 
-	board = newBoard()
+	board = classic.NewBoard()
 	// Vertical win.
 	for i := 0; i < 4; i++ {
 		board[Rows-1-i][2] = 2
 	}
-	if !CheckWin(board, 2) {
+	if !classic.CheckWin(board, 2) {
 		t.Fatalf("expected vertical win")
 	}
 
-	board = newBoard()
+	board = classic.NewBoard()
 	// Diagonal ↘ win.
 	for i := 0; i < 4; i++ {
 		board[Rows-1-i][i] = 1
 	}
-	if !CheckWin(board, 1) {
+	if !classic.CheckWin(board, 1) {
 		t.Fatalf("expected diagonal ↘ win")
 	}
 
-	board = newBoard()
+	board = classic.NewBoard()
 	// Diagonal ↗ win.
 	for i := 0; i < 4; i++ {
 		board[Rows-1-i][3-i] = 2
 	}
-	if !CheckWin(board, 2) {
+	if !classic.CheckWin(board, 2) {
 		t.Fatalf("expected diagonal ↗ win")
 	}
 
-	if CheckWin(newBoard(), 1) {
+	if classic.CheckWin(classic.NewBoard(), 1) {
 		t.Fatalf("did not expect win on empty board")
 	}
 }
 
 func TestIsBoardFull(t *testing.T) {
-	if IsBoardFull(newBoard()) {
+	if classic.IsBoardFull(classic.NewBoard()) {
 		t.Fatalf("empty board should not be full")
 	}
 
-	board := newBoard()
+	board := classic.NewBoard()
 	for r := 0; r < Rows; r++ {
 		for c := 0; c < Columns; c++ {
 			board[r][c] = 1
 		}
 	}
-	if !IsBoardFull(board) {
+	if !classic.IsBoardFull(board) {
 		t.Fatalf("expected board to be full")
 	}
 }
+
+func TestCheckWinHonoursConnectLength(t *testing.T) {
+	rules := Rules{Rows: 8, Columns: 9, Connect: 5}
+	board := rules.NewBoard()
+
+	for c := 0; c < 4; c++ {
+		board[rules.Rows-1][c] = 1
+	}
+	if rules.CheckWin(board, 1) {
+		t.Fatalf("four in a row should not win connect-5")
+	}
+
+	board[rules.Rows-1][4] = 1
+	if !rules.CheckWin(board, 1) {
+		t.Fatalf("expected connect-5 horizontal win")
+	}
+
+	board = rules.NewBoard()
+	for i := 0; i < 5; i++ {
+		board[i][rules.Columns-1-i] = 2
+	}
+	if !rules.CheckWin(board, 2) {
+		t.Fatalf("expected connect-5 diagonal win in the top corner")
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	valid := []Rules{DefaultRules(), {Rows: 8, Columns: 9, Connect: 5}, {Rows: 4, Columns: 16, Connect: 3}}
+	for _, rules := range valid {
+		if err := rules.Validate(); err != nil {
+			t.Fatalf("expected %s to be valid: %v", rules, err)
+		}
+	}
+
+	invalid := []Rules{{}, {Rows: 3, Columns: 7, Connect: 3}, {Rows: 16, Columns: 16, Connect: 4}, {Rows: 6, Columns: 7, Connect: 8}, {Rows: 6, Columns: 7, Connect: 2}}
+	for _, rules := range invalid {
+		if err := rules.Validate(); err == nil {
+			t.Fatalf("expected %s to be rejected", rules)
+		}
+	}
+}
+
+func TestPopDiscShiftsColumn(t *testing.T) {
+	rules := DefaultRules()
+	rules.PopOut = true
+
+	board := rules.NewBoard()
+	board[Rows-1][2] = 1
+	board[Rows-2][2] = 2
+	board[Rows-3][2] = 1
+
+	updated, err := rules.PopDisc(board, 2, 1)
+	if err != nil {
+		t.Fatalf("pop disc failed: %v", err)
+	}
+	if updated[Rows-1][2] != 2 || updated[Rows-2][2] != 1 || updated[Rows-3][2] != 0 {
+		t.Fatalf("expected column to shift down, got %v", []int{updated[Rows-1][2], updated[Rows-2][2], updated[Rows-3][2]})
+	}
+	if board[Rows-1][2] != 1 {
+		t.Fatalf("original board should remain unchanged")
+	}
+
+	if _, err := rules.PopDisc(updated, 2, 1); err == nil {
+		t.Fatalf("expected error when popping opponent disc")
+	}
+	if _, err := classic.PopDisc(board, 2, 1); err == nil {
+		t.Fatalf("expected error when popping without PopOut rules")
+	}
+}
+
+func TestWinningCellsReportsEveryLine(t *testing.T) {
+	board := classic.NewBoard()
+	if cells := classic.WinningCells(board, 1); cells != nil {
+		t.Fatalf("expected no winning cells on empty board, got %v", cells)
+	}
+
+	// Five across the bottom plus a vertical four sharing the corner cell.
+	for c := 0; c < 5; c++ {
+		board[Rows-1][c] = 1
+	}
+	for i := 1; i < 4; i++ {
+		board[Rows-1-i][0] = 1
+	}
+
+	cells := classic.WinningCells(board, 1)
+	want := []Cell{
+		{Row: Rows - 4, Col: 0},
+		{Row: Rows - 3, Col: 0},
+		{Row: Rows - 2, Col: 0},
+		{Row: Rows - 1, Col: 0}, {Row: Rows - 1, Col: 1}, {Row: Rows - 1, Col: 2}, {Row: Rows - 1, Col: 3}, {Row: Rows - 1, Col: 4},
+	}
+	if len(cells) != len(want) {
+		t.Fatalf("expected %d cells, got %v", len(want), cells)
+	}
+	for i := range want {
+		if cells[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, cells)
+		}
+	}
+
+	if cells := classic.WinningCells(board, 2); cells != nil {
+		t.Fatalf("expected no winning cells for player 2, got %v", cells)
+	}
+}
+
+func TestWinningCellsDiagonal(t *testing.T) {
+	board := classic.NewBoard()
+	for i := 0; i < 4; i++ {
+		board[Rows-1-i][3-i] = 2
+	}
+
+	cells := classic.WinningCells(board, 2)
+	if len(cells) != 4 {
+		t.Fatalf("expected 4 cells, got %v", cells)
+	}
+	for _, cell := range cells {
+		if cell.Row-cell.Col != Rows-4 {
+			t.Fatalf("unexpected cell %v", cell)
+		}
+		if board[cell.Row][cell.Col] != 2 {
+			t.Fatalf("cell %v is not owned by player 2", cell)
+		}
+	}
+}
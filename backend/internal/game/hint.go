@@ -0,0 +1,75 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Hint records a player asking for a suggested move.
+type Hint struct {
+	Player string
+	// MoveNumber is the MoveNumber of the move the hint was asked for, the
+	// next entry in the game's move log.
+	MoveNumber  int
+	RequestedAt time.Time
+}
+
+// UseHint records a hint for player, who must be on move in an unrated game
+// and have used fewer than limit hints in it. It returns the hint, for
+// RefundHint, and how many hints the player has left.
+func (m *GameManager) UseHint(gameID, player string, limit int) (Hint, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return Hint{}, 0, fmt.Errorf("game %s not found", gameID)
+	}
+
+	switch {
+	case game.Winner != nil || game.EndedAt != nil:
+		return Hint{}, 0, errors.New("game already finished")
+	case player != game.Player1 && player != game.Player2:
+		return Hint{}, 0, errors.New("player not part of this game")
+	case game.Rated:
+		return Hint{}, 0, errors.New("hints are not allowed in rated games")
+	case (game.CurrentTurn == 1) != (player == game.Player1):
+		return Hint{}, 0, errors.New("hints are only given on your turn")
+	case game.HintsBy(player) >= limit:
+		return Hint{}, 0, errors.New("no hints left in this game")
+	}
+
+	hint := Hint{Player: player, MoveNumber: len(game.Moves) + 1, RequestedAt: time.Now().UTC()}
+	game.Hints = append(game.Hints, hint)
+	return hint, limit - game.HintsBy(player), nil
+}
+
+// RefundHint takes back a hint recorded by UseHint that could not be given,
+// so it does not count against the player's allowance.
+func (m *GameManager) RefundHint(gameID string, hint Hint) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return
+	}
+	for i, recorded := range game.Hints {
+		if recorded == hint {
+			game.Hints = append(game.Hints[:i], game.Hints[i+1:]...)
+			return
+		}
+	}
+}
+
+// HintsBy returns how many hints player has used in this game.
+func (g *Game) HintsBy(player string) int {
+	count := 0
+	for _, hint := range g.Hints {
+		if hint.Player == player {
+			count++
+		}
+	}
+	return count
+}
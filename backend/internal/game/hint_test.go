@@ -0,0 +1,82 @@
+package game
+
+import "testing"
+
+func TestUseHintRecordsAndLimits(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+
+	if _, _, err := gm.UseHint(g.ID, "bob", 2); err == nil {
+		t.Fatalf("expected hint refused off turn")
+	}
+	if _, _, err := gm.UseHint(g.ID, "alice", 2); err != nil {
+		t.Fatalf("first hint: %v", err)
+	}
+	playMoves(t, gm, g, 3, 4)
+	if _, left, err := gm.UseHint(g.ID, "alice", 2); err != nil || left != 0 {
+		t.Fatalf("second hint: %d left, err %v", left, err)
+	}
+	if _, _, err := gm.UseHint(g.ID, "alice", 2); err == nil {
+		t.Fatalf("expected hint refused past the limit")
+	}
+
+	if g.HintsBy("alice") != 2 || g.HintsBy("bob") != 0 {
+		t.Fatalf("unexpected hint counts %+v", g.Hints)
+	}
+	if g.Hints[0].MoveNumber != 1 || g.Hints[1].MoveNumber != 3 {
+		t.Fatalf("expected hints recorded at plies 1 and 3, got %+v", g.Hints)
+	}
+}
+
+func TestUseHintRefusedInRatedGames(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	if err := gm.SetRated(g.ID, true); err != nil {
+		t.Fatalf("set rated: %v", err)
+	}
+
+	if _, _, err := gm.UseHint(g.ID, "alice", 3); err == nil {
+		t.Fatalf("expected hint refused in a rated game")
+	}
+	if len(g.Hints) != 0 {
+		t.Fatalf("expected no hint recorded, got %+v", g.Hints)
+	}
+}
+
+func TestUseHintNumbersPliesAfterPops(t *testing.T) {
+	gm := NewManager()
+	g := createPopOutGame(t, gm)
+	playMoves(t, gm, g, 2, 3)
+	if _, _, err := gm.ApplyMove(g.ID, "alice", POP, 2); err != nil {
+		t.Fatalf("pop: %v", err)
+	}
+
+	if _, _, err := gm.UseHint(g.ID, "bob", 1); err != nil {
+		t.Fatalf("hint: %v", err)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "bob", DROP, 4); err != nil {
+		t.Fatalf("drop: %v", err)
+	}
+
+	hinted := g.Moves[g.Hints[0].MoveNumber-1]
+	if hinted.Player != "bob" || hinted.Column != 4 {
+		t.Fatalf("expected the hint to line up with bob's drop, got hint %+v and move %+v", g.Hints[0], hinted)
+	}
+}
+
+func TestRefundHintRestoresAllowance(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+
+	hint, _, err := gm.UseHint(g.ID, "alice", 1)
+	if err != nil {
+		t.Fatalf("hint: %v", err)
+	}
+	gm.RefundHint(g.ID, hint)
+	if g.HintsBy("alice") != 0 {
+		t.Fatalf("expected the refunded hint to be dropped, got %+v", g.Hints)
+	}
+	if _, left, err := gm.UseHint(g.ID, "alice", 1); err != nil || left != 0 {
+		t.Fatalf("expected the hint to be available again, got %d left, err %v", left, err)
+	}
+}
@@ -3,6 +3,7 @@ package game
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -11,53 +12,146 @@ import (
 
 // Game represents an active match between two players.
 type Game struct {
-	ID          string
-	Player1     string
-	Player2     string
-	Board       [][]int
-	CurrentTurn int
-	CreatedAt   time.Time
-	Winner      *string
-	Moves       []Move
-	EndedAt     *time.Time
+	ID           string
+	Player1      string
+	Player2      string
+	Rules        Rules
+	Board        [][]int
+	CurrentTurn  int
+	CreatedAt    time.Time
+	Winner       *string
+	WinningCells []Cell
+	Moves        []Move
+	EndedAt      *time.Time
+	Termination  Termination
+	TimeControl  TimeControl
+	// BotLevel names the level of the bot in a bot game, if it has one. It
+	// is informational; the bot plays with its registered strategy.
+	BotLevel string
+	Hash     uint64
+	// Rated games count towards the players' standing; hints are refused.
+	Rated bool
+	Hints []Hint
+
+	position    Position
+	seen        map[Position]int
+	clock       [2]time.Duration
+	turnStarted time.Time
+}
+
+// Position returns a copy of the game's bitboard position.
+func (g *Game) Position() Position {
+	return g.position
+}
+
+// SetPosition replaces the game's position, keeping Rules, Board and CurrentTurn in sync.
+func (g *Game) SetPosition(pos Position) {
+	g.position = pos
+	g.Rules = pos.Rules()
+	g.Board = pos.Board()
+	g.CurrentTurn = pos.Turn()
+	g.Hash = pos.Hash()
+	g.seen = map[Position]int{pos: 1}
 }
 
+// MoveKind distinguishes dropping a disc from popping one out under PopOut rules.
+type MoveKind string
+
+const (
+	DROP MoveKind = "DROP"
+	POP  MoveKind = "POP"
+)
+
 // Move captures a single turn taken during a game.
 type Move struct {
 	Player     string
+	Kind       MoveKind
 	Column     int
 	MoveNumber int
+	PlayedAt   time.Time
+	// Undone is the number of plies removed by a TAKEBACK entry.
+	Undone int
 }
 
 // GameManager creates and stores game sessions.
 type GameManager struct {
-	mu    sync.RWMutex
-	games map[string]*Game
+	mu        sync.RWMutex
+	games     map[string]*Game
+	timers    map[string]*time.Timer
+	onTimeout func(*Game)
 }
 
 // NewManager returns a ready-to-use GameManager.
 func NewManager() *GameManager {
 	return &GameManager{
-		games: make(map[string]*Game),
+		games:  make(map[string]*Game),
+		timers: make(map[string]*time.Timer),
 	}
 }
 
-// CreateGame registers a new game for two participants.
-func (m *GameManager) CreateGame(player1, player2 string) *Game {
-	game := &Game{
-		ID:          uuid.NewString(),
-		Player1:     player1,
-		Player2:     player2,
-		Board:       newBoard(),
-		CurrentTurn: 1,
-		CreatedAt:   time.Now().UTC(),
-		Moves:       make([]Move, 0, Rows*Columns),
+// CreateGame registers a new game for two participants played under rules.
+// Timed games start player 1's clock straight away.
+func (m *GameManager) CreateGame(player1, player2 string, rules Rules, clock TimeControl) (*Game, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if err := clock.Validate(); err != nil {
+		return nil, err
 	}
 
+	game := newGame(uuid.NewString(), player1, player2, rules, time.Now().UTC())
+	game.startClock(clock, game.CreatedAt)
+
 	m.mu.Lock()
 	m.games[game.ID] = game
+	m.armClock(game)
 	m.mu.Unlock()
 
+	return game, nil
+}
+
+// SetRated marks a game as rated or casual.
+func (m *GameManager) SetRated(gameID string, rated bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+	game.Rated = rated
+	return nil
+}
+
+// SetBotLevel records the level of the bot in a game, for reporting. It does
+// not change how the bot plays, which follows the bot's registered strategy.
+func (m *GameManager) SetBotLevel(gameID, level string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+	game.BotLevel = level
+	return nil
+}
+
+// newGame returns an empty game at the start position.
+func newGame(id, player1, player2 string, rules Rules, createdAt time.Time) *Game {
+	game := &Game{
+		ID:          id,
+		Player1:     player1,
+		Player2:     player2,
+		Rules:       rules,
+		Board:       rules.NewBoard(),
+		CurrentTurn: 1,
+		CreatedAt:   createdAt,
+		Moves:       make([]Move, 0, rules.Cells()),
+		position:    NewPosition(rules),
+	}
+	game.Hash = game.position.Hash()
+	game.seen = map[Position]int{game.position: 1}
 	return game
 }
 
@@ -70,10 +164,43 @@ func (m *GameManager) GetGame(id string) (*Game, bool) {
 	return game, ok
 }
 
+// PositionOf returns a consistent copy of a game's position, safe to search
+// while other goroutines apply moves.
+func (m *GameManager) PositionOf(id string) (Position, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	game, ok := m.games[id]
+	if !ok {
+		return Position{}, false
+	}
+	return game.position, true
+}
+
+// PlayerToMove returns the username of the player on move in a game along
+// with a consistent copy of its position, as PositionOf does.
+func (m *GameManager) PlayerToMove(id string) (string, Position, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	game, ok := m.games[id]
+	if !ok {
+		return "", Position{}, false
+	}
+	if game.position.Turn() == 2 {
+		return game.Player2, game.position, true
+	}
+	return game.Player1, game.position, true
+}
+
 // RemoveGame deletes a game from the manager.
 func (m *GameManager) RemoveGame(id string) {
 	m.mu.Lock()
 	delete(m.games, id)
+	if timer, ok := m.timers[id]; ok {
+		timer.Stop()
+		delete(m.timers, id)
+	}
 	m.mu.Unlock()
 }
 
@@ -85,10 +212,15 @@ const (
 	WIN
 	DRAW
 	INVALID
+	ABORTED
 )
 
-// ApplyMove validates and applies a move for the given player and column.
-func (m *GameManager) ApplyMove(gameID string, player string, col int) (*Game, MoveResult, error) {
+// ApplyMove validates and applies a drop or pop for the given player and column.
+// A pop can complete lines for both players at once; the player who popped wins.
+// A move made after the mover's clock ran out loses the game on time instead.
+// The game returned is a copy taken under the lock, so callers can read it
+// while the bot and the clock go on changing the game.
+func (m *GameManager) ApplyMove(gameID string, player string, kind MoveKind, col int) (*Game, MoveResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -97,65 +229,108 @@ func (m *GameManager) ApplyMove(gameID string, player string, col int) (*Game, M
 		return nil, INVALID, fmt.Errorf("game %s not found", gameID)
 	}
 
-	if game.Winner != nil {
-		return game, INVALID, errors.New("game already finished")
+	result, err := game.apply(player, kind, col, time.Now().UTC())
+	m.armClock(game)
+	return game.snapshot(), result, err
+}
+
+// snapshot returns a copy of g that later changes to g leave alone. Boards
+// are replaced rather than written to, so the copy shares the rows.
+func (g *Game) snapshot() *Game {
+	c := *g
+	c.Moves = slices.Clone(g.Moves)
+	c.Hints = slices.Clone(g.Hints)
+	c.seen = nil
+	return &c
+}
+
+// apply is the single state transition shared by live play and Replay: it
+// validates the move, appends it to the log and derives the rest of the state.
+func (g *Game) apply(player string, kind MoveKind, col int, at time.Time) (MoveResult, error) {
+	if g.Winner != nil || g.EndedAt != nil {
+		return INVALID, errors.New("game already finished")
 	}
 
 	var playerNum int
 	switch player {
-	case game.Player1:
+	case g.Player1:
 		playerNum = 1
-	case game.Player2:
+	case g.Player2:
 		playerNum = 2
 	default:
-		return game, INVALID, errors.New("player not part of this game")
+		return INVALID, errors.New("player not part of this game")
 	}
 
-	if game.CurrentTurn != playerNum {
-		return game, INVALID, errors.New("not your turn")
+	if g.CurrentTurn != playerNum {
+		return INVALID, errors.New("not your turn")
+	}
+	if result := g.checkClock(at); result != CONTINUE {
+		return result, nil
 	}
 
-	newBoard, _, err := DropDisc(game.Board, col, playerNum)
-	if err != nil {
-		return game, INVALID, err
+	if err := g.position.Apply(Ply{Kind: kind, Column: col}); err != nil {
+		return INVALID, err
+	}
+	g.spend(at, true)
+	if kind == DROP {
+		g.Board = withCell(g.Board, g.position.topRow(col), col, playerNum)
+	} else {
+		g.Board = g.position.Board()
 	}
 
-	game.Board = newBoard
-	game.Winner = nil
-	game.Moves = append(game.Moves, Move{Player: player, Column: col, MoveNumber: len(game.Moves) + 1})
+	g.Hash = g.position.Hash()
+	g.Winner = nil
+	g.Moves = append(g.Moves, Move{Player: player, Kind: kind, Column: col, MoveNumber: len(g.Moves) + 1, PlayedAt: at})
 
-	if CheckWin(newBoard, playerNum) {
-		winner := player
-		game.Winner = &winner
-		finished := time.Now().UTC()
-		game.EndedAt = &finished
-		return game, WIN, nil
+	if g.seen == nil {
+		g.seen = make(map[Position]int)
 	}
+	if winnerNum, over := g.position.outcome(playerNum, g.seen); over {
+		if winnerNum == 0 {
+			reason := TerminationBoardFull
+			if g.Rules.PopOut && g.seen[g.position] >= RepetitionLimit {
+				reason = TerminationRepetition
+			}
+			return finishGame(g, nil, reason, at), nil
+		}
 
-	if IsBoardFull(newBoard) {
-		finished := time.Now().UTC()
-		game.EndedAt = &finished
-		return game, DRAW, nil
+		winner := g.Player1
+		if winnerNum == 2 {
+			winner = g.Player2
+		}
+		g.WinningCells = g.position.WinningCells(winnerNum)
+		return finishGame(g, &winner, TerminationConnect, at), nil
 	}
 
-	if playerNum == 1 {
-		game.CurrentTurn = 2
-	} else {
-		game.CurrentTurn = 1
-	}
+	g.CurrentTurn = g.position.Turn()
 
 	// Reset EndedAt when continuing gameplay to avoid stale timestamps.
-	game.EndedAt = nil
+	g.EndedAt = nil
 
-	return game, CONTINUE, nil
+	return CONTINUE, nil
 }
 
-func newBoard() [][]int {
-	board := make([][]int, Rows)
-	for r := 0; r < Rows; r++ {
-		board[r] = make([]int, Columns)
+// finishGame stamps the end of a game and records the winner, if any, and why it ended.
+func finishGame(game *Game, winner *string, reason Termination, at time.Time) MoveResult {
+	finished := at
+	game.EndedAt = &finished
+	game.Termination = reason
+	if winner == nil {
+		return DRAW
 	}
-	return board
+
+	name := *winner
+	game.Winner = &name
+	return WIN
+}
+
+// withCell returns a copy of board with a single cell changed. Only the touched
+// row is reallocated; previously published boards are never mutated.
+func withCell(board [][]int, row, col, value int) [][]int {
+	updated := append([][]int(nil), board...)
+	updated[row] = append([]int(nil), board[row]...)
+	updated[row][col] = value
+	return updated
 }
 
 // FindGameByPlayers searches for a game containing both players, regardless of order.
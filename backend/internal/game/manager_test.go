@@ -2,11 +2,27 @@ package game
 
 import "testing"
 
+// createGameWith is the fixture behind createGame and its variants.
+func createGameWith(t *testing.T, gm *GameManager, player1, player2 string, rules Rules, clock TimeControl) *Game {
+	t.Helper()
+
+	g, err := gm.CreateGame(player1, player2, rules, clock)
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	return g
+}
+
+func createGame(t *testing.T, gm *GameManager, player1, player2 string) *Game {
+	t.Helper()
+	return createGameWith(t, gm, player1, player2, DefaultRules(), TimeControl{})
+}
+
 func TestApplyMoveSwitchesTurn(t *testing.T) {
 	gm := NewManager()
-	g := gm.CreateGame("alice", "bob")
+	g := createGame(t, gm, "alice", "bob")
 
-	updated, result, err := gm.ApplyMove(g.ID, "alice", 0)
+	updated, result, err := gm.ApplyMove(g.ID, "alice", DROP, 0)
 	if err != nil {
 		t.Fatalf("apply move failed: %v", err)
 	}
@@ -19,13 +35,21 @@ func TestApplyMoveSwitchesTurn(t *testing.T) {
 	if updated.Board[Rows-1][0] != 1 {
 		t.Fatalf("expected disc in column 0")
 	}
+
+	pos, err := PositionFromMoves(classic, "1")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+	if updated.Hash != pos.Hash() {
+		t.Fatalf("expected game hash to track the position")
+	}
 }
 
 func TestApplyMoveRejectsWrongTurn(t *testing.T) {
 	gm := NewManager()
-	g := gm.CreateGame("alice", "bob")
+	g := createGame(t, gm, "alice", "bob")
 
-	_, result, err := gm.ApplyMove(g.ID, "bob", 0)
+	_, result, err := gm.ApplyMove(g.ID, "bob", DROP, 0)
 	if err == nil {
 		t.Fatalf("expected error for wrong turn")
 	}
@@ -36,7 +60,7 @@ func TestApplyMoveRejectsWrongTurn(t *testing.T) {
 
 func TestApplyMoveDetectsWin(t *testing.T) {
 	gm := NewManager()
-	g := gm.CreateGame("alice", "bob")
+	g := createGame(t, gm, "alice", "bob")
 
 	sequences := []struct {
 		player string
@@ -51,12 +75,12 @@ func TestApplyMoveDetectsWin(t *testing.T) {
 	}
 
 	for _, move := range sequences {
-		if _, _, err := gm.ApplyMove(g.ID, move.player, move.col); err != nil {
+		if _, _, err := gm.ApplyMove(g.ID, move.player, DROP, move.col); err != nil {
 			t.Fatalf("setup move failed: %v", err)
 		}
 	}
 
-	updated, result, err := gm.ApplyMove(g.ID, "alice", 0)
+	updated, result, err := gm.ApplyMove(g.ID, "alice", DROP, 0)
 	if err != nil {
 		t.Fatalf("winning move failed: %v", err)
 	}
@@ -66,11 +90,19 @@ func TestApplyMoveDetectsWin(t *testing.T) {
 	if updated.Winner == nil || *updated.Winner != "alice" {
 		t.Fatalf("expected winner alice")
 	}
+	if len(updated.WinningCells) != 4 {
+		t.Fatalf("expected 4 winning cells, got %v", updated.WinningCells)
+	}
+	for _, cell := range updated.WinningCells {
+		if cell.Col != 0 || cell.Row < Rows-4 {
+			t.Fatalf("unexpected winning cell %v", cell)
+		}
+	}
 }
 
 func TestApplyMoveDraw(t *testing.T) {
 	gm := NewManager()
-	g := gm.CreateGame("alice", "bob")
+	g := createGame(t, gm, "alice", "bob")
 
 	pattern := [][]int{
 		{0, 1, 2, 2, 1, 1, 2},
@@ -81,25 +113,232 @@ func TestApplyMoveDraw(t *testing.T) {
 		{2, 2, 1, 1, 2, 2, 1},
 	}
 
-	clone := newBoard()
+	clone := classic.NewBoard()
 	for r := 0; r < Rows; r++ {
 		copy(clone[r], pattern[r])
 	}
 
-	g.Board = clone
-	g.CurrentTurn = 1
+	pos, err := PositionFromBoard(classic, clone, 1)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	g.SetPosition(pos)
 
-	updated, result, err := gm.ApplyMove(g.ID, "alice", 0)
+	updated, result, err := gm.ApplyMove(g.ID, "alice", DROP, 0)
 	if err != nil {
 		t.Fatalf("draw move failed: %v", err)
 	}
 	if result != DRAW {
 		t.Fatalf("expected DRAW, got %v", result)
 	}
-	if !IsBoardFull(updated.Board) {
+	if !classic.IsBoardFull(updated.Board) {
 		t.Fatalf("expected board to be full after draw")
 	}
+	if updated.Termination != TerminationBoardFull {
+		t.Fatalf("expected BOARD_FULL termination, got %q", updated.Termination)
+	}
 	if updated.Winner != nil {
 		t.Fatalf("draw should not set winner")
 	}
 }
+
+func TestApplyMoveLargeBoardConnectFive(t *testing.T) {
+	gm := NewManager()
+	g, err := gm.CreateGame("alice", "bob", Rules{Rows: 8, Columns: 9, Connect: 5}, TimeControl{})
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, result, err := gm.ApplyMove(g.ID, "alice", DROP, 8); err != nil || result != CONTINUE {
+			t.Fatalf("alice move %d: result %v err %v", i, result, err)
+		}
+		if _, result, err := gm.ApplyMove(g.ID, "bob", DROP, 0); err != nil || result != CONTINUE {
+			t.Fatalf("bob move %d: result %v err %v", i, result, err)
+		}
+	}
+
+	updated, result, err := gm.ApplyMove(g.ID, "alice", DROP, 8)
+	if err != nil {
+		t.Fatalf("winning move failed: %v", err)
+	}
+	if result != WIN {
+		t.Fatalf("expected WIN on fifth disc, got %v", result)
+	}
+	if len(updated.Board) != 8 || len(updated.Board[0]) != 9 {
+		t.Fatalf("unexpected board dimensions %dx%d", len(updated.Board), len(updated.Board[0]))
+	}
+}
+
+func TestApplyMoveReturnsSnapshot(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+
+	first, _, err := gm.ApplyMove(g.ID, "alice", DROP, 3)
+	if err != nil {
+		t.Fatalf("first move: %v", err)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "bob", DROP, 3); err != nil {
+		t.Fatalf("second move: %v", err)
+	}
+
+	if first.CurrentTurn != 2 || len(first.Moves) != 1 || first.Board[Rows-2][3] != 0 {
+		t.Fatalf("expected the first move's game to be left alone, got turn %d after %d moves", first.CurrentTurn, len(first.Moves))
+	}
+}
+
+func TestPlayerToMoveFollowsTheTurn(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+
+	if player, pos, ok := gm.PlayerToMove(g.ID); !ok || player != "alice" || pos.Moves() != 0 {
+		t.Fatalf("expected alice on move at the start, got %q after %d moves", player, pos.Moves())
+	}
+	playMoves(t, gm, g, 3)
+	if player, pos, ok := gm.PlayerToMove(g.ID); !ok || player != "bob" || pos.Moves() != 1 {
+		t.Fatalf("expected bob on move after one drop, got %q after %d moves", player, pos.Moves())
+	}
+	if _, _, ok := gm.PlayerToMove("missing"); ok {
+		t.Fatalf("expected no player for an unknown game")
+	}
+}
+
+func TestCreateGameRejectsInvalidRules(t *testing.T) {
+	gm := NewManager()
+	if _, err := gm.CreateGame("alice", "bob", Rules{Rows: 2, Columns: 2, Connect: 4}, TimeControl{}); err == nil {
+		t.Fatalf("expected invalid rules to be rejected")
+	}
+}
+
+func createPopOutGame(t *testing.T, gm *GameManager) *Game {
+	t.Helper()
+
+	rules := DefaultRules()
+	rules.PopOut = true
+	return createGameWith(t, gm, "alice", "bob", rules, TimeControl{})
+}
+
+func TestApplyMovePopShiftsColumn(t *testing.T) {
+	gm := NewManager()
+	g := createPopOutGame(t, gm)
+
+	if _, _, err := gm.ApplyMove(g.ID, "alice", DROP, 2); err != nil {
+		t.Fatalf("setup move: %v", err)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "bob", DROP, 2); err != nil {
+		t.Fatalf("setup move: %v", err)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "alice", POP, 3); err == nil {
+		t.Fatalf("expected error when popping an empty column")
+	}
+
+	updated, result, err := gm.ApplyMove(g.ID, "alice", POP, 2)
+	if err != nil {
+		t.Fatalf("pop failed: %v", err)
+	}
+	if result != CONTINUE {
+		t.Fatalf("expected CONTINUE, got %v", result)
+	}
+	if updated.Board[Rows-1][2] != 2 || updated.Board[Rows-2][2] != 0 {
+		t.Fatalf("expected bob's disc to drop to the bottom")
+	}
+	last := updated.Moves[len(updated.Moves)-1]
+	if last.Kind != POP || last.Column != 2 {
+		t.Fatalf("expected POP move recorded, got %+v", last)
+	}
+
+	if _, _, err := gm.ApplyMove(g.ID, "bob", POP, 0); err == nil {
+		t.Fatalf("expected error when popping an empty column")
+	}
+}
+
+func TestApplyMovePopRejectedInClassicGame(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+
+	if _, _, err := gm.ApplyMove(g.ID, "alice", DROP, 0); err != nil {
+		t.Fatalf("setup move: %v", err)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "bob", DROP, 1); err != nil {
+		t.Fatalf("setup move: %v", err)
+	}
+	if _, result, err := gm.ApplyMove(g.ID, "alice", POP, 0); err == nil || result != INVALID {
+		t.Fatalf("expected pop to be rejected, got result %v err %v", result, err)
+	}
+}
+
+func TestApplyMovePopCompletingBothLinesWinsForMover(t *testing.T) {
+	gm := NewManager()
+	g := createPopOutGame(t, gm)
+
+	board := g.Rules.NewBoard()
+	copy(board[Rows-1], []int{1, 2, 2, 2, 0, 0, 0})
+	copy(board[Rows-2], []int{2, 1, 1, 1, 0, 0, 0})
+	board[Rows-3][0] = 1
+	board[Rows-4][0] = 2
+
+	pos, err := PositionFromBoard(g.Rules, board, 1)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	g.SetPosition(pos)
+
+	updated, result, err := gm.ApplyMove(g.ID, "alice", POP, 0)
+	if err != nil {
+		t.Fatalf("pop failed: %v", err)
+	}
+	if result != WIN {
+		t.Fatalf("expected WIN, got %v", result)
+	}
+	if !g.Rules.CheckWin(updated.Board, 1) || !g.Rules.CheckWin(updated.Board, 2) {
+		t.Fatalf("expected lines for both players after the pop")
+	}
+	if updated.Winner == nil || *updated.Winner != "alice" {
+		t.Fatalf("expected the popping player to win")
+	}
+	for _, cell := range updated.WinningCells {
+		if updated.Board[cell.Row][cell.Col] != 1 {
+			t.Fatalf("winning cells should only cover the winner's line, got %v", updated.WinningCells)
+		}
+	}
+}
+
+func TestApplyMovePopOutThreefoldRepetitionDraws(t *testing.T) {
+	gm := NewManager()
+	g := createPopOutGame(t, gm)
+
+	cycle := []struct {
+		player string
+		kind   MoveKind
+		col    int
+	}{
+		{"alice", DROP, 0},
+		{"bob", DROP, 1},
+		{"alice", POP, 0},
+		{"bob", POP, 1},
+	}
+
+	var result MoveResult
+	for round := 0; round < 2; round++ {
+		for i, move := range cycle {
+			var err error
+			_, result, err = gm.ApplyMove(g.ID, move.player, move.kind, move.col)
+			if err != nil {
+				t.Fatalf("round %d move %d failed: %v", round, i, err)
+			}
+			if round == 0 && result != CONTINUE {
+				t.Fatalf("expected game to continue in the first cycle, got %v", result)
+			}
+		}
+	}
+
+	if result != DRAW {
+		t.Fatalf("expected DRAW on the third repetition, got %v", result)
+	}
+	if g.Termination != TerminationRepetition {
+		t.Fatalf("expected REPETITION termination, got %q", g.Termination)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "alice", DROP, 0); err == nil {
+		t.Fatalf("expected moves after a draw to be rejected")
+	}
+}
@@ -0,0 +1,121 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Move notation lists plies in order as 1-based columns, e.g. "4453". Columns
+// 10 to 16 are written as the letters A to G, and a pop is prefixed with "p".
+// Whitespace between plies is ignored.
+const (
+	popPrefix     = 'p'
+	columnSymbols = "123456789ABCDEFG"
+)
+
+// ParseMoves decodes a move string and checks that every ply is legal under
+// rules. Sequences that continue after the game has ended are rejected.
+func ParseMoves(rules Rules, notation string) ([]Ply, error) {
+	plies, err := parsePlies(rules, notation)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := replayPlies(rules, plies); err != nil {
+		return nil, err
+	}
+	return plies, nil
+}
+
+// parsePlies decodes the syntax of a move string without checking legality.
+func parsePlies(rules Rules, notation string) ([]Ply, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	var plies []Ply
+	pop := false
+	for i, ch := range notation {
+		switch {
+		case unicode.IsSpace(ch):
+			if pop {
+				return nil, fmt.Errorf("pop marker must be followed by a column at offset %d", i)
+			}
+			continue
+		case ch == popPrefix:
+			if pop {
+				return nil, fmt.Errorf("repeated pop marker at offset %d", i)
+			}
+			pop = true
+			continue
+		}
+
+		col := strings.IndexRune(columnSymbols, ch)
+		if col < 0 || col >= rules.Columns {
+			return nil, fmt.Errorf("invalid column %q at offset %d", ch, i)
+		}
+
+		ply := Ply{Kind: DROP, Column: col}
+		if pop {
+			ply.Kind = POP
+			pop = false
+		}
+		plies = append(plies, ply)
+	}
+	if pop {
+		return nil, errors.New("move string ends with a pop marker")
+	}
+	return plies, nil
+}
+
+// EncodeMoves renders plies in move notation after checking they form a legal game.
+func EncodeMoves(rules Rules, plies []Ply) (string, error) {
+	if err := rules.Validate(); err != nil {
+		return "", err
+	}
+	if _, err := replayPlies(rules, plies); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for _, ply := range plies {
+		if ply.Kind == POP {
+			b.WriteRune(popPrefix)
+		}
+		b.WriteByte(columnSymbols[ply.Column])
+	}
+	return b.String(), nil
+}
+
+// PositionFromMoves rebuilds the position reached by a move string, including
+// the player to move.
+func PositionFromMoves(rules Rules, notation string) (Position, error) {
+	plies, err := parsePlies(rules, notation)
+	if err != nil {
+		return Position{}, err
+	}
+	return replayPlies(rules, plies)
+}
+
+// replayPlies plays plies from the empty board, failing on the first illegal
+// ply or on any ply after the game is over.
+func replayPlies(rules Rules, plies []Ply) (Position, error) {
+	pos := NewPosition(rules)
+	seen := map[Position]int{pos: 1}
+	over := false
+
+	for i, ply := range plies {
+		if over {
+			return Position{}, fmt.Errorf("move %d is played after the game ended", i+1)
+		}
+
+		mover := pos.Turn()
+		if err := pos.Apply(ply); err != nil {
+			return Position{}, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		_, over = pos.outcome(mover, seen)
+	}
+
+	return pos, nil
+}
@@ -0,0 +1,94 @@
+package game
+
+import "testing"
+
+func TestParseMovesRoundTrip(t *testing.T) {
+	plies, err := ParseMoves(classic, "4453 12")
+	if err != nil {
+		t.Fatalf("parse moves: %v", err)
+	}
+
+	want := []Ply{{DROP, 3}, {DROP, 3}, {DROP, 4}, {DROP, 2}, {DROP, 0}, {DROP, 1}}
+	if len(plies) != len(want) {
+		t.Fatalf("expected %d plies, got %v", len(want), plies)
+	}
+	for i := range want {
+		if plies[i] != want[i] {
+			t.Fatalf("ply %d: expected %v, got %v", i, want[i], plies[i])
+		}
+	}
+
+	encoded, err := EncodeMoves(classic, plies)
+	if err != nil {
+		t.Fatalf("encode moves: %v", err)
+	}
+	if encoded != "445312" {
+		t.Fatalf("expected 445312, got %q", encoded)
+	}
+}
+
+func TestParseMovesRejectsIllegalSequences(t *testing.T) {
+	cases := map[string]string{
+		"column out of range": "48",
+		"zero column":         "40",
+		"unknown symbol":      "4x",
+		"full column":         "1111111",
+		"pop without popout":  "12p1",
+		"dangling pop marker": "12p",
+		"move after win":      "12121213",
+	}
+	for name, notation := range cases {
+		if _, err := ParseMoves(classic, notation); err == nil {
+			t.Fatalf("%s: expected %q to be rejected", name, notation)
+		}
+	}
+
+	if _, err := ParseMoves(classic, "1212121"); err != nil {
+		t.Fatalf("expected moves up to the win to parse: %v", err)
+	}
+}
+
+func TestParseMovesPopOutAndWideBoards(t *testing.T) {
+	popOut := DefaultRules()
+	popOut.PopOut = true
+
+	plies, err := ParseMoves(popOut, "12p1")
+	if err != nil {
+		t.Fatalf("parse popout moves: %v", err)
+	}
+	if plies[2] != (Ply{POP, 0}) {
+		t.Fatalf("expected pop of column 0, got %v", plies[2])
+	}
+	if _, err := ParseMoves(popOut, "12p2"); err == nil {
+		t.Fatalf("expected popping the opponent's disc to be rejected")
+	}
+	if encoded, err := EncodeMoves(popOut, plies); err != nil || encoded != "12p1" {
+		t.Fatalf("expected 12p1, got %q err %v", encoded, err)
+	}
+
+	wide := Rules{Rows: 6, Columns: 12, Connect: 4}
+	plies, err = ParseMoves(wide, "AC9")
+	if err != nil {
+		t.Fatalf("parse wide board moves: %v", err)
+	}
+	if plies[0].Column != 9 || plies[1].Column != 11 || plies[2].Column != 8 {
+		t.Fatalf("unexpected wide board columns %v", plies)
+	}
+}
+
+func TestPositionFromMoves(t *testing.T) {
+	pos, err := PositionFromMoves(classic, "4453")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+	if pos.Turn() != 1 || pos.Moves() != 4 {
+		t.Fatalf("expected player 1 to move after 4 plies, got turn %d moves %d", pos.Turn(), pos.Moves())
+	}
+	if pos.Cell(Rows-1, 3) != 1 || pos.Cell(Rows-2, 3) != 2 || pos.Cell(Rows-1, 4) != 1 || pos.Cell(Rows-1, 2) != 2 {
+		t.Fatalf("unexpected board %v", pos.Board())
+	}
+
+	if _, err := PositionFromMoves(classic, "11111111"); err == nil {
+		t.Fatalf("expected illegal sequence to be rejected")
+	}
+}
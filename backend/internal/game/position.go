@@ -0,0 +1,342 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Position is a compact board representation backed by one bitboard per player.
+// Moves, undos and win detection run in constant time, which makes it suitable
+// for search. It converts losslessly to and from the [][]int board shape.
+//
+// Bit layout: each column occupies Rows+1 consecutive bits, bottom cell first.
+// The extra bit on top of every column is always empty so that shifted line
+// checks cannot wrap from one column into the next.
+type Position struct {
+	rules  Rules
+	stones [2]bitboard
+	height [MaxColumns]int
+	turn   int
+	moves  int
+	hash   uint64
+	mirror uint64
+}
+
+// NewPosition returns an empty position for rules with player 1 to move.
+func NewPosition(rules Rules) Position {
+	key := rulesKey(rules)
+	return Position{rules: rules, turn: 1, hash: key, mirror: key}
+}
+
+// PositionFromBoard builds a position from a row-major board (row 0 at the top)
+// with the given player to move. Discs must rest on the bottom or on another disc.
+func PositionFromBoard(rules Rules, board [][]int, turn int) (Position, error) {
+	if err := rules.Validate(); err != nil {
+		return Position{}, err
+	}
+	if turn != 1 && turn != 2 {
+		return Position{}, errors.New("invalid player")
+	}
+	if len(board) != rules.Rows {
+		return Position{}, fmt.Errorf("board must have %d rows", rules.Rows)
+	}
+	for r, row := range board {
+		if len(row) != rules.Columns {
+			return Position{}, fmt.Errorf("board row %d must have %d columns", r, rules.Columns)
+		}
+	}
+
+	pos := NewPosition(rules)
+	if turn == 2 {
+		pos.turn = 2
+		pos.hash ^= zobristTurn
+		pos.mirror ^= zobristTurn
+	}
+	for c := 0; c < rules.Columns; c++ {
+		for r := rules.Rows - 1; r >= 0; r-- {
+			cell := board[r][c]
+			switch {
+			case cell == 0:
+				continue
+			case cell != 1 && cell != 2:
+				return Position{}, fmt.Errorf("invalid cell value %d at row %d column %d", cell, r, c)
+			case rules.Rows-1-r != pos.height[c]:
+				return Position{}, fmt.Errorf("floating disc at row %d column %d", r, c)
+			}
+
+			pos.stones[cell-1] = pos.stones[cell-1].or(pos.bit(rules.Rows-1-r, c))
+			pos.toggleCell(cell, rules.Rows-1-r, c)
+			pos.height[c]++
+			pos.moves++
+		}
+	}
+
+	return pos, nil
+}
+
+// Rules returns the rules the position is played under.
+func (p *Position) Rules() Rules {
+	return p.rules
+}
+
+// Board renders the position as a freshly allocated row-major board.
+func (p *Position) Board() [][]int {
+	board := p.rules.NewBoard()
+	for c := 0; c < p.rules.Columns; c++ {
+		for h := 0; h < p.height[c]; h++ {
+			board[p.rules.Rows-1-h][c] = p.cellOwner(h, c)
+		}
+	}
+	return board
+}
+
+// Turn returns the player to move, 1 or 2.
+func (p *Position) Turn() int {
+	return p.turn
+}
+
+// Moves returns the number of discs on the board.
+func (p *Position) Moves() int {
+	return p.moves
+}
+
+// Cell returns the owner of the cell at row (0 at the top) and column, or 0 if empty.
+func (p *Position) Cell(row, col int) int {
+	if row < 0 || row >= p.rules.Rows || col < 0 || col >= p.rules.Columns {
+		return 0
+	}
+	h := p.rules.Rows - 1 - row
+	if h >= p.height[col] {
+		return 0
+	}
+	return p.cellOwner(h, col)
+}
+
+// Ply is a single drop or pop, independent of who played it.
+type Ply struct {
+	Kind   MoveKind
+	Column int
+}
+
+// RepetitionLimit is the number of times a PopOut position may occur before
+// the game is drawn.
+const RepetitionLimit = 3
+
+// Apply validates ply for the player to move and plays it.
+func (p *Position) Apply(ply Ply) error {
+	if ply.Column < 0 || ply.Column >= p.rules.Columns {
+		return errors.New("invalid column")
+	}
+
+	switch ply.Kind {
+	case DROP:
+		if !p.CanPlay(ply.Column) {
+			return errors.New("column is full")
+		}
+		p.Play(ply.Column)
+	case POP:
+		if !p.rules.PopOut {
+			return errors.New("pop moves are not allowed")
+		}
+		if !p.CanPop(ply.Column) {
+			return errors.New("can only pop your own disc from the bottom row")
+		}
+		p.Pop(ply.Column)
+	default:
+		return fmt.Errorf("unknown move kind %q", ply.Kind)
+	}
+	return nil
+}
+
+// outcome decides whether the game ended with mover's last ply. It returns the
+// winning player (0 for a draw) and whether the game is over. When a pop
+// completes lines for both players the mover wins. Under PopOut, seen counts
+// position occurrences for the threefold repetition rule and is updated here.
+func (p *Position) outcome(mover int, seen map[Position]int) (int, bool) {
+	switch {
+	case p.HasWon(mover):
+		return mover, true
+	case p.HasWon(3 - mover):
+		return 3 - mover, true
+	}
+
+	if !p.rules.PopOut {
+		return 0, p.IsFull()
+	}
+
+	seen[*p]++
+	return 0, seen[*p] >= RepetitionLimit || !p.HasLegalMove()
+}
+
+// CanPlay reports whether col is on the board and not full.
+func (p *Position) CanPlay(col int) bool {
+	return col >= 0 && col < p.rules.Columns && p.height[col] < p.rules.Rows
+}
+
+// Play drops a disc for the player to move into col and passes the turn.
+// It returns the board row (0 at the top) the disc landed in. The caller must
+// check CanPlay first.
+func (p *Position) Play(col int) int {
+	h := p.height[col]
+	p.stones[p.turn-1] = p.stones[p.turn-1].or(p.bit(h, col))
+	p.toggleCell(p.turn, h, col)
+	p.height[col]++
+	p.moves++
+	p.passTurn()
+	return p.rules.Rows - 1 - h
+}
+
+// Undo removes the top disc of col, which must have been the last move played,
+// and hands the turn back to the player who made it.
+func (p *Position) Undo(col int) {
+	p.passTurn()
+	p.height[col]--
+	p.moves--
+	p.stones[p.turn-1] = p.stones[p.turn-1].andNot(p.bit(p.height[col], col))
+	p.toggleCell(p.turn, p.height[col], col)
+}
+
+func (p *Position) passTurn() {
+	p.turn = 3 - p.turn
+	p.hash ^= zobristTurn
+	p.mirror ^= zobristTurn
+}
+
+// topRow returns the board row (0 at the top) of the highest disc in col.
+func (p *Position) topRow(col int) int {
+	return p.rules.Rows - p.height[col]
+}
+
+// CanPop reports whether the player to move may pop the bottom disc of col.
+// Pops are only allowed under PopOut rules and only for the mover's own discs.
+func (p *Position) CanPop(col int) bool {
+	return p.rules.PopOut && col >= 0 && col < p.rules.Columns && p.height[col] > 0 &&
+		p.stones[p.turn-1].has(cellIndex(p.rules, 0, col))
+}
+
+// Pop removes the mover's disc from the bottom of col, shifts the rest of the
+// column down and passes the turn. The caller must check CanPop first.
+func (p *Position) Pop(col int) {
+	column := bitboard{lo: 1<<p.rules.Rows - 1}.shl(col * (p.rules.Rows + 1))
+	p.toggleColumn(col)
+	for i, stones := range p.stones {
+		p.stones[i] = stones.andNot(column).or(stones.and(column).shr(1).and(column))
+	}
+	p.height[col]--
+	p.toggleColumn(col)
+	p.moves--
+	p.passTurn()
+}
+
+// HasLegalMove reports whether the player to move can drop or pop anywhere.
+func (p *Position) HasLegalMove() bool {
+	for col := 0; col < p.rules.Columns; col++ {
+		if p.CanPlay(col) || p.CanPop(col) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasWon reports whether player has a complete line.
+func (p *Position) HasWon(player int) bool {
+	return hasLine(p.stones[player-1], p.rules)
+}
+
+// IsWinningMove reports whether dropping a disc for player into col would
+// complete a line. The position is left unchanged.
+func (p *Position) IsWinningMove(col, player int) bool {
+	if !p.CanPlay(col) {
+		return false
+	}
+	return hasLine(p.stones[player-1].or(p.bit(p.height[col], col)), p.rules)
+}
+
+// WinningCells returns every cell that is part of a complete line for player,
+// ordered top to bottom and left to right. Overlapping lines are merged.
+func (p *Position) WinningCells(player int) []Cell {
+	return lineCells(p.stones[player-1], p.rules)
+}
+
+// IsFull reports whether every cell is occupied.
+func (p *Position) IsFull() bool {
+	return p.moves == p.rules.Cells()
+}
+
+func (p *Position) cellOwner(h, col int) int {
+	if p.stones[0].has(cellIndex(p.rules, h, col)) {
+		return 1
+	}
+	return 2
+}
+
+func (p *Position) bit(h, col int) bitboard {
+	return bitAt(cellIndex(p.rules, h, col))
+}
+
+// cellIndex maps a height above the bottom and a column to a bit index.
+func cellIndex(rules Rules, h, col int) int {
+	return col*(rules.Rows+1) + h
+}
+
+// lineShifts returns the bit distance between neighbouring cells in each
+// direction: vertical, horizontal and both diagonals.
+func lineShifts(rules Rules) [4]int {
+	stride := rules.Rows + 1
+	return [4]int{1, stride, stride - 1, stride + 1}
+}
+
+// hasLine checks all four directions with shifted intersections.
+func hasLine(b bitboard, rules Rules) bool {
+	for _, shift := range lineShifts(rules) {
+		m := b
+		for k := 1; k < rules.Connect && !m.isZero(); k++ {
+			m = m.and(b.shr(k * shift))
+		}
+		if !m.isZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// lineCells expands the start of every complete line back into its cells.
+func lineCells(b bitboard, rules Rules) []Cell {
+	var cells bitboard
+	for _, shift := range lineShifts(rules) {
+		starts := b
+		for k := 1; k < rules.Connect && !starts.isZero(); k++ {
+			starts = starts.and(b.shr(k * shift))
+		}
+		for k := 0; k < rules.Connect && !starts.isZero(); k++ {
+			cells = cells.or(starts.shl(k * shift))
+		}
+	}
+	if cells.isZero() {
+		return nil
+	}
+
+	var out []Cell
+	for row := 0; row < rules.Rows; row++ {
+		for col := 0; col < rules.Columns; col++ {
+			if cells.has(cellIndex(rules, rules.Rows-1-row, col)) {
+				out = append(out, Cell{Row: row, Col: col})
+			}
+		}
+	}
+	return out
+}
+
+// boardBits collects the cells owned by player from an arbitrary board,
+// without requiring the discs to obey gravity.
+func boardBits(rules Rules, board [][]int, player int) bitboard {
+	var b bitboard
+	for r := 0; r < rules.Rows && r < len(board); r++ {
+		for c := 0; c < rules.Columns && c < len(board[r]); c++ {
+			if board[r][c] == player {
+				b = b.or(bitAt(cellIndex(rules, rules.Rows-1-r, c)))
+			}
+		}
+	}
+	return b
+}
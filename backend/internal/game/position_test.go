@@ -0,0 +1,197 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestPositionBoardRoundTrip(t *testing.T) {
+	board := classic.NewBoard()
+	board[Rows-1][0] = 1
+	board[Rows-2][0] = 2
+	board[Rows-1][3] = 2
+	board[Rows-1][6] = 1
+
+	pos, err := PositionFromBoard(classic, board, 1)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	if pos.Moves() != 4 {
+		t.Fatalf("expected 4 discs, got %d", pos.Moves())
+	}
+
+	out := pos.Board()
+	for r := 0; r < Rows; r++ {
+		for c := 0; c < Columns; c++ {
+			if out[r][c] != board[r][c] {
+				t.Fatalf("cell %d,%d: expected %d, got %d", r, c, board[r][c], out[r][c])
+			}
+			if pos.Cell(r, c) != board[r][c] {
+				t.Fatalf("Cell(%d,%d): expected %d, got %d", r, c, board[r][c], pos.Cell(r, c))
+			}
+		}
+	}
+}
+
+func TestPositionFromBoardRejectsInvalidBoards(t *testing.T) {
+	floating := classic.NewBoard()
+	floating[0][0] = 1
+	if _, err := PositionFromBoard(classic, floating, 1); err == nil {
+		t.Fatalf("expected error for floating disc")
+	}
+
+	badValue := classic.NewBoard()
+	badValue[Rows-1][0] = 3
+	if _, err := PositionFromBoard(classic, badValue, 1); err == nil {
+		t.Fatalf("expected error for invalid cell value")
+	}
+
+	if _, err := PositionFromBoard(classic, classic.NewBoard()[:Rows-1], 1); err == nil {
+		t.Fatalf("expected error for short board")
+	}
+
+	if _, err := PositionFromBoard(classic, classic.NewBoard(), 3); err == nil {
+		t.Fatalf("expected error for invalid turn")
+	}
+}
+
+func TestPositionPlayAndUndo(t *testing.T) {
+	pos := NewPosition(classic)
+
+	row := pos.Play(3)
+	if row != Rows-1 {
+		t.Fatalf("expected bottom row, got %d", row)
+	}
+	if pos.Turn() != 2 {
+		t.Fatalf("expected player 2 to move, got %d", pos.Turn())
+	}
+	if pos.Play(3) != Rows-2 {
+		t.Fatalf("expected second disc to stack")
+	}
+
+	pos.Undo(3)
+	if pos.Cell(Rows-2, 3) != 0 || pos.Cell(Rows-1, 3) != 1 {
+		t.Fatalf("undo removed the wrong disc")
+	}
+	if pos.Turn() != 2 || pos.Moves() != 1 {
+		t.Fatalf("expected turn 2 after one move, got turn %d moves %d", pos.Turn(), pos.Moves())
+	}
+
+	for i := 0; i < Rows; i++ {
+		pos.Play(0)
+	}
+	if pos.CanPlay(0) {
+		t.Fatalf("expected column 0 to be full")
+	}
+	if pos.CanPlay(-1) || pos.CanPlay(Columns) {
+		t.Fatalf("expected out-of-range columns to be unplayable")
+	}
+}
+
+func TestPositionWinMatchesCheckWin(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for _, rules := range []Rules{classic, {Rows: 8, Columns: 9, Connect: 5}, {Rows: 10, Columns: 11, Connect: 4}} {
+		for n := 0; n < 200; n++ {
+			pos := NewPosition(rules)
+			for !pos.IsFull() {
+				col := rng.Intn(rules.Columns)
+				if !pos.CanPlay(col) {
+					continue
+				}
+
+				mover := pos.Turn()
+				predicted := pos.IsWinningMove(col, mover)
+				pos.Play(col)
+
+				board := pos.Board()
+				if pos.HasWon(mover) != rules.CheckWin(board, mover) {
+					t.Fatalf("%s: bitboard and grid disagree on win for player %d", rules, mover)
+				}
+				if predicted != pos.HasWon(mover) {
+					t.Fatalf("%s: IsWinningMove predicted %v, got %v", rules, predicted, pos.HasWon(mover))
+				}
+
+				reloaded, err := PositionFromBoard(rules, board, pos.Turn())
+				if err != nil {
+					t.Fatalf("%s: reload board: %v", rules, err)
+				}
+				if reloaded != pos {
+					t.Fatalf("%s: board round trip changed the position", rules)
+				}
+				if pos.HasWon(mover) {
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestPositionPopMatchesPopDisc(t *testing.T) {
+	rules := DefaultRules()
+	rules.PopOut = true
+	rng := rand.New(rand.NewSource(2))
+
+	for n := 0; n < 200; n++ {
+		pos := NewPosition(rules)
+		for i := 0; i < 60 && pos.HasLegalMove(); i++ {
+			col := rng.Intn(rules.Columns)
+			before := pos.Board()
+			mover := pos.Turn()
+
+			switch {
+			case pos.CanPop(col) && rng.Intn(3) == 0:
+				want, err := rules.PopDisc(before, col, mover)
+				if err != nil {
+					t.Fatalf("PopDisc rejected a legal pop: %v", err)
+				}
+				pos.Pop(col)
+
+				reloaded, err := PositionFromBoard(rules, want, pos.Turn())
+				if err != nil {
+					t.Fatalf("reload popped board: %v", err)
+				}
+				if reloaded != pos {
+					t.Fatalf("Pop and PopDisc disagree")
+				}
+			case pos.CanPlay(col):
+				pos.Play(col)
+			}
+		}
+	}
+}
+
+func BenchmarkDropDiscCheckWin(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		board := classic.NewBoard()
+		for _, col := range benchmarkMoves {
+			board, _, _ = classic.DropDisc(board, col, 1+i%2)
+			classic.CheckWin(board, 1)
+		}
+	}
+}
+
+func BenchmarkPositionPlayHasWon(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		pos := NewPosition(classic)
+		for _, col := range benchmarkMoves {
+			pos.Play(col)
+			pos.HasWon(1)
+		}
+	}
+}
+
+func BenchmarkPositionPlayUndo(b *testing.B) {
+	pos := NewPosition(classic)
+	for i := 0; i < b.N; i++ {
+		for _, col := range benchmarkMoves {
+			pos.Play(col)
+		}
+		for j := len(benchmarkMoves) - 1; j >= 0; j-- {
+			pos.Undo(benchmarkMoves[j])
+		}
+	}
+}
+
+// benchmarkMoves is a fixed opening shared by the benchmarks above.
+var benchmarkMoves = []int{3, 3, 2, 4, 4, 2, 1, 5, 5, 1, 0, 6, 6, 0}
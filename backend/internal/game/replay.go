@@ -0,0 +1,130 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStateMismatch reports that a game's state disagrees with its move log.
+var ErrStateMismatch = errors.New("game state does not match its move log")
+
+// Replay rebuilds a game's state (board, turn, winner, winning cells, end time,
+// termination and hash) from its move log alone, validating every move exactly
+// as live play does, including takebacks, resignations, timeouts, draws and
+// aborts. Clocks are not replayed: a TIMEOUT entry is taken as recorded.
+// Player names are taken from the log: the first mover is player 1 and the
+// next distinct mover is player 2. The returned game has no ID or creation time.
+func Replay(rules Rules, moves []Move) (*Game, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	var player1, player2 string
+	for _, mv := range moves {
+		switch {
+		case mv.Kind == TAKEBACK || mv.Player == player1:
+		case player1 == "":
+			player1 = mv.Player
+		case player2 == "":
+			player2 = mv.Player
+		}
+	}
+
+	return ReplayBetween(rules, player1, player2, moves)
+}
+
+// ReplayBetween is Replay for a game whose players are already known. Use it
+// when the log alone may not name both players, such as a resignation before
+// the opponent has moved.
+func ReplayBetween(rules Rules, player1, player2 string, moves []Move) (*Game, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	g := newGame("", player1, player2, rules, time.Time{})
+	for i, mv := range moves {
+		if mv.MoveNumber != i+1 {
+			return nil, fmt.Errorf("move %d is numbered %d", i+1, mv.MoveNumber)
+		}
+
+		switch mv.Kind {
+		case TAKEBACK:
+			if err := g.takeback(mv.Player, mv.Undone, mv.PlayedAt); err != nil {
+				return nil, fmt.Errorf("move %d: %w", i+1, err)
+			}
+			continue
+		case RESIGN, FORFEIT, AGREE_DRAW, ABORT, TIMEOUT:
+			if _, err := g.end(mv.Player, mv.Kind, mv.PlayedAt); err != nil {
+				return nil, fmt.Errorf("move %d: %w", i+1, err)
+			}
+			continue
+		}
+
+		kind := mv.Kind
+		if kind == "" {
+			kind = DROP
+		}
+		if _, err := g.apply(mv.Player, kind, mv.Column, mv.PlayedAt); err != nil {
+			return nil, fmt.Errorf("move %d: %w", i+1, err)
+		}
+	}
+
+	return g, nil
+}
+
+// Verify replays a live game's move log and reports any disagreement with the
+// state the manager holds. Mismatches wrap ErrStateMismatch.
+func (m *GameManager) Verify(gameID string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+
+	replayed, err := ReplayBetween(game.Rules, game.Player1, game.Player2, game.Moves)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStateMismatch, err)
+	}
+	return compareState(game, replayed)
+}
+
+func compareState(live, replayed *Game) error {
+	for r := range replayed.Board {
+		for c := range replayed.Board[r] {
+			if r >= len(live.Board) || c >= len(live.Board[r]) || live.Board[r][c] != replayed.Board[r][c] {
+				return fmt.Errorf("%w: board differs at row %d column %d", ErrStateMismatch, r, c)
+			}
+		}
+	}
+
+	switch {
+	case live.position != replayed.position || live.Hash != replayed.Hash:
+		return fmt.Errorf("%w: position differs", ErrStateMismatch)
+	case live.CurrentTurn != replayed.CurrentTurn:
+		return fmt.Errorf("%w: current turn is %d, log says %d", ErrStateMismatch, live.CurrentTurn, replayed.CurrentTurn)
+	case !sameName(live.Winner, replayed.Winner):
+		return fmt.Errorf("%w: winner differs", ErrStateMismatch)
+	case !sameTime(live.EndedAt, replayed.EndedAt):
+		return fmt.Errorf("%w: end time differs", ErrStateMismatch)
+	case live.Termination != replayed.Termination:
+		return fmt.Errorf("%w: termination is %s, log says %s", ErrStateMismatch, live.Termination, replayed.Termination)
+	}
+	return nil
+}
+
+func sameName(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
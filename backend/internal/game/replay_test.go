@@ -0,0 +1,123 @@
+package game
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func playMoves(t *testing.T, gm *GameManager, g *Game, cols ...int) {
+	t.Helper()
+
+	for i, col := range cols {
+		player := g.Player1
+		if i%2 == 1 {
+			player = g.Player2
+		}
+		if _, _, err := gm.ApplyMove(g.ID, player, DROP, col); err != nil {
+			t.Fatalf("move %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestReplayRebuildsFinishedGame(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 0, 1, 0, 1, 0, 1, 0)
+
+	replayed, err := Replay(g.Rules, g.Moves)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if replayed.Player1 != "alice" || replayed.Player2 != "bob" {
+		t.Fatalf("unexpected players %s/%s", replayed.Player1, replayed.Player2)
+	}
+	if replayed.Winner == nil || *replayed.Winner != "alice" {
+		t.Fatalf("expected alice to win the replay")
+	}
+	if replayed.EndedAt == nil || !replayed.EndedAt.Equal(g.Moves[len(g.Moves)-1].PlayedAt) {
+		t.Fatalf("expected end time to come from the final move")
+	}
+	if len(replayed.WinningCells) != 4 {
+		t.Fatalf("expected winning cells, got %v", replayed.WinningCells)
+	}
+
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected live game to match its log: %v", err)
+	}
+}
+
+func TestVerifyDetectsDivergedState(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 3, 3)
+
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected live game to match its log: %v", err)
+	}
+
+	pos := g.Position()
+	pos.Play(4)
+	g.SetPosition(pos)
+
+	if err := gm.Verify(g.ID); !errors.Is(err, ErrStateMismatch) {
+		t.Fatalf("expected ErrStateMismatch, got %v", err)
+	}
+}
+
+func TestReplayRejectsInvalidLogs(t *testing.T) {
+	at := time.Now().UTC()
+	cases := map[string][]Move{
+		"misnumbered": {
+			{Player: "alice", Kind: DROP, Column: 0, MoveNumber: 2, PlayedAt: at},
+		},
+		"same player twice": {
+			{Player: "alice", Kind: DROP, Column: 0, MoveNumber: 1, PlayedAt: at},
+			{Player: "alice", Kind: DROP, Column: 1, MoveNumber: 2, PlayedAt: at},
+		},
+		"unknown player": {
+			{Player: "alice", Kind: DROP, Column: 0, MoveNumber: 1, PlayedAt: at},
+			{Player: "bob", Kind: DROP, Column: 1, MoveNumber: 2, PlayedAt: at},
+			{Player: "carol", Kind: DROP, Column: 1, MoveNumber: 3, PlayedAt: at},
+		},
+		"pop in classic game": {
+			{Player: "alice", Kind: DROP, Column: 0, MoveNumber: 1, PlayedAt: at},
+			{Player: "bob", Kind: DROP, Column: 1, MoveNumber: 2, PlayedAt: at},
+			{Player: "alice", Kind: POP, Column: 0, MoveNumber: 3, PlayedAt: at},
+		},
+	}
+
+	for name, moves := range cases {
+		if _, err := Replay(classic, moves); err == nil {
+			t.Fatalf("%s: expected replay to fail", name)
+		}
+	}
+}
+
+func TestReplayPopOutRepetitionDraw(t *testing.T) {
+	gm := NewManager()
+	g := createPopOutGame(t, gm)
+
+	for round := 0; round < 2; round++ {
+		for _, move := range []struct {
+			player string
+			kind   MoveKind
+			col    int
+		}{{"alice", DROP, 0}, {"bob", DROP, 1}, {"alice", POP, 0}, {"bob", POP, 1}} {
+			if _, _, err := gm.ApplyMove(g.ID, move.player, move.kind, move.col); err != nil {
+				t.Fatalf("move failed: %v", err)
+			}
+		}
+	}
+
+	replayed, err := Replay(g.Rules, g.Moves)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if replayed.EndedAt == nil || replayed.Winner != nil {
+		t.Fatalf("expected replay to end in a draw")
+	}
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected live game to match its log: %v", err)
+	}
+}
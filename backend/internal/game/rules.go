@@ -0,0 +1,81 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Classic board dimensions and line length.
+const (
+	Rows          = 6
+	Columns       = 7
+	ConnectLength = 4
+)
+
+// Board size limits. A board plus one sentinel row per column must fit in a bitboard.
+const (
+	MinSize    = 4
+	MaxColumns = 16
+	MaxRows    = 16
+	maxCells   = 128
+)
+
+// Rules describes the board dimensions and the number of discs in a row needed to win.
+// With PopOut enabled a player may instead remove one of their own discs from the bottom row.
+type Rules struct {
+	Rows    int  `json:"rows"`
+	Columns int  `json:"columns"`
+	Connect int  `json:"connect"`
+	PopOut  bool `json:"popOut,omitempty"`
+}
+
+// Cell addresses a board slot by row (0 at the top) and column.
+type Cell struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+// DefaultRules returns the classic 6x7 connect-four rules.
+func DefaultRules() Rules {
+	return Rules{Rows: Rows, Columns: Columns, Connect: ConnectLength}
+}
+
+// Validate reports whether the rules describe a playable board.
+func (r Rules) Validate() error {
+	if r.Rows < MinSize || r.Rows > MaxRows {
+		return fmt.Errorf("rows must be between %d and %d", MinSize, MaxRows)
+	}
+	if r.Columns < MinSize || r.Columns > MaxColumns {
+		return fmt.Errorf("columns must be between %d and %d", MinSize, MaxColumns)
+	}
+	if (r.Rows+1)*r.Columns > maxCells {
+		return errors.New("board is too large")
+	}
+	if r.Connect < 3 || (r.Connect > r.Rows && r.Connect > r.Columns) {
+		return errors.New("connect length must be at least 3 and fit on the board")
+	}
+	return nil
+}
+
+// Cells returns the number of slots on the board.
+func (r Rules) Cells() int {
+	return r.Rows * r.Columns
+}
+
+// NewBoard returns an empty row-major board with the rules' dimensions.
+func (r Rules) NewBoard() [][]int {
+	board := make([][]int, r.Rows)
+	for row := range board {
+		board[row] = make([]int, r.Columns)
+	}
+	return board
+}
+
+// String renders the rules as e.g. "6x7 connect 4" or "6x7 connect 4 popout".
+func (r Rules) String() string {
+	s := fmt.Sprintf("%dx%d connect %d", r.Rows, r.Columns, r.Connect)
+	if r.PopOut {
+		s += " popout"
+	}
+	return s
+}
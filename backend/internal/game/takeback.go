@@ -0,0 +1,137 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TAKEBACK marks a move log entry that took back the last Undone plies.
+const TAKEBACK MoveKind = "TAKEBACK"
+
+// Undo takes back the requesting player's most recent move, together with any
+// opponent reply made since, so that it is the requester's turn again. The
+// takeback is appended to the move history. It returns the number of plies removed.
+func (m *GameManager) Undo(gameID, player string) (*Game, int, error) {
+	m.mu.Lock()
+	game, ok := m.games[gameID]
+	if !ok {
+		m.mu.Unlock()
+		return nil, 0, fmt.Errorf("game %s not found", gameID)
+	}
+
+	now := time.Now().UTC()
+	if game.checkClock(now) != CONTINUE {
+		m.armClock(game)
+		notify := m.onTimeout
+		m.mu.Unlock()
+		if notify != nil {
+			notify(game)
+		}
+		return game, 0, errors.New("game already finished")
+	}
+	defer m.mu.Unlock()
+
+	count, err := game.undoCount(player)
+	if err != nil {
+		return game, 0, err
+	}
+
+	if err := game.takeback(player, count, now); err != nil {
+		return game, 0, err
+	}
+	m.armClock(game)
+	return game, count, nil
+}
+
+// CanUndo reports why player could not take back a move right now, or nil if they can.
+func (m *GameManager) CanUndo(gameID, player string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return fmt.Errorf("game %s not found", gameID)
+	}
+	_, err := game.undoCount(player)
+	return err
+}
+
+// undoCount returns how many plies must come off for player to be back on
+// move with their last move removed.
+func (g *Game) undoCount(player string) (int, error) {
+	if g.Winner != nil || g.EndedAt != nil {
+		return 0, errors.New("game already finished")
+	}
+	if player != g.Player1 && player != g.Player2 {
+		return 0, errors.New("player not part of this game")
+	}
+
+	active := ActiveMoves(g.Moves)
+	for i := len(active) - 1; i >= 0; i-- {
+		if active[i].Player == player {
+			return len(active) - i, nil
+		}
+	}
+	return 0, errors.New("no move to take back")
+}
+
+// TakebacksBy returns how many takebacks player has made in this game.
+func (g *Game) TakebacksBy(player string) int {
+	count := 0
+	for _, mv := range g.Moves {
+		if mv.Kind == TAKEBACK && mv.Player == player {
+			count++
+		}
+	}
+	return count
+}
+
+// ActiveMoves returns the drops and pops still on the board once takebacks are applied.
+func ActiveMoves(moves []Move) []Move {
+	active := make([]Move, 0, len(moves))
+	for _, mv := range moves {
+		if mv.Kind.IsPly() {
+			active = append(active, mv)
+			continue
+		}
+		if mv.Kind == TAKEBACK && mv.Undone > 0 && mv.Undone <= len(active) {
+			active = active[:len(active)-mv.Undone]
+		}
+	}
+	return active
+}
+
+// takeback removes the last count plies by rebuilding the game from the
+// remaining moves, then records the takeback in the move log. Clocks carry on
+// from where they were, with the requester's clock now running.
+func (g *Game) takeback(player string, count int, at time.Time) error {
+	if g.Winner != nil || g.EndedAt != nil {
+		return errors.New("game already finished")
+	}
+	if player != g.Player1 && player != g.Player2 {
+		return errors.New("player not part of this game")
+	}
+
+	active := ActiveMoves(g.Moves)
+	if count < 1 || count > len(active) {
+		return fmt.Errorf("cannot take back %d moves", count)
+	}
+
+	rebuilt := newGame(g.ID, g.Player1, g.Player2, g.Rules, g.CreatedAt)
+	for _, mv := range active[:len(active)-count] {
+		if _, err := rebuilt.apply(mv.Player, mv.Kind, mv.Column, mv.PlayedAt); err != nil {
+			return err
+		}
+	}
+
+	g.spend(at, false)
+	g.position = rebuilt.position
+	g.seen = rebuilt.seen
+	g.Board = rebuilt.Board
+	g.CurrentTurn = rebuilt.CurrentTurn
+	g.Hash = rebuilt.Hash
+	g.WinningCells = nil
+	g.Moves = append(g.Moves, Move{Player: player, Kind: TAKEBACK, Undone: count, MoveNumber: len(g.Moves) + 1, PlayedAt: at})
+	return nil
+}
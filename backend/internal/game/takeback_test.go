@@ -0,0 +1,89 @@
+package game
+
+import "testing"
+
+func TestUndoRestoresRequestersTurn(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 3, 3, 4)
+
+	// alice takes back their own last move: only one ply comes off.
+	updated, undone, err := gm.Undo(g.ID, "alice")
+	if err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if undone != 1 {
+		t.Fatalf("expected alice's last move to be taken back, got %d plies", undone)
+	}
+	if updated.CurrentTurn != 1 || updated.Board[Rows-1][4] != 0 {
+		t.Fatalf("expected alice to move again on the restored board")
+	}
+
+	// With alice to move, bob's takeback removes alice's reply too.
+	playMoves(t, gm, g, 2)
+	if _, undone, err = gm.Undo(g.ID, "bob"); err != nil || undone != 2 {
+		t.Fatalf("expected two plies undone for bob, got %d err %v", undone, err)
+	}
+	if g.CurrentTurn != 2 || g.Board[Rows-2][3] != 0 || g.Board[Rows-1][2] != 0 {
+		t.Fatalf("expected bob to move again with their disc removed")
+	}
+
+	pos, err := PositionFromMoves(classic, "4")
+	if err != nil {
+		t.Fatalf("position from moves: %v", err)
+	}
+	if g.Hash != pos.Hash() {
+		t.Fatalf("expected hash to match the position after takebacks")
+	}
+
+	last := g.Moves[len(g.Moves)-1]
+	if last.Kind != TAKEBACK || last.Player != "bob" || last.Undone != 2 {
+		t.Fatalf("expected takeback to be recorded, got %+v", last)
+	}
+	if g.TakebacksBy("alice") != 1 || g.TakebacksBy("bob") != 1 {
+		t.Fatalf("unexpected takeback counts")
+	}
+	if len(ActiveMoves(g.Moves)) != 1 {
+		t.Fatalf("expected one move left on the board, got %v", ActiveMoves(g.Moves))
+	}
+
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected takebacks to replay: %v", err)
+	}
+}
+
+func TestUndoRejections(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+
+	if _, _, err := gm.Undo(g.ID, "alice"); err == nil {
+		t.Fatalf("expected undo before any move to fail")
+	}
+	if _, _, err := gm.Undo(g.ID, "carol"); err == nil {
+		t.Fatalf("expected undo by an outsider to fail")
+	}
+
+	playMoves(t, gm, g, 3)
+	if _, _, err := gm.Undo(g.ID, "bob"); err == nil {
+		t.Fatalf("expected undo by a player who has not moved to fail")
+	}
+
+	finished := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, finished, 3, 0, 3, 0, 3, 0, 3)
+	if finished.Winner == nil {
+		t.Fatalf("expected alice to have won")
+	}
+	if _, _, err := gm.Undo(finished.ID, "bob"); err == nil {
+		t.Fatalf("expected undo after the game ended to fail")
+	}
+}
+
+func TestReplayRejectsOversizedTakeback(t *testing.T) {
+	moves := []Move{
+		{Player: "alice", Kind: DROP, Column: 3, MoveNumber: 1},
+		{Player: "alice", Kind: TAKEBACK, Undone: 2, MoveNumber: 2},
+	}
+	if _, err := Replay(classic, moves); err == nil {
+		t.Fatalf("expected takeback of more plies than were played to fail")
+	}
+}
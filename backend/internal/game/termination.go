@@ -0,0 +1,137 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Termination records why a game ended.
+type Termination string
+
+const (
+	TerminationConnect    Termination = "CONNECT"
+	TerminationBoardFull  Termination = "BOARD_FULL"
+	TerminationRepetition Termination = "REPETITION"
+	TerminationResign     Termination = "RESIGNATION"
+	TerminationAgreement  Termination = "AGREEMENT"
+	TerminationAbort      Termination = "ABORTED"
+	TerminationForfeit    Termination = "FORFEIT"
+)
+
+// Move log entries that end the game without a disc being played.
+const (
+	RESIGN     MoveKind = "RESIGN"
+	AGREE_DRAW MoveKind = "AGREE_DRAW"
+	ABORT      MoveKind = "ABORT"
+	FORFEIT    MoveKind = "FORFEIT"
+)
+
+// IsPly reports whether kind places or removes a disc. Moves recorded before
+// kinds were stored have an empty kind and are drops.
+func (k MoveKind) IsPly() bool {
+	return k == "" || k == DROP || k == POP
+}
+
+// Resign ends the game with player's opponent as the winner.
+func (m *GameManager) Resign(gameID, player string) (*Game, MoveResult, error) {
+	return m.end(gameID, player, RESIGN)
+}
+
+// AgreeDraw ends the game as a draw. player is the one accepting the offer;
+// tracking the offer itself is up to the caller.
+func (m *GameManager) AgreeDraw(gameID, player string) (*Game, MoveResult, error) {
+	return m.end(gameID, player, AGREE_DRAW)
+}
+
+// Forfeit ends the game with player's opponent as the winner because player
+// could not move, such as a bot whose strategy failed.
+func (m *GameManager) Forfeit(gameID, player string) (*Game, MoveResult, error) {
+	return m.end(gameID, player, FORFEIT)
+}
+
+// Abort cancels a game that has not got going: it is only allowed until both
+// players have made a move. Aborted games have no winner and return ABORTED.
+//
+// If the player to move is already out of time, each of these ends the game
+// on time instead and returns its result.
+func (m *GameManager) Abort(gameID, player string) (*Game, MoveResult, error) {
+	return m.end(gameID, player, ABORT)
+}
+
+func (m *GameManager) end(gameID, player string, kind MoveKind) (*Game, MoveResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	game, ok := m.games[gameID]
+	if !ok {
+		return nil, INVALID, fmt.Errorf("game %s not found", gameID)
+	}
+
+	now := time.Now().UTC()
+	if result := game.checkClock(now); result != CONTINUE {
+		m.armClock(game)
+		return game, result, nil
+	}
+
+	result, err := game.end(player, kind, now)
+	m.armClock(game)
+	return game, result, err
+}
+
+// end finishes the game through a resignation, forfeit, timeout, agreed draw or abort
+// and appends the entry to the move log. It is shared by live play and Replay.
+func (g *Game) end(player string, kind MoveKind, at time.Time) (MoveResult, error) {
+	if g.Winner != nil || g.EndedAt != nil {
+		return INVALID, errors.New("game already finished")
+	}
+
+	var opponent string
+	switch player {
+	case g.Player1:
+		opponent = g.Player2
+	case g.Player2:
+		opponent = g.Player1
+	default:
+		return INVALID, errors.New("player not part of this game")
+	}
+
+	switch kind {
+	case RESIGN, FORFEIT, AGREE_DRAW, ABORT, TIMEOUT:
+	default:
+		return INVALID, fmt.Errorf("unknown move kind %q", kind)
+	}
+	if kind == ABORT && g.bothMoved() {
+		return INVALID, errors.New("game can no longer be aborted")
+	}
+
+	g.Moves = append(g.Moves, Move{Player: player, Kind: kind, MoveNumber: len(g.Moves) + 1, PlayedAt: at})
+
+	switch kind {
+	case RESIGN:
+		return finishGame(g, &opponent, TerminationResign, at), nil
+	case FORFEIT:
+		return finishGame(g, &opponent, TerminationForfeit, at), nil
+	case TIMEOUT:
+		return finishGame(g, &opponent, TerminationTimeout, at), nil
+	case AGREE_DRAW:
+		return finishGame(g, nil, TerminationAgreement, at), nil
+	default:
+		finishGame(g, nil, TerminationAbort, at)
+		return ABORTED, nil
+	}
+}
+
+// bothMoved reports whether each player has played at least one disc,
+// counting moves that were later taken back.
+func (g *Game) bothMoved() bool {
+	var first, second bool
+	for _, mv := range g.Moves {
+		if !mv.Kind.IsPly() {
+			continue
+		}
+		first = first || mv.Player == g.Player1
+		second = second || mv.Player == g.Player2
+	}
+	return first && second
+}
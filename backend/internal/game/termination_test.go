@@ -0,0 +1,93 @@
+package game
+
+import "testing"
+
+func TestResignAwardsOpponent(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 3, 3, 4)
+
+	updated, result, err := gm.Resign(g.ID, "alice")
+	if err != nil {
+		t.Fatalf("resign failed: %v", err)
+	}
+	if result != WIN || updated.Winner == nil || *updated.Winner != "bob" {
+		t.Fatalf("expected bob to win by resignation, got %v", result)
+	}
+	if updated.Termination != TerminationResign || updated.EndedAt == nil {
+		t.Fatalf("expected resignation to end the game, got %q", updated.Termination)
+	}
+	if _, _, err := gm.ApplyMove(g.ID, "bob", DROP, 0); err == nil {
+		t.Fatalf("expected moves after resignation to be rejected")
+	}
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected resignation to replay: %v", err)
+	}
+}
+
+func TestForfeitAwardsOpponent(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 3, 3)
+
+	updated, result, err := gm.Forfeit(g.ID, "alice")
+	if err != nil {
+		t.Fatalf("forfeit failed: %v", err)
+	}
+	if result != WIN || updated.Winner == nil || *updated.Winner != "bob" || updated.Termination != TerminationForfeit {
+		t.Fatalf("expected bob to win by forfeit, got %v %q", result, updated.Termination)
+	}
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected the forfeit to replay: %v", err)
+	}
+}
+
+func TestAgreeDrawAndNaturalTerminations(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 3)
+
+	if _, result, err := gm.AgreeDraw(g.ID, "bob"); err != nil || result != DRAW {
+		t.Fatalf("expected agreed draw, got %v err %v", result, err)
+	}
+	if g.Winner != nil || g.Termination != TerminationAgreement {
+		t.Fatalf("expected draw by agreement, got %q", g.Termination)
+	}
+	if err := gm.Verify(g.ID); err != nil {
+		t.Fatalf("expected agreed draw to replay: %v", err)
+	}
+
+	won := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, won, 0, 1, 0, 1, 0, 1, 0)
+	if won.Termination != TerminationConnect {
+		t.Fatalf("expected CONNECT termination, got %q", won.Termination)
+	}
+}
+
+func TestAbortOnlyBeforeBothSidesMoved(t *testing.T) {
+	gm := NewManager()
+	g := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, g, 3)
+
+	updated, result, err := gm.Abort(g.ID, "bob")
+	if err != nil || result != ABORTED {
+		t.Fatalf("expected abort after one move, got %v err %v", result, err)
+	}
+	if updated.Winner != nil || updated.Termination != TerminationAbort {
+		t.Fatalf("expected aborted game without a winner")
+	}
+
+	started := createGame(t, gm, "alice", "bob")
+	playMoves(t, gm, started, 3, 3)
+	if _, _, err := gm.Abort(started.ID, "alice"); err == nil {
+		t.Fatalf("expected abort after both players moved to fail")
+	}
+
+	// A takeback does not reopen the abort window.
+	if _, _, err := gm.Undo(started.ID, "bob"); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if _, _, err := gm.Abort(started.ID, "bob"); err == nil {
+		t.Fatalf("expected abort after a takeback to fail")
+	}
+}
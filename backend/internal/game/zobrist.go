@@ -0,0 +1,68 @@
+package game
+
+// Zobrist keys are derived from a fixed seed so that hashes are stable across
+// processes and can be stored, e.g. in an opening book.
+var (
+	zobristCells [2][maxCells]uint64
+	zobristTurn  uint64
+)
+
+func init() {
+	state := uint64(0x9E3779B97F4A7C15)
+	for player := range zobristCells {
+		for i := range zobristCells[player] {
+			zobristCells[player][i] = splitMix64(&state)
+		}
+	}
+	zobristTurn = splitMix64(&state)
+}
+
+// splitMix64 advances state and returns the next pseudo-random value.
+func splitMix64(state *uint64) uint64 {
+	*state += 0x9E3779B97F4A7C15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return z ^ (z >> 31)
+}
+
+// rulesKey separates hashes of positions played under different rules.
+func rulesKey(r Rules) uint64 {
+	state := uint64(r.Rows) | uint64(r.Columns)<<8 | uint64(r.Connect)<<16
+	if r.PopOut {
+		state |= 1 << 24
+	}
+	return splitMix64(&state)
+}
+
+// toggleCell flips player's disc at (h, col) in both the plain and mirrored hash.
+func (p *Position) toggleCell(player, h, col int) {
+	p.hash ^= zobristCells[player-1][cellIndex(p.rules, h, col)]
+	p.mirror ^= zobristCells[player-1][cellIndex(p.rules, h, p.rules.Columns-1-col)]
+}
+
+// toggleColumn flips every disc currently in col.
+func (p *Position) toggleColumn(col int) {
+	for h := 0; h < p.height[col]; h++ {
+		p.toggleCell(p.cellOwner(h, col), h, col)
+	}
+}
+
+// Hash returns the Zobrist hash of the position, including the player to move
+// and the rules. It is maintained incrementally as moves are played.
+func (p *Position) Hash() uint64 {
+	return p.hash
+}
+
+// MirrorHash returns the hash of the position reflected left to right.
+func (p *Position) MirrorHash() uint64 {
+	return p.mirror
+}
+
+// CanonicalHash returns a hash shared by the position and its left-right mirror.
+func (p *Position) CanonicalHash() uint64 {
+	if p.mirror < p.hash {
+		return p.mirror
+	}
+	return p.hash
+}
@@ -0,0 +1,95 @@
+package game
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func mustPosition(t *testing.T, rules Rules, notation string) Position {
+	t.Helper()
+
+	pos, err := PositionFromMoves(rules, notation)
+	if err != nil {
+		t.Fatalf("position from %q: %v", notation, err)
+	}
+	return pos
+}
+
+func TestHashTranspositionsAndUndo(t *testing.T) {
+	a := mustPosition(t, classic, "1234")
+	b := mustPosition(t, classic, "3214")
+	if a.Hash() != b.Hash() {
+		t.Fatalf("transposed move orders should hash the same")
+	}
+
+	empty := NewPosition(classic)
+	pos := empty
+	pos.Play(3)
+	if pos.Hash() == empty.Hash() {
+		t.Fatalf("hash should change after a move")
+	}
+	pos.Undo(3)
+	if pos != empty {
+		t.Fatalf("undo should restore the position and its hash")
+	}
+
+	large := NewPosition(Rules{Rows: 8, Columns: 9, Connect: 5})
+	if empty.Hash() == large.Hash() {
+		t.Fatalf("different rules should hash differently")
+	}
+
+	sameDiscs, err := PositionFromBoard(classic, a.Board(), 2)
+	if err != nil {
+		t.Fatalf("load position: %v", err)
+	}
+	if sameDiscs.Hash() == a.Hash() {
+		t.Fatalf("side to move should be part of the hash")
+	}
+}
+
+func TestHashMatchesRebuiltPosition(t *testing.T) {
+	rules := DefaultRules()
+	rules.PopOut = true
+	rng := rand.New(rand.NewSource(3))
+
+	pos := NewPosition(rules)
+	for i := 0; i < 300 && pos.HasLegalMove(); i++ {
+		col := rng.Intn(rules.Columns)
+		switch {
+		case pos.CanPop(col) && rng.Intn(3) == 0:
+			pos.Pop(col)
+		case pos.CanPlay(col):
+			pos.Play(col)
+		default:
+			continue
+		}
+
+		rebuilt, err := PositionFromBoard(rules, pos.Board(), pos.Turn())
+		if err != nil {
+			t.Fatalf("rebuild position: %v", err)
+		}
+		if rebuilt.Hash() != pos.Hash() || rebuilt.MirrorHash() != pos.MirrorHash() {
+			t.Fatalf("incremental hash diverged from rebuilt hash after %d plies", i+1)
+		}
+	}
+}
+
+func TestCanonicalHashIgnoresMirroring(t *testing.T) {
+	left := mustPosition(t, classic, "1223")
+	right := mustPosition(t, classic, "7665")
+
+	if left.Hash() == right.Hash() {
+		t.Fatalf("mirrored positions should have distinct plain hashes")
+	}
+	if left.CanonicalHash() != right.CanonicalHash() {
+		t.Fatalf("mirrored positions should share a canonical hash")
+	}
+	if left.MirrorHash() != right.Hash() {
+		t.Fatalf("mirror hash should equal the hash of the reflected position")
+	}
+
+	centre := mustPosition(t, classic, "44")
+	if centre.Hash() != centre.MirrorHash() {
+		t.Fatalf("a symmetric position should equal its mirror")
+	}
+}
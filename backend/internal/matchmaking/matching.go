@@ -0,0 +1,648 @@
+package matchmaking
+
+import "slices"
+
+// maxBitmaskPlayers is the largest group bestMatching pairs by trying every
+// subset of players; larger groups go to the blossom algorithm.
+const maxBitmaskPlayers = 16
+
+// edge joins two players of a group, numbered from zero, who accept each
+// other as opponents, and records the rating gap between them.
+type edge struct {
+	a, b, gap int
+}
+
+// bestMatching pairs n players along edges, choosing the pairing with the most
+// pairs and then the smallest total rating gap. Among equally good pairings,
+// small groups favour pairing lower-numbered players.
+func bestMatching(n int, edges []edge) [][2]int {
+	if len(edges) == 0 {
+		return nil
+	}
+	if n <= maxBitmaskPlayers {
+		return bitmaskMatching(n, edges)
+	}
+	return blossomMatching(n, edges)
+}
+
+// bitmaskMatching is bestMatching by dynamic programming over the subsets of
+// players still unpaired. The lowest of them plays one of its neighbours in
+// the subset, or waits if that leaves a better pairing of the rest.
+func bitmaskMatching(n int, edges []edge) [][2]int {
+	type plan struct {
+		pairs, gap int32
+		partner    int8
+	}
+	better := func(a, b plan) bool {
+		return a.pairs > b.pairs || (a.pairs == b.pairs && a.gap < b.gap)
+	}
+
+	neighbours := make([][]edge, n)
+	for _, e := range edges {
+		neighbours[e.a] = append(neighbours[e.a], edge{e.a, e.b, e.gap})
+		neighbours[e.b] = append(neighbours[e.b], edge{e.b, e.a, e.gap})
+	}
+
+	best := make([]plan, 1<<n)
+	for mask := 1; mask < len(best); mask++ {
+		low := lowestBit(mask)
+		rest := mask &^ (1 << low)
+		best[mask] = plan{pairs: -1}
+		for _, e := range neighbours[low] {
+			if mask&(1<<e.b) == 0 {
+				continue
+			}
+			left := best[rest&^(1<<e.b)]
+			candidate := plan{pairs: left.pairs + 1, gap: left.gap + int32(e.gap), partner: int8(e.b)}
+			if better(candidate, best[mask]) {
+				best[mask] = candidate
+			}
+		}
+		if wait := best[rest]; better(wait, best[mask]) {
+			best[mask] = plan{pairs: wait.pairs, gap: wait.gap, partner: -1}
+		}
+	}
+
+	var pairs [][2]int
+	for mask := len(best) - 1; mask != 0; {
+		low, partner := lowestBit(mask), int(best[mask].partner)
+		mask &^= 1 << low
+		if partner >= 0 {
+			pairs = append(pairs, [2]int{low, partner})
+			mask &^= 1 << partner
+		}
+	}
+	return pairs
+}
+
+func lowestBit(mask int) int {
+	bit := 0
+	for mask&(1<<bit) == 0 {
+		bit++
+	}
+	return bit
+}
+
+// blossomMatching is bestMatching by Edmonds' blossom algorithm for a
+// maximum-weight matching among the matchings with the most pairs, in Van
+// Rantwijk's O(n³) formulation. Each edge weighs one more than the largest
+// gap less its own gap, so the heaviest of those matchings has the smallest
+// total gap.
+func blossomMatching(n int, edges []edge) [][2]int {
+	largest := 0
+	for _, e := range edges {
+		largest = max(largest, e.gap)
+	}
+	weights := make([]int, len(edges))
+	for k, e := range edges {
+		weights[k] = largest + 1 - e.gap
+	}
+
+	b := newBlossom(n, edges, weights)
+	b.solve()
+
+	var pairs [][2]int
+	for v, p := range b.mate {
+		if p >= 0 && v < b.endpoint[p] {
+			pairs = append(pairs, [2]int{v, b.endpoint[p]})
+		}
+	}
+	return pairs
+}
+
+// blossom is the state of blossomMatching. Vertices are numbered below n and
+// non-trivial blossoms from n up to 2n. Edge k has endpoints 2k and 2k+1;
+// endpoint[p] is the vertex at endpoint p, and p^1 is the other end.
+type blossom struct {
+	n       int
+	edges   []edge
+	weights []int
+
+	endpoint  []int
+	neighbend [][]int
+	// mate[v] is the remote endpoint of v's matched edge, or -1.
+	mate []int
+	// label is 0 for free, 1 for S, 2 for T; labelend is the endpoint
+	// through which the label was reached, or -1.
+	label            []int
+	labelend         []int
+	inblossom        []int
+	blossomparent    []int
+	blossomchilds    [][]int
+	blossombase      []int
+	blossomendps     [][]int
+	bestedge         []int
+	blossombestedges [][]int
+	unusedblossoms   []int
+	dualvar          []int
+	allowedge        []bool
+	queue            []int
+}
+
+func newBlossom(n int, edges []edge, weights []int) *blossom {
+	maxWeight := 0
+	for _, w := range weights {
+		maxWeight = max(maxWeight, w)
+	}
+
+	b := &blossom{
+		n:                n,
+		edges:            edges,
+		weights:          weights,
+		endpoint:         make([]int, 2*len(edges)),
+		neighbend:        make([][]int, n),
+		mate:             make([]int, n),
+		label:            make([]int, 2*n),
+		labelend:         make([]int, 2*n),
+		inblossom:        make([]int, n),
+		blossomparent:    make([]int, 2*n),
+		blossomchilds:    make([][]int, 2*n),
+		blossombase:      make([]int, 2*n),
+		blossomendps:     make([][]int, 2*n),
+		bestedge:         make([]int, 2*n),
+		blossombestedges: make([][]int, 2*n),
+		dualvar:          make([]int, 2*n),
+		allowedge:        make([]bool, len(edges)),
+	}
+	for k, e := range edges {
+		b.endpoint[2*k], b.endpoint[2*k+1] = e.a, e.b
+		b.neighbend[e.a] = append(b.neighbend[e.a], 2*k+1)
+		b.neighbend[e.b] = append(b.neighbend[e.b], 2*k)
+	}
+	for v := 0; v < 2*n; v++ {
+		b.labelend[v] = -1
+		b.blossomparent[v] = -1
+		b.bestedge[v] = -1
+		b.blossombase[v] = -1
+		if v < n {
+			b.mate[v] = -1
+			b.inblossom[v] = v
+			b.blossombase[v] = v
+			b.dualvar[v] = maxWeight
+		} else {
+			b.unusedblossoms = append(b.unusedblossoms, v)
+		}
+	}
+	return b
+}
+
+// slack is the reduced cost of edge k, twice over.
+func (b *blossom) slack(k int) int {
+	e := b.edges[k]
+	return b.dualvar[e.a] + b.dualvar[e.b] - 2*b.weights[k]
+}
+
+// leaves lists the vertices inside blossom t.
+func (b *blossom) leaves(t int) []int {
+	if t < b.n {
+		return []int{t}
+	}
+	var vertices []int
+	for _, child := range b.blossomchilds[t] {
+		vertices = append(vertices, b.leaves(child)...)
+	}
+	return vertices
+}
+
+// assignLabel labels vertex w and its top-level blossom t (1 for S, 2 for T),
+// reached through endpoint p, and labels the mate of a T blossom's base S.
+func (b *blossom) assignLabel(w, t, p int) {
+	top := b.inblossom[w]
+	b.label[w], b.label[top] = t, t
+	b.labelend[w], b.labelend[top] = p, p
+	b.bestedge[w], b.bestedge[top] = -1, -1
+	switch t {
+	case 1:
+		b.queue = append(b.queue, b.leaves(top)...)
+	case 2:
+		base := b.blossombase[top]
+		b.assignLabel(b.endpoint[b.mate[base]], 1, b.mate[base]^1)
+	}
+}
+
+// scanBlossom traces back from S vertices v and w to find either the base of
+// a new blossom, which it returns, or -1 when the paths reach two different
+// roots and the edge between them augments the matching.
+func (b *blossom) scanBlossom(v, w int) int {
+	var path []int
+	base := -1
+	for v != -1 || w != -1 {
+		top := b.inblossom[v]
+		if b.label[top]&4 != 0 {
+			base = b.blossombase[top]
+			break
+		}
+		path = append(path, top)
+		b.label[top] = 5
+		if b.labelend[top] == -1 {
+			v = -1
+		} else {
+			v = b.endpoint[b.labelend[top]]
+			top = b.inblossom[v]
+			v = b.endpoint[b.labelend[top]]
+		}
+		if w != -1 {
+			v, w = w, v
+		}
+	}
+	for _, top := range path {
+		b.label[top] = 1
+	}
+	return base
+}
+
+// addBlossom makes a new blossom from base and the odd cycle closed by edge
+// k, and works out its best edges to other S blossoms.
+func (b *blossom) addBlossom(base, k int) {
+	v, w := b.edges[k].a, b.edges[k].b
+	bb, bv, bw := b.inblossom[base], b.inblossom[v], b.inblossom[w]
+
+	nb := b.unusedblossoms[len(b.unusedblossoms)-1]
+	b.unusedblossoms = b.unusedblossoms[:len(b.unusedblossoms)-1]
+	b.blossombase[nb] = base
+	b.blossomparent[nb] = -1
+	b.blossomparent[bb] = nb
+
+	var path, endps []int
+	for bv != bb {
+		b.blossomparent[bv] = nb
+		path = append(path, bv)
+		endps = append(endps, b.labelend[bv])
+		v = b.endpoint[b.labelend[bv]]
+		bv = b.inblossom[v]
+	}
+	path = append(path, bb)
+	slices.Reverse(path)
+	slices.Reverse(endps)
+	endps = append(endps, 2*k)
+	for bw != bb {
+		b.blossomparent[bw] = nb
+		path = append(path, bw)
+		endps = append(endps, b.labelend[bw]^1)
+		w = b.endpoint[b.labelend[bw]]
+		bw = b.inblossom[w]
+	}
+	b.blossomchilds[nb] = path
+	b.blossomendps[nb] = endps
+
+	b.label[nb] = 1
+	b.labelend[nb] = b.labelend[bb]
+	b.dualvar[nb] = 0
+	for _, leaf := range b.leaves(nb) {
+		if b.label[b.inblossom[leaf]] == 2 {
+			b.queue = append(b.queue, leaf)
+		}
+		b.inblossom[leaf] = nb
+	}
+
+	bestedgeto := make([]int, 2*b.n)
+	for i := range bestedgeto {
+		bestedgeto[i] = -1
+	}
+	for _, child := range path {
+		var lists [][]int
+		if b.blossombestedges[child] == nil {
+			for _, leaf := range b.leaves(child) {
+				list := make([]int, len(b.neighbend[leaf]))
+				for i, p := range b.neighbend[leaf] {
+					list[i] = p / 2
+				}
+				lists = append(lists, list)
+			}
+		} else {
+			lists = [][]int{b.blossombestedges[child]}
+		}
+		for _, list := range lists {
+			for _, ek := range list {
+				j := b.edges[ek].b
+				if b.inblossom[j] == nb {
+					j = b.edges[ek].a
+				}
+				bj := b.inblossom[j]
+				if bj != nb && b.label[bj] == 1 && (bestedgeto[bj] == -1 || b.slack(ek) < b.slack(bestedgeto[bj])) {
+					bestedgeto[bj] = ek
+				}
+			}
+		}
+		b.blossombestedges[child] = nil
+		b.bestedge[child] = -1
+	}
+
+	b.blossombestedges[nb] = []int{}
+	for _, ek := range bestedgeto {
+		if ek != -1 {
+			b.blossombestedges[nb] = append(b.blossombestedges[nb], ek)
+		}
+	}
+	b.bestedge[nb] = -1
+	for _, ek := range b.blossombestedges[nb] {
+		if b.bestedge[nb] == -1 || b.slack(ek) < b.slack(b.bestedge[nb]) {
+			b.bestedge[nb] = ek
+		}
+	}
+}
+
+// at indexes list from the end for negative j.
+func at(list []int, j int) int {
+	if j < 0 {
+		j += len(list)
+	}
+	return list[j]
+}
+
+// expandBlossom turns blossom t back into its children, relabelling them if
+// t was a T blossom in the middle of a stage. At the end of a stage it also
+// expands children whose dual variable has dropped to zero.
+func (b *blossom) expandBlossom(t int, endstage bool) {
+	for _, s := range b.blossomchilds[t] {
+		b.blossomparent[s] = -1
+		switch {
+		case s < b.n:
+			b.inblossom[s] = s
+		case endstage && b.dualvar[s] == 0:
+			b.expandBlossom(s, endstage)
+		default:
+			for _, leaf := range b.leaves(s) {
+				b.inblossom[leaf] = s
+			}
+		}
+	}
+
+	if !endstage && b.label[t] == 2 {
+		childs, endps := b.blossomchilds[t], b.blossomendps[t]
+		entrychild := b.inblossom[b.endpoint[b.labelend[t]^1]]
+		j := slices.Index(childs, entrychild)
+		jstep, endptrick := -1, 1
+		if j&1 != 0 {
+			j -= len(childs)
+			jstep, endptrick = 1, 0
+		}
+
+		p := b.labelend[t]
+		for j != 0 {
+			b.label[b.endpoint[p^1]] = 0
+			b.label[b.endpoint[at(endps, j-endptrick)^endptrick^1]] = 0
+			b.assignLabel(b.endpoint[p^1], 2, p)
+			b.allowedge[at(endps, j-endptrick)/2] = true
+			j += jstep
+			p = at(endps, j-endptrick) ^ endptrick
+			b.allowedge[p/2] = true
+			j += jstep
+		}
+
+		bv := at(childs, j)
+		b.label[b.endpoint[p^1]], b.label[bv] = 2, 2
+		b.labelend[b.endpoint[p^1]], b.labelend[bv] = p, p
+		b.bestedge[bv] = -1
+
+		j += jstep
+		for at(childs, j) != entrychild {
+			bv = at(childs, j)
+			if b.label[bv] == 1 {
+				j += jstep
+				continue
+			}
+			leaf := -1
+			for _, leaf = range b.leaves(bv) {
+				if b.label[leaf] != 0 {
+					break
+				}
+			}
+			if b.label[leaf] != 0 {
+				b.label[leaf] = 0
+				b.label[b.endpoint[b.mate[b.blossombase[bv]]]] = 0
+				b.assignLabel(leaf, 2, b.labelend[leaf])
+			}
+			j += jstep
+		}
+	}
+
+	b.label[t], b.labelend[t] = -1, -1
+	b.blossomchilds[t], b.blossomendps[t] = nil, nil
+	b.blossombase[t] = -1
+	b.blossombestedges[t] = nil
+	b.bestedge[t] = -1
+	b.unusedblossoms = append(b.unusedblossoms, t)
+}
+
+// augmentBlossom swaps matched and unmatched edges inside blossom t along the
+// path from vertex v to its base, making v the new base.
+func (b *blossom) augmentBlossom(t, v int) {
+	child := v
+	for b.blossomparent[child] != t {
+		child = b.blossomparent[child]
+	}
+	if child >= b.n {
+		b.augmentBlossom(child, v)
+	}
+
+	childs, endps := b.blossomchilds[t], b.blossomendps[t]
+	i := slices.Index(childs, child)
+	j := i
+	jstep, endptrick := -1, 1
+	if i&1 != 0 {
+		j -= len(childs)
+		jstep, endptrick = 1, 0
+	}
+	for j != 0 {
+		j += jstep
+		child = at(childs, j)
+		p := at(endps, j-endptrick) ^ endptrick
+		if child >= b.n {
+			b.augmentBlossom(child, b.endpoint[p])
+		}
+		j += jstep
+		child = at(childs, j)
+		if child >= b.n {
+			b.augmentBlossom(child, b.endpoint[p^1])
+		}
+		b.mate[b.endpoint[p]] = p ^ 1
+		b.mate[b.endpoint[p^1]] = p
+	}
+
+	b.blossomchilds[t] = append(append([]int{}, childs[i:]...), childs[:i]...)
+	b.blossomendps[t] = append(append([]int{}, endps[i:]...), endps[:i]...)
+	b.blossombase[t] = b.blossombase[b.blossomchilds[t][0]]
+}
+
+// augmentMatching flips the augmenting path through edge k, between two S
+// vertices in different trees.
+func (b *blossom) augmentMatching(k int) {
+	ends := [2][2]int{{b.edges[k].a, 2*k + 1}, {b.edges[k].b, 2 * k}}
+	for _, end := range ends {
+		s, p := end[0], end[1]
+		for {
+			bs := b.inblossom[s]
+			if bs >= b.n {
+				b.augmentBlossom(bs, s)
+			}
+			b.mate[s] = p
+			if b.labelend[bs] == -1 {
+				break
+			}
+			t := b.endpoint[b.labelend[bs]]
+			bt := b.inblossom[t]
+			s = b.endpoint[b.labelend[bt]]
+			j := b.endpoint[b.labelend[bt]^1]
+			if bt >= b.n {
+				b.augmentBlossom(bt, j)
+			}
+			b.mate[j] = b.labelend[bt]
+			p = b.labelend[bt] ^ 1
+		}
+	}
+}
+
+// solve runs stages until no augmenting path is left. Each stage grows
+// alternating trees from the free vertices, adjusting the dual variables
+// whenever no tight edge is left to follow.
+func (b *blossom) solve() {
+	n := b.n
+	for range n {
+		for i := range b.label {
+			b.label[i] = 0
+			b.bestedge[i] = -1
+		}
+		for i := n; i < 2*n; i++ {
+			b.blossombestedges[i] = nil
+		}
+		for i := range b.allowedge {
+			b.allowedge[i] = false
+		}
+		b.queue = b.queue[:0]
+
+		for v := 0; v < n; v++ {
+			if b.mate[v] == -1 && b.label[b.inblossom[v]] == 0 {
+				b.assignLabel(v, 1, -1)
+			}
+		}
+
+		augmented := false
+		for {
+			for len(b.queue) > 0 && !augmented {
+				v := b.queue[len(b.queue)-1]
+				b.queue = b.queue[:len(b.queue)-1]
+
+				for _, p := range b.neighbend[v] {
+					k := p / 2
+					w := b.endpoint[p]
+					if b.inblossom[v] == b.inblossom[w] {
+						continue
+					}
+					kslack := 0
+					if !b.allowedge[k] {
+						if kslack = b.slack(k); kslack <= 0 {
+							b.allowedge[k] = true
+						}
+					}
+					switch {
+					case b.allowedge[k]:
+						switch {
+						case b.label[b.inblossom[w]] == 0:
+							b.assignLabel(w, 2, p^1)
+						case b.label[b.inblossom[w]] == 1:
+							if base := b.scanBlossom(v, w); base >= 0 {
+								b.addBlossom(base, k)
+							} else {
+								b.augmentMatching(k)
+								augmented = true
+							}
+						case b.label[w] == 0:
+							b.label[w] = 2
+							b.labelend[w] = p ^ 1
+						}
+					case b.label[b.inblossom[w]] == 1:
+						top := b.inblossom[v]
+						if b.bestedge[top] == -1 || kslack < b.slack(b.bestedge[top]) {
+							b.bestedge[top] = k
+						}
+					case b.label[w] == 0:
+						if b.bestedge[w] == -1 || kslack < b.slack(b.bestedge[w]) {
+							b.bestedge[w] = k
+						}
+					}
+					if augmented {
+						break
+					}
+				}
+			}
+			if augmented {
+				break
+			}
+
+			deltatype, delta, deltaedge, deltablossom := -1, 0, -1, -1
+			for v := 0; v < n; v++ {
+				if b.label[b.inblossom[v]] == 0 && b.bestedge[v] != -1 {
+					if d := b.slack(b.bestedge[v]); deltatype == -1 || d < delta {
+						deltatype, delta, deltaedge = 2, d, b.bestedge[v]
+					}
+				}
+			}
+			for t := 0; t < 2*n; t++ {
+				if b.blossomparent[t] == -1 && b.label[t] == 1 && b.bestedge[t] != -1 {
+					if d := b.slack(b.bestedge[t]) / 2; deltatype == -1 || d < delta {
+						deltatype, delta, deltaedge = 3, d, b.bestedge[t]
+					}
+				}
+			}
+			for t := n; t < 2*n; t++ {
+				if b.blossombase[t] >= 0 && b.blossomparent[t] == -1 && b.label[t] == 2 && (deltatype == -1 || b.dualvar[t] < delta) {
+					deltatype, delta, deltablossom = 4, b.dualvar[t], t
+				}
+			}
+			if deltatype == -1 {
+				// No further improvement is possible with the most pairs
+				// kept; settle the duals and end the stage.
+				deltatype = 1
+				delta = max(0, slices.Min(b.dualvar[:n]))
+			}
+
+			for v := 0; v < n; v++ {
+				switch b.label[b.inblossom[v]] {
+				case 1:
+					b.dualvar[v] -= delta
+				case 2:
+					b.dualvar[v] += delta
+				}
+			}
+			for t := n; t < 2*n; t++ {
+				if b.blossombase[t] >= 0 && b.blossomparent[t] == -1 {
+					switch b.label[t] {
+					case 1:
+						b.dualvar[t] += delta
+					case 2:
+						b.dualvar[t] -= delta
+					}
+				}
+			}
+
+			switch deltatype {
+			case 1:
+			case 2:
+				b.allowedge[deltaedge] = true
+				i := b.edges[deltaedge].a
+				if b.label[b.inblossom[i]] == 0 {
+					i = b.edges[deltaedge].b
+				}
+				b.queue = append(b.queue, i)
+			case 3:
+				b.allowedge[deltaedge] = true
+				b.queue = append(b.queue, b.edges[deltaedge].a)
+			case 4:
+				b.expandBlossom(deltablossom, false)
+			}
+			if deltatype == 1 {
+				break
+			}
+		}
+		if !augmented {
+			return
+		}
+
+		for t := n; t < 2*n; t++ {
+			if b.blossomparent[t] == -1 && b.blossombase[t] >= 0 && b.label[t] == 1 && b.dualvar[t] == 0 {
+				b.expandBlossom(t, true)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package matchmaking
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkMatching fails unless pairs is a matching along edges, and returns how
+// many pairs it has and their total gap.
+func checkMatching(t *testing.T, n int, edges []edge, pairs [][2]int) (int, int) {
+	t.Helper()
+
+	gaps := make(map[[2]int]int)
+	for _, e := range edges {
+		gaps[[2]int{e.a, e.b}] = e.gap
+		gaps[[2]int{e.b, e.a}] = e.gap
+	}
+	used := make([]bool, n)
+	total := 0
+	for _, pair := range pairs {
+		gap, ok := gaps[pair]
+		if !ok || used[pair[0]] || used[pair[1]] {
+			t.Fatalf("invalid pairing %v of edges %v", pairs, edges)
+		}
+		used[pair[0]], used[pair[1]] = true, true
+		total += gap
+	}
+	return len(pairs), total
+}
+
+func TestBlossomMatchingAgreesWithBitmask(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 500; round++ {
+		n := 2 + rng.Intn(maxBitmaskPlayers-1)
+		density := rng.Float64()
+		var edges []edge
+		for a := 0; a < n; a++ {
+			for b := a + 1; b < n; b++ {
+				if rng.Float64() < density {
+					edges = append(edges, edge{a, b, rng.Intn(20)})
+				}
+			}
+		}
+		if len(edges) == 0 {
+			continue
+		}
+
+		wantPairs, wantGap := checkMatching(t, n, edges, bitmaskMatching(n, edges))
+		gotPairs, gotGap := checkMatching(t, n, edges, blossomMatching(n, edges))
+		if gotPairs != wantPairs || gotGap != wantGap {
+			t.Fatalf("round %d: blossom found %d pairs with gap %d, bitmask %d with gap %d, edges %v", round, gotPairs, gotGap, wantPairs, wantGap, edges)
+		}
+	}
+}
+
+func TestBestMatchingPairsLargeGroups(t *testing.T) {
+	// The players form a path in which the couples 2i, 2i+1 have the larger
+	// gap, yet pairing them is the only way to seat everyone.
+	n := 3 * maxBitmaskPlayers
+	var edges []edge
+	for i := 0; i+1 < n; i++ {
+		gap := 0
+		if i%2 == 0 {
+			gap = 5
+		}
+		edges = append(edges, edge{i, i + 1, gap})
+	}
+
+	pairs, gap := checkMatching(t, n, edges, bestMatching(n, edges))
+	if pairs != n/2 || gap != 5*n/2 {
+		t.Fatalf("expected %d pairs with gap %d, got %d with gap %d", n/2, 5*n/2, pairs, gap)
+	}
+}
@@ -2,49 +2,191 @@ package matchmaking
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/rating"
 	"github.com/example/connect-four/backend/internal/types"
 )
 
 const (
 	matchInterval        = time.Second
 	botFallbackThreshold = 10 * time.Second
+
+	// ratingWindow is the largest rating gap a player accepts on joining the
+	// queue. It grows by ratingWindowGrowth for every second they wait.
+	ratingWindow       = 100
+	ratingWindowGrowth = 30
 )
 
 type socketSender interface {
 	SendToUsername(ctx context.Context, username string, message types.ServerMessage) error
 }
 
-// Matchmaker coordinates players waiting for a game session.
+type botDirectory interface {
+	IsBot(username string) bool
+	LevelOf(username string) string
+}
+
+// RatingSource looks up the rating players are paired by.
+type RatingSource interface {
+	GetRating(username string) (int, error)
+}
+
+// BotChooser picks the bot a player falls back to when they did not ask for
+// a particular one, and the level it plays at.
+type BotChooser interface {
+	BotFor(username string) (bot, level string)
+}
+
+// Matchmaker coordinates players waiting for a game session. Players are
+// paired by rating: each accepts opponents within a rating gap that widens the
+// longer they wait. Every tick, each group of compatible players is paired to
+// start as many games as possible and then keep rating gaps small. Players
+// left waiting too long play a bot instead.
 type Matchmaker struct {
 	mu      sync.Mutex
 	waiting []waitingPlayer
 	gameMgr *game.GameManager
 	wsMgr   socketSender
+	bots    botDirectory
 	botName string
+
+	// botOpened records, per player, whether the bot moved first in their
+	// last bot game, for alternating who starts.
+	botOpened map[string]bool
+	onBotGame func(context.Context, *game.Game)
+	chooser   BotChooser
+	ratings   RatingSource
+	intn      func(int) int
 }
 
 type waitingPlayer struct {
 	username   string
+	prefs      Preferences
+	rating     int
 	enqueuedAt time.Time
 }
 
-// NewMatchmaker builds a Matchmaker.
-func NewMatchmaker(gameMgr *game.GameManager, wsMgr socketSender, botName string) *Matchmaker {
+// window is the largest rating gap p accepts at now.
+func (p waitingPlayer) window(now time.Time) int {
+	return ratingWindow + int(ratingWindowGrowth*now.Sub(p.enqueuedAt).Seconds())
+}
+
+// Preferences captures what a player asked for when joining the queue.
+// Players are only paired with others whose preferences are compatible.
+// Bot names the bot to play if the player falls back to a bot game; empty or
+// unknown names leave the choice to the matchmaker, and Opener decides who
+// moves first in that game. Rated players only meet other rated players;
+// games against a bot are never rated.
+type Preferences struct {
+	Rules       game.Rules
+	TimeControl game.TimeControl
+	Bot         string
+	Opener      Opener
+	Rated       bool
+}
+
+// Opener is who moves first in a bot game.
+type Opener string
+
+const (
+	// OpenAlternate takes turns with the bot from one game to the next,
+	// starting with the player. It is the default.
+	OpenAlternate Opener = "alternate"
+	OpenPlayer    Opener = "player"
+	OpenBot       Opener = "bot"
+	OpenRandom    Opener = "random"
+)
+
+// ParseOpener validates an opener name. An empty name selects OpenAlternate.
+func ParseOpener(name string) (Opener, error) {
+	switch opener := Opener(name); opener {
+	case "":
+		return OpenAlternate, nil
+	case OpenAlternate, OpenPlayer, OpenBot, OpenRandom:
+		return opener, nil
+	}
+	return "", fmt.Errorf("unknown opener %q", name)
+}
+
+func (p Preferences) compatible(other Preferences) bool {
+	return p.Rules == other.Rules && p.TimeControl == other.TimeControl && p.Rated == other.Rated
+}
+
+// NewMatchmaker builds a Matchmaker. Players who wait too long are matched
+// against the bot they picked if bots knows it, and against botName otherwise.
+func NewMatchmaker(gameMgr *game.GameManager, wsMgr socketSender, bots botDirectory, botName string) *Matchmaker {
 	return &Matchmaker{
 		gameMgr: gameMgr,
 		wsMgr:   wsMgr,
+		bots:    bots,
 		botName: botName,
 		waiting: make([]waitingPlayer, 0),
+
+		botOpened: make(map[string]bool),
+		intn:      rand.Intn,
 	}
 }
 
-// Enqueue adds a player to the waiting list.
-func (m *Matchmaker) Enqueue(username string) {
+// SetRatings makes the matchmaker pair players by the ratings in source.
+// Without a source, or for players it does not know, everyone is rated
+// rating.Default.
+func (m *Matchmaker) SetRatings(source RatingSource) {
+	m.mu.Lock()
+	m.ratings = source
+	m.mu.Unlock()
+}
+
+// SetBotChooser makes players who leave the choice of bot to the matchmaker
+// fall back to the bot chooser picks for them rather than the default bot.
+func (m *Matchmaker) SetBotChooser(chooser BotChooser) {
+	m.mu.Lock()
+	m.chooser = chooser
+	m.mu.Unlock()
+}
+
+// NotifyBotGames registers fn to be called with each bot game once the player
+// has been sent GAME_START, so the bot can open the game when it moves first.
+func (m *Matchmaker) NotifyBotGames(fn func(context.Context, *game.Game)) {
+	m.mu.Lock()
+	m.onBotGame = fn
+	m.mu.Unlock()
+}
+
+// Enqueue adds a player to the waiting list. A zero Rules value selects the
+// classic board. Bots never queue.
+func (m *Matchmaker) Enqueue(username string, prefs Preferences) {
+	if m.isBot(username) {
+		log.Printf("matchmaker: refusing to queue bot username=%s", username)
+		return
+	}
+	if prefs.Rules == (game.Rules{}) {
+		prefs.Rules = game.DefaultRules()
+	}
+	if !m.isBot(prefs.Bot) {
+		prefs.Bot = ""
+	}
+	if prefs.Opener == "" {
+		prefs.Opener = OpenAlternate
+	}
+
+	m.mu.Lock()
+	ratings := m.ratings
+	m.mu.Unlock()
+
+	playerRating := rating.Default
+	if ratings != nil {
+		if value, err := ratings.GetRating(username); err == nil {
+			playerRating = value
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -54,8 +196,8 @@ func (m *Matchmaker) Enqueue(username string) {
 		}
 	}
 
-	m.waiting = append(m.waiting, waitingPlayer{username: username, enqueuedAt: time.Now().UTC()})
-	log.Printf("matchmaker: queued username=%s", username)
+	m.waiting = append(m.waiting, waitingPlayer{username: username, prefs: prefs, rating: playerRating, enqueuedAt: time.Now().UTC()})
+	log.Printf("matchmaker: queued username=%s rating=%d rules=%q clock=%s", username, playerRating, prefs.Rules, prefs.TimeControl)
 }
 
 // Start launches the matchmaking loop in the provided context.
@@ -73,32 +215,196 @@ func (m *Matchmaker) Start(ctx context.Context) {
 	}
 }
 
+// tick pairs waiting players, then gives a bot game to those who have waited
+// past botFallbackThreshold. Their bots are chosen with m.mu released, since
+// the chooser may look levels up in the store.
 func (m *Matchmaker) tick(ctx context.Context) {
+	m.mu.Lock()
+	m.pairWaiting(ctx)
+	var overdue []waitingPlayer
+	for _, player := range m.waiting {
+		if time.Since(player.enqueuedAt) >= botFallbackThreshold {
+			overdue = append(overdue, player)
+		}
+	}
+	chooser := m.chooser
+	m.mu.Unlock()
+
+	if len(overdue) == 0 {
+		return
+	}
+	type botChoice struct{ name, level string }
+	choices := make([]botChoice, len(overdue))
+	for i, player := range overdue {
+		choices[i].name, choices[i].level = m.botFor(player, chooser)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for len(m.waiting) >= 2 {
-		p1 := m.waiting[0]
-		p2 := m.waiting[1]
-		m.waiting = m.waiting[2:]
+	for i, player := range overdue {
+		m.startBotGame(ctx, player, choices[i].name, choices[i].level)
+	}
+}
+
+// pairWaiting starts a game for every pair pairings finds and takes the
+// paired players off the queue. The caller must hold m.mu.
+func (m *Matchmaker) pairWaiting(ctx context.Context) {
+	paired := make([]bool, len(m.waiting))
+	for _, pair := range m.pairings(time.Now()) {
+		p1, p2 := m.waiting[pair[0]], m.waiting[pair[1]]
 
-		game := m.gameMgr.CreateGame(p1.username, p2.username)
-		log.Printf("matchmaker: created game id=%s p1=%s p2=%s", game.ID, game.Player1, game.Player2)
+		game, err := m.gameMgr.CreateGame(p1.username, p2.username, p1.prefs.Rules, p1.prefs.TimeControl)
+		if err != nil {
+			log.Printf("matchmaker: create game failed p1=%s p2=%s err=%v", p1.username, p2.username, err)
+			continue
+		}
+		if err := m.gameMgr.SetRated(game.ID, p1.prefs.Rated); err != nil {
+			log.Printf("matchmaker: set rated failed id=%s err=%v", game.ID, err)
+		}
+		paired[pair[0]], paired[pair[1]] = true, true
+		log.Printf("matchmaker: created game id=%s p1=%s p2=%s ratings=%d/%d rated=%t", game.ID, game.Player1, game.Player2, p1.rating, p2.rating, game.Rated)
 
 		m.notifyPlayers(ctx, game)
 	}
 
-	if len(m.waiting) == 1 {
-		player := m.waiting[0]
-		if time.Since(player.enqueuedAt) >= botFallbackThreshold {
-			m.waiting = m.waiting[1:]
+	remaining := make([]waitingPlayer, 0, len(m.waiting))
+	for i, player := range m.waiting {
+		if !paired[i] {
+			remaining = append(remaining, player)
+		}
+	}
+	m.waiting = remaining
+}
+
+// startBotGame starts a game between player and botName and takes player off
+// the queue, if they are still in it. The caller must hold m.mu.
+func (m *Matchmaker) startBotGame(ctx context.Context, player waitingPlayer, botName, level string) {
+	index := -1
+	for i, waiting := range m.waiting {
+		if waiting.username == player.username && waiting.enqueuedAt.Equal(player.enqueuedAt) {
+			index = i
+		}
+	}
+	if index < 0 {
+		return
+	}
 
-			game := m.gameMgr.CreateGame(player.username, m.botName)
-			log.Printf("matchmaker: created bot game id=%s player=%s bot=%s", game.ID, player.username, m.botName)
+	botFirst := m.botOpens(player)
+	p1, p2 := player.username, botName
+	if botFirst {
+		p1, p2 = p2, p1
+	}
+	game, err := m.gameMgr.CreateGame(p1, p2, player.prefs.Rules, player.prefs.TimeControl)
+	if err != nil {
+		log.Printf("matchmaker: create bot game failed player=%s err=%v", player.username, err)
+		return
+	}
+	if err := m.gameMgr.SetBotLevel(game.ID, level); err != nil {
+		log.Printf("matchmaker: set bot level failed id=%s err=%v", game.ID, err)
+	}
+	m.waiting = append(m.waiting[:index], m.waiting[index+1:]...)
+	m.botOpened[player.username] = botFirst
+	log.Printf("matchmaker: created bot game id=%s player=%s bot=%s level=%s botFirst=%t", game.ID, player.username, botName, game.BotLevel, botFirst)
+
+	m.notifyBotGame(ctx, game, player.username)
+	if m.onBotGame != nil {
+		m.onBotGame(ctx, game)
+	}
+}
 
-			m.notifyBotGame(ctx, game)
+// pairings chooses who plays whom among the waiting players, as pairs of
+// indices into m.waiting with the longer-waiting player first. Each group of
+// compatible players gets the pairing with the most games and then the
+// smallest total rating gap, among players within each other's windows. The
+// caller must hold m.mu.
+func (m *Matchmaker) pairings(now time.Time) [][2]int {
+	var groups [][]int
+	for i, player := range m.waiting {
+		placed := false
+		for g, group := range groups {
+			if m.waiting[group[0]].prefs.compatible(player.prefs) {
+				groups[g] = append(group, i)
+				placed = true
+				break
+			}
 		}
+		if !placed {
+			groups = append(groups, []int{i})
+		}
+	}
+
+	var pairs [][2]int
+	for _, group := range groups {
+		sort.SliceStable(group, func(a, b int) bool {
+			return m.waiting[group[a]].rating < m.waiting[group[b]].rating
+		})
+		for _, pair := range bestMatching(len(group), m.acceptable(group, now)) {
+			a, b := group[pair[0]], group[pair[1]]
+			pairs = append(pairs, [2]int{min(a, b), max(a, b)})
+		}
+	}
+	sort.Slice(pairs, func(a, b int) bool { return pairs[a][0] < pairs[b][0] })
+	return pairs
+}
+
+// acceptable lists the pairs in a group sorted by rating who are within each
+// other's windows at now, numbered by their place in the group.
+func (m *Matchmaker) acceptable(group []int, now time.Time) []edge {
+	var edges []edge
+	for i := range group {
+		first := m.waiting[group[i]]
+		for j := i + 1; j < len(group); j++ {
+			second := m.waiting[group[j]]
+			gap := second.rating - first.rating
+			if gap > first.window(now) {
+				// Later players are further away still.
+				break
+			}
+			if gap <= second.window(now) {
+				edges = append(edges, edge{i, j, gap})
+			}
+		}
+	}
+	return edges
+}
+
+// botFor returns the bot player falls back to and its level: the one
+// chooser gave, or else the bot's own. chooser may query the store, so the
+// caller must not hold m.mu.
+func (m *Matchmaker) botFor(player waitingPlayer, chooser BotChooser) (string, string) {
+	name, level := player.prefs.Bot, ""
+	if name == "" && chooser != nil {
+		if chosen, chosenLevel := chooser.BotFor(player.username); m.isBot(chosen) {
+			name, level = chosen, chosenLevel
+		}
+	}
+	if name == "" {
+		name = m.botName
+	}
+	if level == "" && m.bots != nil {
+		level = m.bots.LevelOf(name)
+	}
+	return name, level
+}
+
+// botOpens decides whether the bot moves first against player. The caller
+// must hold m.mu.
+func (m *Matchmaker) botOpens(player waitingPlayer) bool {
+	switch player.prefs.Opener {
+	case OpenPlayer:
+		return false
+	case OpenBot:
+		return true
+	case OpenRandom:
+		return m.intn(2) == 0
 	}
+	opened, played := m.botOpened[player.username]
+	return played && !opened
+}
+
+func (m *Matchmaker) isBot(username string) bool {
+	return m.bots != nil && m.bots.IsBot(username)
 }
 
 // WaitingCount returns the number of players currently queued.
@@ -111,18 +417,22 @@ func (m *Matchmaker) WaitingCount() int {
 
 func (m *Matchmaker) notifyPlayers(ctx context.Context, game *game.Game) {
 	msgP1 := types.ServerMessage{
-		Type:     "GAME_START",
-		GameID:   game.ID,
-		You:      1,
-		Opponent: game.Player2,
+		Type:      "GAME_START",
+		GameID:    game.ID,
+		You:       1,
+		Opponent:  game.Player2,
+		Rows:      game.Rules.Rows,
+		Columns:   game.Rules.Columns,
+		Connect:   game.Rules.Connect,
+		PopOut:    game.Rules.PopOut,
+		BaseTime:  game.TimeControl.Base.Milliseconds(),
+		Increment: game.TimeControl.Increment.Milliseconds(),
+		Rated:     game.Rated,
 	}
 
-	msgP2 := types.ServerMessage{
-		Type:     "GAME_START",
-		GameID:   game.ID,
-		You:      2,
-		Opponent: game.Player1,
-	}
+	msgP2 := msgP1
+	msgP2.You = 2
+	msgP2.Opponent = game.Player1
 
 	for _, entry := range []struct {
 		username string
@@ -137,15 +447,26 @@ func (m *Matchmaker) notifyPlayers(ctx context.Context, game *game.Game) {
 	}
 }
 
-func (m *Matchmaker) notifyBotGame(ctx context.Context, game *game.Game) {
+func (m *Matchmaker) notifyBotGame(ctx context.Context, game *game.Game, player string) {
 	msg := types.ServerMessage{
-		Type:     "GAME_START",
-		GameID:   game.ID,
-		You:      1,
-		Opponent: game.Player2,
+		Type:      "GAME_START",
+		GameID:    game.ID,
+		You:       1,
+		Opponent:  game.Player2,
+		Rows:      game.Rules.Rows,
+		Columns:   game.Rules.Columns,
+		Connect:   game.Rules.Connect,
+		PopOut:    game.Rules.PopOut,
+		BaseTime:  game.TimeControl.Base.Milliseconds(),
+		Increment: game.TimeControl.Increment.Milliseconds(),
+		BotLevel:  game.BotLevel,
+	}
+
+	if player == game.Player2 {
+		msg.You, msg.Opponent = 2, game.Player1
 	}
 
-	if err := m.wsMgr.SendToUsername(ctx, game.Player1, msg); err != nil {
-		log.Printf("matchmaker: send bot GAME_START failed username=%s err=%v", game.Player1, err)
+	if err := m.wsMgr.SendToUsername(ctx, player, msg); err != nil {
+		log.Printf("matchmaker: send bot GAME_START failed username=%s err=%v", player, err)
 	}
 }
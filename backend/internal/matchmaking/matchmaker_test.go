@@ -2,11 +2,13 @@ package matchmaking
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/rating"
 	"github.com/example/connect-four/backend/internal/types"
 )
 
@@ -37,6 +39,18 @@ func (s *stubSocketManager) messagesFor(username string) []types.ServerMessage {
 	return append([]types.ServerMessage(nil), s.messages[username]...)
 }
 
+// stubBots maps bot usernames to their levels.
+type stubBots map[string]string
+
+func (s stubBots) IsBot(username string) bool {
+	_, ok := s[username]
+	return ok
+}
+
+func (s stubBots) LevelOf(username string) string {
+	return s[username]
+}
+
 func (s *stubSocketManager) SendToUsername(ctx context.Context, username string, message types.ServerMessage) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -56,9 +70,9 @@ func TestMatchmakerPairsPlayers(t *testing.T) {
 	sockets.add("alice")
 	sockets.add("bob")
 
-	matcher := NewMatchmaker(gm, sockets, "BOT")
-	matcher.Enqueue("alice")
-	matcher.Enqueue("bob")
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	matcher.Enqueue("alice", Preferences{})
+	matcher.Enqueue("bob", Preferences{})
 
 	matcher.tick(ctx)
 
@@ -108,8 +122,8 @@ func TestMatchmakerBotFallback(t *testing.T) {
 	sockets := newStubSocketManager()
 	sockets.add("carol")
 
-	matcher := NewMatchmaker(gm, sockets, "BOT")
-	matcher.Enqueue("carol")
+	matcher := NewMatchmaker(gm, sockets, stubBots{"BOT": "medium", "BOT_HARD": "hard"}, "BOT")
+	matcher.Enqueue("carol", Preferences{Bot: "BOT_HARD"})
 
 	matcher.waiting[0].enqueuedAt = time.Now().Add(-botFallbackThreshold - time.Second)
 
@@ -119,9 +133,8 @@ func TestMatchmakerBotFallback(t *testing.T) {
 		t.Fatalf("expected queue to be empty after bot fallback, got %d", matcher.WaitingCount())
 	}
 
-	_, ok := gm.FindGameByPlayers("carol", "BOT")
-	if !ok {
-		t.Fatalf("expected game between carol and BOT")
+	if _, ok := gm.FindGameByPlayers("carol", "BOT_HARD"); !ok {
+		t.Fatalf("expected game between carol and the requested bot")
 	}
 
 	msgs := sockets.messagesFor("carol")
@@ -130,7 +143,7 @@ func TestMatchmakerBotFallback(t *testing.T) {
 	}
 
 	msg := msgs[0]
-	if msg.Type != "GAME_START" || msg.Opponent != "BOT" {
+	if msg.Type != "GAME_START" || msg.Opponent != "BOT_HARD" {
 		t.Fatalf("unexpected bot fallback message: %+v", msg)
 	}
 	if msg.You != 1 {
@@ -146,10 +159,10 @@ func TestMatchmakerRetainsUnpairedPlayer(t *testing.T) {
 	sockets.add("bob")
 	sockets.add("charlie")
 
-	matcher := NewMatchmaker(gm, sockets, "BOT")
-	matcher.Enqueue("alice")
-	matcher.Enqueue("bob")
-	matcher.Enqueue("charlie")
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	matcher.Enqueue("alice", Preferences{})
+	matcher.Enqueue("bob", Preferences{})
+	matcher.Enqueue("charlie", Preferences{})
 
 	matcher.tick(ctx)
 
@@ -165,3 +178,423 @@ func TestMatchmakerRetainsUnpairedPlayer(t *testing.T) {
 		t.Fatalf("expected no messages for charlie")
 	}
 }
+
+func TestMatchmakerPairsOnlyMatchingRules(t *testing.T) {
+	ctx := context.Background()
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	sockets.add("alice")
+	sockets.add("bob")
+	sockets.add("charlie")
+
+	large := game.Rules{Rows: 8, Columns: 9, Connect: 5}
+
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	matcher.Enqueue("alice", Preferences{Rules: large})
+	matcher.Enqueue("bob", Preferences{})
+	matcher.Enqueue("charlie", Preferences{Rules: large})
+
+	matcher.tick(ctx)
+
+	if matcher.WaitingCount() != 1 {
+		t.Fatalf("expected bob to keep waiting, got %d waiting", matcher.WaitingCount())
+	}
+
+	created, ok := gm.FindGameByPlayers("alice", "charlie")
+	if !ok {
+		t.Fatalf("expected game between alice and charlie")
+	}
+	if created.Rules != large {
+		t.Fatalf("expected game rules %s, got %s", large, created.Rules)
+	}
+
+	msgs := sockets.messagesFor("charlie")
+	if len(msgs) != 1 {
+		t.Fatalf("expected one GAME_START for charlie, got %d", len(msgs))
+	}
+	if msgs[0].Rows != 8 || msgs[0].Columns != 9 || msgs[0].Connect != 5 {
+		t.Fatalf("expected GAME_START to announce 8x9 connect 5, got %+v", msgs[0])
+	}
+	if len(sockets.messagesFor("bob")) != 0 {
+		t.Fatalf("expected no messages for bob")
+	}
+}
+
+func TestMatchmakerPairsOnlyMatchingTimeControls(t *testing.T) {
+	ctx := context.Background()
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	sockets.add("alice")
+	sockets.add("bob")
+	sockets.add("charlie")
+
+	blitz := game.TimeControl{Base: 3 * time.Minute, Increment: 2 * time.Second}
+
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	matcher.Enqueue("alice", Preferences{TimeControl: blitz})
+	matcher.Enqueue("bob", Preferences{})
+	matcher.Enqueue("charlie", Preferences{TimeControl: blitz})
+
+	matcher.tick(ctx)
+
+	created, ok := gm.FindGameByPlayers("alice", "charlie")
+	if !ok {
+		t.Fatalf("expected game between alice and charlie")
+	}
+	if created.TimeControl != blitz {
+		t.Fatalf("expected time control %s, got %s", blitz, created.TimeControl)
+	}
+
+	msgs := sockets.messagesFor("alice")
+	if len(msgs) != 1 || msgs[0].BaseTime != 180000 || msgs[0].Increment != 2000 {
+		t.Fatalf("expected GAME_START to announce the time control, got %+v", msgs)
+	}
+	if matcher.WaitingCount() != 1 {
+		t.Fatalf("expected bob to keep waiting, got %d waiting", matcher.WaitingCount())
+	}
+}
+
+func TestMatchmakerFallsBackToDefaultBotAndRefusesBots(t *testing.T) {
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+
+	matcher := NewMatchmaker(gm, sockets, stubBots{"BOT": "medium", "BOT_HARD": "hard"}, "BOT")
+	matcher.Enqueue("BOT_HARD", Preferences{})
+	if matcher.WaitingCount() != 0 {
+		t.Fatalf("expected bots not to be queued")
+	}
+
+	matcher.Enqueue("dave", Preferences{Bot: "BOT_UNKNOWN"})
+	matcher.waiting[0].enqueuedAt = time.Now().Add(-botFallbackThreshold - time.Second)
+	matcher.tick(context.Background())
+
+	if _, ok := gm.FindGameByPlayers("dave", "BOT"); !ok {
+		t.Fatalf("expected unknown bot to fall back to the default bot")
+	}
+}
+
+func TestMatchmakerPairsRatedPlayersSeparately(t *testing.T) {
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	sockets.add("alice")
+	sockets.add("bob")
+	sockets.add("charlie")
+
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	matcher.Enqueue("alice", Preferences{Rated: true})
+	matcher.Enqueue("bob", Preferences{})
+	matcher.Enqueue("charlie", Preferences{Rated: true})
+
+	matcher.tick(context.Background())
+
+	created, ok := gm.FindGameByPlayers("alice", "charlie")
+	if !ok || !created.Rated {
+		t.Fatalf("expected a rated game between alice and charlie")
+	}
+	if msgs := sockets.messagesFor("charlie"); len(msgs) != 1 || !msgs[0].Rated {
+		t.Fatalf("expected GAME_START to announce a rated game, got %+v", msgs)
+	}
+	if matcher.WaitingCount() != 1 {
+		t.Fatalf("expected bob to keep waiting, got %d waiting", matcher.WaitingCount())
+	}
+}
+
+func TestMatchmakerChoosesBotGameOpener(t *testing.T) {
+	ctx := context.Background()
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	sockets.add("erin")
+
+	matcher := NewMatchmaker(gm, sockets, stubBots{"BOT": "medium"}, "BOT")
+	var opened []*game.Game
+	matcher.NotifyBotGames(func(_ context.Context, g *game.Game) { opened = append(opened, g) })
+
+	fallback := func(prefs Preferences) *game.Game {
+		t.Helper()
+		matcher.Enqueue("erin", prefs)
+		matcher.waiting[0].enqueuedAt = time.Now().Add(-botFallbackThreshold - time.Second)
+		before := len(opened)
+		matcher.tick(ctx)
+		if len(opened) != before+1 {
+			t.Fatalf("expected the bot game to be announced")
+		}
+		g := opened[len(opened)-1]
+		gm.RemoveGame(g.ID)
+		return g
+	}
+
+	// Alternating starts with the player and then hands the first move back
+	// and forth.
+	for i, wantFirst := range []string{"erin", "BOT", "erin"} {
+		if g := fallback(Preferences{}); g.Player1 != wantFirst {
+			t.Fatalf("game %d: expected %s to move first, got %s", i+1, wantFirst, g.Player1)
+		}
+	}
+
+	if g := fallback(Preferences{Opener: OpenBot}); g.Player1 != "BOT" {
+		t.Fatalf("expected the bot to open on request, got %s", g.Player1)
+	}
+	if g := fallback(Preferences{Opener: OpenPlayer}); g.Player1 != "erin" {
+		t.Fatalf("expected the player to open on request, got %s", g.Player1)
+	}
+
+	matcher.intn = func(int) int { return 0 }
+	if g := fallback(Preferences{Opener: OpenRandom}); g.Player1 != "BOT" {
+		t.Fatalf("expected the random draw to let the bot open, got %s", g.Player1)
+	}
+
+	msgs := sockets.messagesFor("erin")
+	if start := msgs[1]; start.Type != "GAME_START" || start.You != 2 || start.Opponent != "BOT" {
+		t.Fatalf("expected GAME_START as player 2 when the bot opens, got %+v", start)
+	}
+	if start := msgs[0]; start.You != 1 || start.Opponent != "BOT" {
+		t.Fatalf("expected GAME_START as player 1 when the player opens, got %+v", start)
+	}
+}
+
+func TestParseOpener(t *testing.T) {
+	if opener, err := ParseOpener(""); err != nil || opener != OpenAlternate {
+		t.Fatalf("expected the default opener to alternate, got %q %v", opener, err)
+	}
+	if opener, err := ParseOpener("bot"); err != nil || opener != OpenBot {
+		t.Fatalf("expected bot opener, got %q %v", opener, err)
+	}
+	if _, err := ParseOpener("sideways"); err == nil {
+		t.Fatalf("expected unknown openers to be rejected")
+	}
+}
+
+type fixedChooser struct{ bot, level string }
+
+func (c fixedChooser) BotFor(string) (string, string) {
+	return c.bot, c.level
+}
+
+// queueForBots returns a matcher where frank waits for a casual game and
+// grace for a rated one with grace's preferences, both for long enough to be
+// given a bot. Since only grace plays rated, the two are never paired.
+func queueForBots(t *testing.T, bots stubBots, chooser BotChooser, grace Preferences) (*Matchmaker, *game.GameManager, *stubSocketManager) {
+	t.Helper()
+
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	sockets.add("frank")
+	sockets.add("grace")
+
+	matcher := NewMatchmaker(gm, sockets, bots, "BOT")
+	matcher.SetBotChooser(chooser)
+	matcher.Enqueue("frank", Preferences{Opener: OpenPlayer})
+	grace.Opener, grace.Rated = OpenPlayer, true
+	matcher.Enqueue("grace", grace)
+	for i := range matcher.waiting {
+		matcher.waiting[i].enqueuedAt = time.Now().Add(-botFallbackThreshold - time.Second)
+	}
+	return matcher, gm, sockets
+}
+
+func TestMatchmakerUsesBotChooser(t *testing.T) {
+	bots := stubBots{"BOT": "medium", "BOT_EASY": "easy", "BOT_HARD": "hard"}
+	matcher, gm, sockets := queueForBots(t, bots, fixedChooser{bot: "BOT_EASY", level: "easy"}, Preferences{Bot: "BOT_HARD"})
+
+	matcher.tick(context.Background())
+
+	if _, ok := gm.FindGameByPlayers("frank", "BOT_EASY"); !ok {
+		t.Fatalf("expected frank to meet the chosen bot")
+	}
+	if _, ok := gm.FindGameByPlayers("grace", "BOT_HARD"); !ok {
+		t.Fatalf("expected grace to keep the bot they asked for")
+	}
+	if msgs := sockets.messagesFor("frank"); len(msgs) != 1 || msgs[0].BotLevel != "easy" {
+		t.Fatalf("expected GAME_START to report the chosen level, got %+v", msgs)
+	}
+	if msgs := sockets.messagesFor("grace"); len(msgs) != 1 || msgs[0].BotLevel != "hard" {
+		t.Fatalf("expected GAME_START to report the requested bot's level, got %+v", msgs)
+	}
+	if g, _ := gm.FindGameByPlayers("grace", "BOT_HARD"); g.BotLevel != "hard" {
+		t.Fatalf("expected the bot level on the game, got %q", g.BotLevel)
+	}
+}
+
+type stubRatings map[string]int
+
+func (s stubRatings) GetRating(username string) (int, error) {
+	rating, ok := s[username]
+	if !ok {
+		return 0, errors.New("no rating")
+	}
+	return rating, nil
+}
+
+func TestMatchmakerPairsByRating(t *testing.T) {
+	ctx := context.Background()
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	for _, username := range []string{"alice", "bob", "charlie", "dave"} {
+		sockets.add(username)
+	}
+
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	matcher.SetRatings(stubRatings{"alice": 1200, "bob": 1900, "charlie": 1250, "dave": 1850})
+	for _, username := range []string{"alice", "bob", "charlie", "dave"} {
+		matcher.Enqueue(username, Preferences{})
+	}
+
+	matcher.tick(ctx)
+
+	if matcher.WaitingCount() != 0 {
+		t.Fatalf("expected queue to be empty, got %d", matcher.WaitingCount())
+	}
+	if _, ok := gm.FindGameByPlayers("alice", "charlie"); !ok {
+		t.Fatalf("expected alice to meet charlie")
+	}
+	if _, ok := gm.FindGameByPlayers("bob", "dave"); !ok {
+		t.Fatalf("expected bob to meet dave")
+	}
+}
+
+func TestMatchmakerWidensRatingWindow(t *testing.T) {
+	ctx := context.Background()
+	gm := game.NewManager()
+	sockets := newStubSocketManager()
+	sockets.add("alice")
+	sockets.add("bob")
+
+	matcher := NewMatchmaker(gm, sockets, nil, "BOT")
+	// Bob is unknown to the source and so starts at the default rating.
+	matcher.SetRatings(stubRatings{"alice": rating.Default - 250})
+	matcher.Enqueue("alice", Preferences{})
+	matcher.Enqueue("bob", Preferences{})
+
+	matcher.tick(ctx)
+
+	if matcher.WaitingCount() != 2 {
+		t.Fatalf("expected both players to keep waiting, got %d", matcher.WaitingCount())
+	}
+
+	for i := range matcher.waiting {
+		matcher.waiting[i].enqueuedAt = time.Now().Add(-6 * time.Second)
+	}
+	matcher.tick(ctx)
+
+	if _, ok := gm.FindGameByPlayers("alice", "bob"); !ok {
+		t.Fatalf("expected a wider window to pair alice with bob")
+	}
+}
+
+// blockingChooser waits for release before choosing, like a slow store.
+type blockingChooser struct {
+	asked   chan string
+	release chan struct{}
+}
+
+func (c blockingChooser) BotFor(username string) (string, string) {
+	c.asked <- username
+	<-c.release
+	return "BOT_EASY", "easy"
+}
+
+func TestMatchmakerChoosesBotsWithoutHoldingQueue(t *testing.T) {
+	chooser := blockingChooser{asked: make(chan string), release: make(chan struct{})}
+	matcher, gm, sockets := queueForBots(t, stubBots{"BOT": "medium", "BOT_EASY": "easy"}, chooser, Preferences{})
+	sockets.add("heidi")
+
+	// The chooser is asked for frank and grace in turn, and holds the first
+	// answer until released.
+	done := make(chan struct{})
+	go func() {
+		matcher.tick(context.Background())
+		close(done)
+	}()
+
+	<-chooser.asked
+	// The queue stays usable while the chooser is busy.
+	matcher.Enqueue("heidi", Preferences{})
+	if matcher.WaitingCount() != 3 {
+		t.Fatalf("expected heidi to join the queue, got %d waiting", matcher.WaitingCount())
+	}
+	close(chooser.release)
+	<-chooser.asked
+	<-done
+
+	for _, username := range []string{"frank", "grace"} {
+		if _, ok := gm.FindGameByPlayers(username, "BOT_EASY"); !ok {
+			t.Fatalf("expected %s to meet the chosen bot", username)
+		}
+	}
+	if matcher.WaitingCount() != 1 || matcher.waiting[0].username != "heidi" {
+		t.Fatalf("expected only heidi left waiting, got %+v", matcher.waiting)
+	}
+}
+
+func TestMatchmakerPairingsWithMixedWindows(t *testing.T) {
+	type entrant struct {
+		name   string
+		rating int
+		waited time.Duration
+	}
+	tests := []struct {
+		name     string
+		entrants []entrant
+		want     [][2]string
+	}{
+		{
+			name:     "close ratings pair up",
+			entrants: []entrant{{"a", 1200, 0}, {"b", 1900, 0}, {"c", 1250, 0}, {"d", 1850, 0}},
+			want:     [][2]string{{"a", "c"}, {"b", "d"}},
+		},
+		{
+			name:     "newcomer between two patient players",
+			entrants: []entrant{{"a", 1500, 9 * time.Second}, {"b", 1620, 0}, {"c", 1750, 9 * time.Second}},
+			want:     [][2]string{{"a", "c"}},
+		},
+		{
+			name:     "patient player reaches a newcomer within their window",
+			entrants: []entrant{{"a", 1500, 9 * time.Second}, {"b", 1580, 0}, {"c", 1750, 9 * time.Second}},
+			want:     [][2]string{{"a", "b"}},
+		},
+		{
+			name:     "narrow windows on both sides",
+			entrants: []entrant{{"a", 1500, 0}, {"b", 1620, 9 * time.Second}},
+			want:     nil,
+		},
+		{
+			name:     "chain keeps the closer pair",
+			entrants: []entrant{{"a", 1500, 3 * time.Second}, {"b", 1560, 3 * time.Second}, {"c", 1590, 3 * time.Second}},
+			want:     [][2]string{{"b", "c"}},
+		},
+		{
+			name:     "patient players nest around a closer pair",
+			entrants: []entrant{{"a", 1000, 9 * time.Second}, {"b", 1150, 0}, {"c", 1250, 0}, {"d", 1370, 9 * time.Second}},
+			want:     [][2]string{{"a", "d"}, {"b", "c"}},
+		},
+		{
+			name:     "crossing pairs seat everyone",
+			entrants: []entrant{{"a", 1000, 0}, {"b", 1060, 9 * time.Second}, {"c", 1100, 0}, {"d", 1420, 9 * time.Second}},
+			want:     [][2]string{{"a", "c"}, {"b", "d"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			matcher := NewMatchmaker(game.NewManager(), newStubSocketManager(), nil, "BOT")
+			for _, e := range tt.entrants {
+				prefs := Preferences{Rules: game.DefaultRules(), Opener: OpenAlternate}
+				matcher.waiting = append(matcher.waiting, waitingPlayer{username: e.name, prefs: prefs, rating: e.rating, enqueuedAt: now.Add(-e.waited)})
+			}
+
+			var got [][2]string
+			for _, pair := range matcher.pairings(now) {
+				got = append(got, [2]string{matcher.waiting[pair[0]].username, matcher.waiting[pair[1]].username})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected pairs %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected pairs %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
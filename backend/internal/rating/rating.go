@@ -0,0 +1,25 @@
+// Package rating keeps Elo ratings for players of rated games.
+package rating
+
+import "math"
+
+const (
+	// Default is the rating of a player who has not played a rated game.
+	Default = 1500
+	// K is how many points a single game can move a rating.
+	K = 32
+)
+
+// Expected returns the score a player rated a is expected to make against a
+// player rated b, between 0 and 1.
+func Expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// Update returns the new ratings of two players rated a and b after a game in
+// which the first scored score: 1 for a win, a half for a draw and 0 for a
+// loss. The points one player gains the other loses.
+func Update(a, b int, score float64) (int, int) {
+	delta := int(math.Round(K * (score - Expected(a, b))))
+	return a + delta, b - delta
+}
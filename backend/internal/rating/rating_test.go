@@ -0,0 +1,35 @@
+package rating
+
+import (
+	"math"
+	"testing"
+)
+
+func TestExpected(t *testing.T) {
+	if got := Expected(Default, Default); got != 0.5 {
+		t.Fatalf("expected even players to expect half a point, got %v", got)
+	}
+	if got := Expected(1900, 1500); math.Abs(got-0.909) > 0.001 {
+		t.Fatalf("expected a 400 point favourite to expect about 0.909, got %v", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		a, b  int
+		score float64
+		wantA int
+		wantB int
+	}{
+		{1500, 1500, 1, 1516, 1484},
+		{1500, 1500, 0.5, 1500, 1500},
+		{1500, 1500, 0, 1484, 1516},
+		{1900, 1500, 1, 1903, 1497},
+		{1900, 1500, 0, 1871, 1529},
+	}
+	for _, tt := range tests {
+		if a, b := Update(tt.a, tt.b, tt.score); a != tt.wantA || b != tt.wantB {
+			t.Fatalf("Update(%d, %d, %v) = %d, %d; want %d, %d", tt.a, tt.b, tt.score, a, b, tt.wantA, tt.wantB)
+		}
+	}
+}
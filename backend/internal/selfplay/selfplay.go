@@ -0,0 +1,178 @@
+// Package selfplay turns arena games into labelled training positions.
+package selfplay
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/example/connect-four/backend/internal/arena"
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/solver"
+)
+
+// Sample is one labelled position. Board lists the rows from the top, one
+// digit per cell, separated by slashes; Moves is the game so far in move
+// notation, which numbers columns from 1. Column is the column played, from
+// 0 like game.Ply. Result is the game's outcome for the player to move: 1
+// for a win, 0 for a draw and -1 for a loss.
+type Sample struct {
+	Game        int    `json:"game"`
+	Ply         int    `json:"ply"`
+	Moves       string `json:"moves"`
+	Board       string `json:"board"`
+	Turn        int    `json:"turn"`
+	Kind        string `json:"kind"`
+	Column      int    `json:"column"`
+	Result      int    `json:"result"`
+	SolverScore *int   `json:"solverScore,omitempty"`
+
+	hash uint64
+}
+
+var csvHeader = []string{"game", "ply", "moves", "board", "turn", "kind", "column", "result", "solver_score"}
+
+func (s Sample) csvRow() []string {
+	score := ""
+	if s.SolverScore != nil {
+		score = strconv.Itoa(*s.SolverScore)
+	}
+	return []string{
+		strconv.Itoa(s.Game), strconv.Itoa(s.Ply), s.Moves, s.Board, strconv.Itoa(s.Turn),
+		s.Kind, strconv.Itoa(s.Column), strconv.Itoa(s.Result), score,
+	}
+}
+
+// Game is the samples of one arena game, in the order they were played.
+type Game struct {
+	Index   int
+	Samples []Sample
+}
+
+// Label turns records into samples on workers goroutines. Games come out in
+// the order they are labelled, not by index; Write puts them back in order.
+// scores may be nil to leave solver scores out.
+func Label(records <-chan arena.Record, scores *ScoreCache, workers int) <-chan Game {
+	labelled := make(chan Game, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var s *solver.Solver
+			if scores != nil {
+				s = solver.New()
+			}
+			for r := range records {
+				samples, err := SamplesOf(r, scores, s)
+				if err != nil {
+					log.Printf("game %d: %v", r.Index, err)
+				}
+				labelled <- Game{Index: r.Index, Samples: samples}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(labelled)
+	}()
+	return labelled
+}
+
+// SamplesOf replays r and labels every position after the random opening.
+// Forfeited games say nothing about the position, and games stopped at the
+// ply cap have no real result, so both are skipped. s is only used when
+// scores is not nil.
+func SamplesOf(r arena.Record, scores *ScoreCache, s *solver.Solver) ([]Sample, error) {
+	if r.Termination == arena.TerminationForfeit || r.Termination == arena.TerminationPlyLimit {
+		return nil, nil
+	}
+
+	pos := game.NewPosition(r.Rules)
+	samples := make([]Sample, 0, max(0, len(r.Plies)-r.Opening))
+	for i, ply := range r.Plies {
+		if i >= r.Opening {
+			moves, err := game.EncodeMoves(r.Rules, r.Plies[:i])
+			if err != nil {
+				return nil, err
+			}
+			smp := Sample{
+				Game:   r.Index,
+				Ply:    i + 1,
+				Moves:  moves,
+				Board:  boardString(pos),
+				Turn:   pos.Turn(),
+				Kind:   string(ply.Kind),
+				Column: ply.Column,
+				Result: resultFor(r.Winner, pos.Turn()),
+				hash:   pos.CanonicalHash(),
+			}
+			if scores != nil {
+				smp.SolverScore = scores.score(s, pos)
+			}
+			samples = append(samples, smp)
+		}
+		if err := pos.Apply(ply); err != nil {
+			return nil, fmt.Errorf("ply %d: %w", i+1, err)
+		}
+	}
+	return samples, nil
+}
+
+func resultFor(winner, turn int) int {
+	switch winner {
+	case 0:
+		return 0
+	case turn:
+		return 1
+	}
+	return -1
+}
+
+func boardString(pos game.Position) string {
+	var sb strings.Builder
+	for r, row := range pos.Board() {
+		if r > 0 {
+			sb.WriteByte('/')
+		}
+		for _, cell := range row {
+			sb.WriteByte(byte('0' + cell))
+		}
+	}
+	return sb.String()
+}
+
+// ScoreCache remembers solver scores by canonical hash, since the same
+// positions come up in many games. It is safe for concurrent use.
+type ScoreCache struct {
+	minPlies int
+	scores   sync.Map
+}
+
+// NewScoreCache returns a cache that only solves positions with at least
+// minPlies discs, since earlier ones take too long.
+func NewScoreCache(minPlies int) *ScoreCache {
+	return &ScoreCache{minPlies: minPlies}
+}
+
+// score returns the solver score of pos for the player to move, or nil if
+// pos is too early in the game to solve quickly.
+func (c *ScoreCache) score(s *solver.Solver, pos game.Position) *int {
+	if pos.Moves() < c.minPlies {
+		return nil
+	}
+	if cached, ok := c.scores.Load(pos.CanonicalHash()); ok {
+		score := cached.(int)
+		return &score
+	}
+
+	result, err := s.Solve(pos)
+	if err != nil {
+		log.Printf("solve position: %v", err)
+		return nil
+	}
+	c.scores.Store(pos.CanonicalHash(), result.Score)
+	return &result.Score
+}
@@ -0,0 +1,173 @@
+package selfplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/example/connect-four/backend/internal/arena"
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func drops(cols ...int) []game.Ply {
+	plies := make([]game.Ply, len(cols))
+	for i, col := range cols {
+		plies[i] = game.Ply{Kind: game.DROP, Column: col}
+	}
+	return plies
+}
+
+// generate plays a small match and writes its samples under dir, returning
+// the contents of every shard in order.
+func generate(t *testing.T, dir string, concurrency, shardSize int) [][]byte {
+	t.Helper()
+
+	a := arena.Player{Name: "EASY", Strategy: bot.Easy.Strategy()}
+	b := arena.Player{Name: "MEDIUM", Strategy: bot.Medium.Strategy()}
+	cfg := arena.Config{Rules: game.DefaultRules(), Games: 6, OpeningPlies: 2, Concurrency: concurrency, Seed: 3}
+
+	records := make(chan arena.Record, concurrency)
+	go func() {
+		defer close(records)
+		if _, err := arena.Run(context.Background(), a, b, cfg, func(r arena.Record) { records <- r }); err != nil {
+			t.Errorf("run: %v", err)
+		}
+	}()
+
+	w, err := NewShardWriter(filepath.Join(dir, "out"), "jsonl", shardSize)
+	if err != nil {
+		t.Fatalf("new shard writer: %v", err)
+	}
+	if _, err := Write(Label(records, nil, concurrency), w, true, nil); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	shards, err := filepath.Glob(filepath.Join(dir, "out-*.jsonl"))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	if len(shards) != w.Shards() {
+		t.Fatalf("expected %d shard files, found %d", w.Shards(), len(shards))
+	}
+	contents := make([][]byte, len(shards))
+	for i, name := range shards {
+		if contents[i], err = os.ReadFile(name); err != nil {
+			t.Fatalf("read shard: %v", err)
+		}
+	}
+	return contents
+}
+
+func TestOutputIsIndependentOfConcurrency(t *testing.T) {
+	serial := generate(t, t.TempDir(), 1, 1000)
+	parallel := generate(t, t.TempDir(), 4, 1000)
+
+	if len(serial) != 1 || len(serial[0]) == 0 {
+		t.Fatalf("expected one non-empty shard, got %d", len(serial))
+	}
+	if len(parallel) != len(serial) || !bytes.Equal(serial[0], parallel[0]) {
+		t.Fatalf("expected the same samples with and without concurrency")
+	}
+}
+
+func TestShardSizeRollsFilesOver(t *testing.T) {
+	shards := generate(t, t.TempDir(), 2, 5)
+	if len(shards) < 2 {
+		t.Fatalf("expected several shards of 5 samples, got %d", len(shards))
+	}
+	for i, shard := range shards {
+		lines := bytes.Count(shard, []byte("\n"))
+		if lines > 5 || (i < len(shards)-1 && lines != 5) {
+			t.Fatalf("shard %d: expected 5 samples, or fewer in the last shard, got %d", i, lines)
+		}
+	}
+}
+
+func TestWriteSkipsMirrorPositions(t *testing.T) {
+	rules := game.DefaultRules()
+	games := make(chan Game, 2)
+	for i, record := range []arena.Record{
+		{Index: 0, Rules: rules, Plies: drops(0, 1), Winner: 1},
+		{Index: 1, Rules: rules, Plies: drops(6, 5), Winner: 2},
+	} {
+		samples, err := SamplesOf(record, nil, nil)
+		if err != nil {
+			t.Fatalf("game %d: %v", i, err)
+		}
+		games <- Game{Index: record.Index, Samples: samples}
+	}
+	close(games)
+
+	dir := t.TempDir()
+	w, err := NewShardWriter(filepath.Join(dir, "out"), "jsonl", 10)
+	if err != nil {
+		t.Fatalf("new shard writer: %v", err)
+	}
+	stats, err := Write(games, w, true, nil)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	// The second game mirrors the first, so only the first game's empty
+	// board and one-disc position are written.
+	if stats.Games != 2 || stats.Written != 2 || stats.Duplicates != 2 {
+		t.Fatalf("expected 2 positions written and 2 mirrors skipped, got %+v", stats)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "out-00000.jsonl"))
+	if err != nil {
+		t.Fatalf("read shard: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var s Sample
+		if err := dec.Decode(&s); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if s.Game != 0 {
+			t.Fatalf("expected positions labelled from the first game, got game %d", s.Game)
+		}
+	}
+}
+
+func TestSamplesAreLabelledForTheSideToMove(t *testing.T) {
+	record := arena.Record{Rules: game.DefaultRules(), Opening: 1, Plies: drops(3, 3, 4), Winner: 2}
+	samples, err := SamplesOf(record, nil, nil)
+	if err != nil {
+		t.Fatalf("samples: %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("expected the opening ply to be left out, got %d samples", len(samples))
+	}
+
+	// Player 2 moves at ply 2 and player 1 at ply 3; player 2 won.
+	if s := samples[0]; s.Ply != 2 || s.Turn != 2 || s.Result != 1 || s.Moves != "4" || s.Column != 3 {
+		t.Fatalf("expected a win for player 2 after 4, got %+v", s)
+	}
+	if s := samples[1]; s.Ply != 3 || s.Turn != 1 || s.Result != -1 || s.Moves != "44" || s.Column != 4 {
+		t.Fatalf("expected a loss for player 1 after 44, got %+v", s)
+	}
+
+	record.Winner = 0
+	samples, _ = SamplesOf(record, nil, nil)
+	for _, s := range samples {
+		if s.Result != 0 {
+			t.Fatalf("expected draws to be labelled 0, got %+v", s)
+		}
+	}
+
+	record.Termination = arena.TerminationPlyLimit
+	if samples, _ := SamplesOf(record, nil, nil); len(samples) != 0 {
+		t.Fatalf("expected games stopped at the ply cap to be skipped, got %d samples", len(samples))
+	}
+}
@@ -0,0 +1,161 @@
+package selfplay
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Stats counts what Write has done so far.
+type Stats struct {
+	Games      int
+	Written    int
+	Duplicates int
+}
+
+// Write writes games to w in index order, holding back those that finish
+// early, so the output does not depend on which worker was fastest. With
+// dedupe, a position already written, or its mirror image, is skipped.
+// progress, if not nil, is called after every game.
+func Write(labelled <-chan Game, w *ShardWriter, dedupe bool, progress func(Stats)) (Stats, error) {
+	var stats Stats
+	seen := make(map[uint64]bool)
+	pending := make(map[int]Game)
+	next := 0
+
+	flush := func(g Game) error {
+		stats.Games++
+		for _, smp := range g.Samples {
+			if dedupe {
+				if seen[smp.hash] {
+					stats.Duplicates++
+					continue
+				}
+				seen[smp.hash] = true
+			}
+			if err := w.Write(smp); err != nil {
+				return err
+			}
+			stats.Written++
+		}
+		if progress != nil {
+			progress(stats)
+		}
+		return nil
+	}
+
+	for g := range labelled {
+		pending[g.Index] = g
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if err := flush(ready); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	// Games missing after a stop leave gaps; write the rest in order.
+	indices := make([]int, 0, len(pending))
+	for index := range pending {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	for _, index := range indices {
+		if err := flush(pending[index]); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// ShardWriter writes samples to numbered files of at most size samples
+// each, named <prefix>-00000.<format> and so on. Format is jsonl or csv.
+type ShardWriter struct {
+	prefix string
+	format string
+	size   int
+
+	shards int
+	count  int
+	file   *os.File
+	json   *json.Encoder
+	csv    *csv.Writer
+}
+
+// NewShardWriter returns a writer for shards of size samples in format,
+// which must be jsonl or csv.
+func NewShardWriter(prefix, format string, size int) (*ShardWriter, error) {
+	if format != "jsonl" && format != "csv" {
+		return nil, fmt.Errorf("unknown format %q, want jsonl or csv", format)
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("shard size must be positive, got %d", size)
+	}
+	return &ShardWriter{prefix: prefix, format: format, size: size}, nil
+}
+
+// Shards returns the number of files started so far.
+func (w *ShardWriter) Shards() int {
+	return w.shards
+}
+
+// Write appends s to the current shard, starting a new one when it is full.
+func (w *ShardWriter) Write(s Sample) error {
+	if w.file == nil || w.count == w.size {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+	w.count++
+
+	if w.csv != nil {
+		return w.csv.Write(s.csvRow())
+	}
+	return w.json.Encode(s)
+}
+
+// open closes the current shard and starts the next.
+func (w *ShardWriter) open() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%05d.%s", w.prefix, w.shards, w.format)
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w.file, w.count = f, 0
+	w.shards++
+
+	if w.format == "csv" {
+		w.csv = csv.NewWriter(f)
+		return w.csv.Write(csvHeader)
+	}
+	w.json = json.NewEncoder(f)
+	return nil
+}
+
+// Close flushes and closes the current shard, if any.
+func (w *ShardWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	var err error
+	if w.csv != nil {
+		w.csv.Flush()
+		err = w.csv.Error()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file, w.csv, w.json = nil, nil, nil
+	return err
+}
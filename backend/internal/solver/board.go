@@ -0,0 +1,172 @@
+package solver
+
+import (
+	"errors"
+	"math/bits"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+const (
+	width  = 7
+	height = 6
+	cells  = width * height
+
+	// Scores count how early the side to move wins: a win with the winner's
+	// k-th disc scores 22-k, a loss the negative of that and a draw zero.
+	minScore = -cells/2 + 3
+	maxScore = (cells+1)/2 - 3
+)
+
+var (
+	bottomMask = bottomRow()
+	boardMask  = bottomMask * (1<<height - 1)
+)
+
+// bottomRow sets the lowest cell of every column.
+func bottomRow() uint64 {
+	var mask uint64
+	for col := 0; col < width; col++ {
+		mask |= bottomCell(col)
+	}
+	return mask
+}
+
+func bottomCell(col int) uint64 {
+	return 1 << (col * (height + 1))
+}
+
+func topCell(col int) uint64 {
+	return 1 << (height - 1) << (col * (height + 1))
+}
+
+func columnCells(col int) uint64 {
+	return (1<<height - 1) << (col * (height + 1))
+}
+
+// board is the solver's own 6x7 bitboard. Each column takes height+1 bits,
+// bottom first, so shifted line checks never wrap between columns. current
+// holds the discs of the side to move and mask every occupied cell, which
+// together identify the position uniquely.
+type board struct {
+	current uint64
+	mask    uint64
+	moves   int
+}
+
+// errUnsupportedRules is returned for anything other than the classic board.
+var errUnsupportedRules = errors.New("solver only supports the classic 6x7 connect-four board")
+
+// fromPosition copies a classic game position into a board.
+func fromPosition(pos game.Position) (board, error) {
+	if pos.Rules() != game.DefaultRules() {
+		return board{}, errUnsupportedRules
+	}
+	if pos.HasWon(1) || pos.HasWon(2) {
+		return board{}, errors.New("game is already over")
+	}
+
+	var b board
+	for col := 0; col < width; col++ {
+		for h := 0; h < height; h++ {
+			owner := pos.Cell(height-1-h, col)
+			if owner == 0 {
+				break
+			}
+			cell := uint64(1) << (col*(height+1) + h)
+			b.mask |= cell
+			if owner == pos.Turn() {
+				b.current |= cell
+			}
+		}
+	}
+	b.moves = pos.Moves()
+	return b, nil
+}
+
+// key identifies the position for the transposition table.
+func (b *board) key() uint64 {
+	return b.current + b.mask + bottomMask
+}
+
+func (b *board) canPlay(col int) bool {
+	return b.mask&topCell(col) == 0
+}
+
+// play drops a disc given by its single-bit cell and passes the turn.
+func (b *board) play(move uint64) {
+	b.current ^= b.mask
+	b.mask |= move
+	b.moves++
+}
+
+func (b *board) playColumn(col int) {
+	b.play((b.mask + bottomCell(col)) & columnCells(col))
+}
+
+// isWinningMove reports whether dropping into col wins for the side to move.
+func (b *board) isWinningMove(col int) bool {
+	return b.winningCells()&b.possible()&columnCells(col) != 0
+}
+
+func (b *board) canWinNext() bool {
+	return b.winningCells()&b.possible() != 0
+}
+
+// possible returns the cells a disc can be dropped into.
+func (b *board) possible() uint64 {
+	return (b.mask + bottomMask) & boardMask
+}
+
+// nonLosingMoves returns the playable cells that do not hand the opponent an
+// immediate win. It must not be called when the side to move can win at once.
+func (b *board) nonLosingMoves() uint64 {
+	possible := b.possible()
+	threats := b.opponentWinningCells()
+	if forced := possible & threats; forced != 0 {
+		if forced&(forced-1) != 0 {
+			// Two threats cannot both be blocked.
+			return 0
+		}
+		possible = forced
+	}
+	// Never play directly beneath an opponent's winning cell.
+	return possible &^ (threats >> 1)
+}
+
+// moveScore ranks a move by the number of winning cells it creates.
+func (b *board) moveScore(move uint64) int {
+	return bits.OnesCount64(winningCells(b.current|move, b.mask))
+}
+
+func (b *board) winningCells() uint64 {
+	return winningCells(b.current, b.mask)
+}
+
+func (b *board) opponentWinningCells() uint64 {
+	return winningCells(b.current^b.mask, b.mask)
+}
+
+// winningCells returns the empty cells that would complete a line for the
+// discs in stones.
+func winningCells(stones, mask uint64) uint64 {
+	// Vertical: three discs directly below.
+	r := (stones << 1) & (stones << 2) & (stones << 3)
+	r |= lineEnds(stones, height+1) // horizontal
+	r |= lineEnds(stones, height)   // one diagonal
+	r |= lineEnds(stones, height+2) // the other diagonal
+
+	return r & (boardMask ^ mask)
+}
+
+// lineEnds returns the cells that complete four in a row along the line
+// whose neighbouring cells are shift bits apart, gaps included.
+func lineEnds(stones uint64, shift uint) uint64 {
+	p := (stones << shift) & (stones << (2 * shift))
+	r := p & (stones << (3 * shift))
+	r |= p & (stones >> shift)
+	p = (stones >> shift) & (stones >> (2 * shift))
+	r |= p & (stones << shift)
+	r |= p & (stones >> (3 * shift))
+	return r
+}
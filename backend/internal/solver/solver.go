@@ -0,0 +1,303 @@
+// Package solver plays classic 6x7 Connect Four perfectly. It is meant as
+// ground truth for testing the bot, for hints and for post-game review.
+package solver
+
+import (
+	"errors"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Outcome is the result of a position under perfect play, from the point of
+// view of the player to move.
+type Outcome int
+
+const (
+	Loss Outcome = iota - 1
+	Draw
+	Win
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "win"
+	case Loss:
+		return "loss"
+	default:
+		return "draw"
+	}
+}
+
+// Result is the exact value of a position for the player to move.
+type Result struct {
+	Outcome Outcome
+	// Score is positive for a win and negative for a loss. Its magnitude is
+	// 22 minus the number of discs the winner has once the line is made, so
+	// faster wins score higher. Draws score zero.
+	Score int
+	// Plies is the number of moves, counting both sides, until the game ends
+	// when the winner wins as fast and the loser holds out as long as possible.
+	Plies int
+}
+
+// resultFor converts a score for a board into a Result.
+func resultFor(score, moves int) Result {
+	switch {
+	case score > 0:
+		// The winner is to move and has played moves/2 discs so far.
+		return Result{Outcome: Win, Score: score, Plies: 2*(cells/2+1-score-moves/2) - 1}
+	case score < 0:
+		return Result{Outcome: Loss, Score: score, Plies: 2 * (cells/2 + 1 + score - (moves+1)/2)}
+	default:
+		return Result{Outcome: Draw, Plies: cells - moves}
+	}
+}
+
+// Solver searches positions with a transposition table that is kept between
+// calls. A Solver is not safe for concurrent use.
+type Solver struct {
+	table *table
+	nodes uint64
+}
+
+// New returns a Solver with an empty transposition table of about 40 MB.
+func New() *Solver {
+	return &Solver{table: newTable()}
+}
+
+// Nodes returns the number of positions searched since the Solver was created.
+func (s *Solver) Nodes() uint64 {
+	return s.nodes
+}
+
+// Reset clears the transposition table.
+func (s *Solver) Reset() {
+	s.table.reset()
+}
+
+// Solve returns the exact value of pos for the player to move. Only classic
+// 6x7 positions in which nobody has won yet can be solved.
+func (s *Solver) Solve(pos game.Position) (Result, error) {
+	b, err := fromPosition(pos)
+	if err != nil {
+		return Result{}, err
+	}
+	return resultFor(s.solve(b), b.moves), nil
+}
+
+// Analyze solves every column of pos for the player to move. Full columns are
+// reported as nil. The columns share the transposition table, so positions
+// reached through more than one of them are only searched once.
+func (s *Solver) Analyze(pos game.Position) ([]*Result, error) {
+	b, err := fromPosition(pos)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, width)
+	for _, col := range columnOrder {
+		if !b.canPlay(col) {
+			continue
+		}
+		result := resultFor(s.solveColumn(b, col), b.moves)
+		results[col] = &result
+	}
+	return results, nil
+}
+
+// BestMove returns a column with the best value for the player to move,
+// preferring the centre among equals. It solves the position once and then
+// takes the first column that reaches its value, which one null-window search
+// per column settles using the bounds the solve left in the table.
+func (s *Solver) BestMove(pos game.Position) (int, Result, error) {
+	b, err := fromPosition(pos)
+	if err != nil {
+		return -1, Result{}, err
+	}
+
+	score := s.solve(b)
+	for _, col := range columnOrder {
+		if !b.canPlay(col) {
+			continue
+		}
+		if b.isWinningMove(col) || s.columnBeats(b, col, score-1) {
+			return col, resultFor(score, b.moves), nil
+		}
+	}
+	return -1, Result{}, errors.New("no legal move")
+}
+
+// solveColumn returns the exact score of playing col in b.
+func (s *Solver) solveColumn(b board, col int) int {
+	if b.isWinningMove(col) {
+		return (cells + 1 - b.moves) / 2
+	}
+	next := b
+	next.playColumn(col)
+	return -s.solve(next)
+}
+
+// columnBeats reports whether playing col in b scores more than score.
+func (s *Solver) columnBeats(b board, col, score int) bool {
+	next := b
+	next.playColumn(col)
+	switch {
+	case next.moves == cells:
+		return 0 > score
+	case next.canWinNext():
+		return -(cells+1-next.moves)/2 > score
+	}
+	// The column beats score exactly when the opponent's score is below
+	// -score, which fails low on the window (-score-1, -score).
+	return s.negamax(next, -score-1, -score) <= -score-1
+}
+
+// columnOrder explores the centre columns first.
+var columnOrder = [width]int{3, 2, 4, 1, 5, 0, 6}
+
+// solve finds the exact score of b by iterative deepening on the score
+// window. A score near the extremes means a short game, so each iteration
+// asks with a null-window search whether either side wins within a horizon,
+// starting one disc ahead and doubling it, which only needs a search about
+// that deep. Once the horizon covers what is left of the window, the
+// remaining range is halved instead. Every probe reuses the bounds the
+// earlier ones left in the transposition table.
+func (s *Solver) solve(b board) int {
+	if b.moves == cells {
+		return 0
+	}
+	if b.canWinNext() {
+		return (cells + 1 - b.moves) / 2
+	}
+
+	min := -(cells - b.moves) / 2
+	max := (cells + 1 - b.moves) / 2
+	for horizon := 1; min < max; horizon *= 2 {
+		if 2*horizon >= max-min {
+			med := min + (max-min)/2
+			min, max = s.probe(b, med, min, max)
+			continue
+		}
+		// A quick win for the side to move, then a quick loss.
+		min, max = s.probe(b, max-horizon, min, max)
+		if min < max && min+horizon < max {
+			min, max = s.probe(b, min+horizon-1, min, max)
+		}
+	}
+	return min
+}
+
+// probe narrows the window [min, max] holding the score of b by testing
+// whether it is above med.
+func (s *Solver) probe(b board, med, min, max int) (int, int) {
+	score := s.negamax(b, med, med+1)
+	if score <= med {
+		return min, score
+	}
+	return score, max
+}
+
+// negamax returns the score of b within the window (alpha, beta). The side
+// to move must not be able to win immediately.
+//
+// Table entries hold either an upper bound, stored as score-minScore+1, or a
+// lower bound, stored as score+maxScore-2*minScore+2 so that both ranges fit
+// in a byte without overlapping.
+func (s *Solver) negamax(b board, alpha, beta int) int {
+	s.nodes++
+
+	next := b.nonLosingMoves()
+	if next == 0 {
+		// Every move lets the opponent win on their next disc.
+		return -(cells - b.moves) / 2
+	}
+	if b.moves >= cells-2 {
+		// Neither side can complete a line in the last two moves.
+		return 0
+	}
+
+	// The opponent cannot win next move, so the score is bounded by the
+	// earliest possible loss and win.
+	lower := -(cells - 2 - b.moves) / 2
+	if alpha < lower {
+		alpha = lower
+		if alpha >= beta {
+			return alpha
+		}
+	}
+	upper := (cells - 1 - b.moves) / 2
+
+	key := b.key()
+	if value := int(s.table.get(key)); value != 0 {
+		if value > maxScore-minScore+1 {
+			lower = value + 2*minScore - maxScore - 2
+			if alpha < lower {
+				alpha = lower
+				if alpha >= beta {
+					return alpha
+				}
+			}
+		} else {
+			upper = value + minScore - 1
+		}
+	}
+	if beta > upper {
+		beta = upper
+		if alpha >= beta {
+			return beta
+		}
+	}
+
+	// Try the moves that create the most threats first, breaking ties
+	// towards the centre.
+	var moves moveSorter
+	for i := width - 1; i >= 0; i-- {
+		if move := next & columnCells(columnOrder[i]); move != 0 {
+			moves.add(move, b.moveScore(move))
+		}
+	}
+
+	for move := moves.next(); move != 0; move = moves.next() {
+		child := b
+		child.play(move)
+		score := -s.negamax(child, -beta, -alpha)
+		if score >= beta {
+			s.table.put(key, uint8(score+maxScore-2*minScore+2))
+			return score
+		}
+		if score > alpha {
+			alpha = score
+		}
+	}
+
+	s.table.put(key, uint8(alpha-minScore+1))
+	return alpha
+}
+
+// moveSorter yields moves by descending score. Among equal scores the move
+// added last comes out first, so callers add their preferred moves last.
+type moveSorter struct {
+	moves  [width]uint64
+	scores [width]int
+	size   int
+}
+
+func (m *moveSorter) add(move uint64, score int) {
+	pos := m.size
+	m.size++
+	for ; pos > 0 && m.scores[pos-1] > score; pos-- {
+		m.moves[pos] = m.moves[pos-1]
+		m.scores[pos] = m.scores[pos-1]
+	}
+	m.moves[pos] = move
+	m.scores[pos] = score
+}
+
+func (m *moveSorter) next() uint64 {
+	if m.size == 0 {
+		return 0
+	}
+	m.size--
+	return m.moves[m.size]
+}
@@ -0,0 +1,259 @@
+package solver
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func positionFromMoves(t testing.TB, moves string) game.Position {
+	t.Helper()
+
+	pos, err := game.PositionFromMoves(game.DefaultRules(), moves)
+	if err != nil {
+		t.Fatalf("position from moves %q: %v", moves, err)
+	}
+	return pos
+}
+
+// referenceScore is a plain alpha-beta search over game.Position scored the
+// same way as the solver, used to check it on positions close to the end.
+func referenceScore(pos game.Position, alpha, beta int) int {
+	if pos.IsFull() {
+		return 0
+	}
+	for col := 0; col < width; col++ {
+		if pos.IsWinningMove(col, pos.Turn()) {
+			return (cells + 1 - pos.Moves()) / 2
+		}
+	}
+
+	best := -cells
+	for col := 0; col < width; col++ {
+		if !pos.CanPlay(col) {
+			continue
+		}
+		pos.Play(col)
+		score := -referenceScore(pos, -beta, -alpha)
+		pos.Undo(col)
+
+		if score > best {
+			best = score
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// randomEndgame plays random non-winning moves until discs are on the board.
+func randomEndgame(rng *rand.Rand, discs int) (game.Position, bool) {
+	pos := game.NewPosition(game.DefaultRules())
+	for pos.Moves() < discs {
+		var cols []int
+		for col := 0; col < width; col++ {
+			if pos.CanPlay(col) && !pos.IsWinningMove(col, pos.Turn()) {
+				cols = append(cols, col)
+			}
+		}
+		if len(cols) == 0 {
+			return pos, false
+		}
+		pos.Play(cols[rng.Intn(len(cols))])
+	}
+	return pos, true
+}
+
+func TestSolveMatchesReferenceSearch(t *testing.T) {
+	s := New()
+	rng := rand.New(rand.NewSource(1))
+
+	for checked := 0; checked < 40; {
+		pos, ok := randomEndgame(rng, 30+rng.Intn(6))
+		if !ok {
+			continue
+		}
+		checked++
+
+		want := referenceScore(pos, -cells, cells)
+		got, err := s.Solve(pos)
+		if err != nil {
+			t.Fatalf("solve: %v", err)
+		}
+		if got.Score != want {
+			t.Fatalf("position %v: expected score %d, got %d", pos.Board(), want, got.Score)
+		}
+	}
+}
+
+func TestSolveFindsQuickWin(t *testing.T) {
+	// Player 1 has 4 and 5 on the bottom row and wins by making an open three.
+	got, err := New().Solve(positionFromMoves(t, "4455"))
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+	want := Result{Outcome: Win, Score: 18, Plies: 3}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestAnalyzeScoresEveryColumn(t *testing.T) {
+	s := New()
+	pos := positionFromMoves(t, "444444326")
+
+	results, err := s.Analyze(pos)
+	if err != nil {
+		t.Fatalf("analyze: %v", err)
+	}
+	if results[3] != nil {
+		t.Fatalf("expected the full centre column to have no result, got %+v", results[3])
+	}
+
+	// Only column 5 stops player 1's threat on the bottom row.
+	for col, result := range results {
+		if col == 3 {
+			continue
+		}
+		if col == 4 {
+			if result.Outcome != Loss || result.Score != -1 || result.Plies != 32 {
+				t.Fatalf("column 5: expected a loss in 32 plies, got %+v", result)
+			}
+			continue
+		}
+		if result.Outcome != Loss || result.Plies != 2 {
+			t.Fatalf("column %d: expected an immediate loss, got %+v", col+1, result)
+		}
+	}
+
+	col, best, err := s.BestMove(pos)
+	if err != nil {
+		t.Fatalf("best move: %v", err)
+	}
+	if col != 4 || best != *results[4] {
+		t.Fatalf("expected column 5 as best move, got column %d with %+v", col+1, best)
+	}
+
+	solved, err := s.Solve(pos)
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+	if solved != best {
+		t.Fatalf("expected position value %+v to match the best column, got %+v", best, solved)
+	}
+}
+
+func TestBestMoveAgreesWithAnalyze(t *testing.T) {
+	s := New()
+	rng := rand.New(rand.NewSource(2))
+
+	for checked := 0; checked < 20; {
+		pos, ok := randomEndgame(rng, 22+rng.Intn(8))
+		if !ok {
+			continue
+		}
+		checked++
+
+		results, err := s.Analyze(pos)
+		if err != nil {
+			t.Fatalf("analyze: %v", err)
+		}
+		want := -1
+		for _, col := range columnOrder {
+			if results[col] != nil && (want < 0 || results[col].Score > results[want].Score) {
+				want = col
+			}
+		}
+
+		col, best, err := s.BestMove(pos)
+		if err != nil {
+			t.Fatalf("best move: %v", err)
+		}
+		if col != want || best != *results[want] {
+			t.Fatalf("position %v: expected column %d with %+v, got column %d with %+v", pos.Board(), want+1, *results[want], col+1, best)
+		}
+	}
+}
+
+func TestBestMoveFindsQuickWinWithoutSolvingEveryColumn(t *testing.T) {
+	s := New()
+	col, best, err := s.BestMove(positionFromMoves(t, "4455"))
+	if err != nil {
+		t.Fatalf("best move: %v", err)
+	}
+	if col != 2 || best.Plies != 3 {
+		t.Fatalf("expected the open three in column 3, got column %d with %+v", col+1, best)
+	}
+	// Analyze needs tens of millions of nodes here for the slow columns.
+	if s.Nodes() > 1000 {
+		t.Fatalf("expected the quick win to be found in a handful of nodes, searched %d", s.Nodes())
+	}
+}
+
+func TestSolveShallowPositionInTime(t *testing.T) {
+	// Eight discs in, this takes about ten million nodes, a couple of
+	// seconds on a single core.
+	pos := positionFromMoves(t, "12345671")
+
+	started := time.Now()
+	got, err := New().Solve(pos)
+	if err != nil {
+		t.Fatalf("solve: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 20*time.Second {
+		t.Fatalf("expected the solve to take seconds, took %s", elapsed)
+	}
+	if got.Outcome != Draw {
+		t.Fatalf("expected a draw, got %+v", got)
+	}
+}
+
+func TestResultPlies(t *testing.T) {
+	cases := []struct {
+		score, moves int
+		want         Result
+	}{
+		// The first player wins the empty board with their 21st disc.
+		{1, 0, Result{Outcome: Win, Score: 1, Plies: 41}},
+		{-1, 1, Result{Outcome: Loss, Score: -1, Plies: 40}},
+		{0, 12, Result{Outcome: Draw, Plies: 30}},
+		{(cells + 1 - 6) / 2, 6, Result{Outcome: Win, Score: 18, Plies: 1}},
+	}
+	for _, c := range cases {
+		if got := resultFor(c.score, c.moves); got != c.want {
+			t.Fatalf("score %d after %d moves: expected %+v, got %+v", c.score, c.moves, c.want, got)
+		}
+	}
+}
+
+func TestSolveRejectsUnsupportedPositions(t *testing.T) {
+	s := New()
+
+	popOut := game.DefaultRules()
+	popOut.PopOut = true
+	if _, err := s.Solve(game.NewPosition(popOut)); err == nil {
+		t.Fatalf("expected PopOut positions to be rejected")
+	}
+	if _, err := s.Solve(game.NewPosition(game.Rules{Rows: 7, Columns: 8, Connect: 4})); err == nil {
+		t.Fatalf("expected other board sizes to be rejected")
+	}
+	if _, err := s.Solve(positionFromMoves(t, "1212121")); err == nil {
+		t.Fatalf("expected a finished game to be rejected")
+	}
+}
+
+func BenchmarkSolveMidgame(b *testing.B) {
+	pos := positionFromMoves(b, "444444326")
+	for i := 0; i < b.N; i++ {
+		s := New()
+		if _, err := s.Solve(pos); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
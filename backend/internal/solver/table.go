@@ -0,0 +1,33 @@
+package solver
+
+// tableSize is a prime just above 2^23. Because it exceeds 2^17, the low 32
+// bits of a 49-bit key together with its slot identify the key exactly.
+const tableSize = 8388617
+
+// table is a fixed-size transposition table that keeps the latest bound
+// stored for each slot. Each entry packs the low 32 bits of the key above
+// the 8-bit value, so a lookup touches a single cache line. A zero value
+// marks an empty slot.
+type table struct {
+	entries []uint64
+}
+
+func newTable() *table {
+	return &table{entries: make([]uint64, tableSize)}
+}
+
+func (t *table) put(key uint64, value uint8) {
+	t.entries[key%tableSize] = uint64(uint32(key))<<8 | uint64(value)
+}
+
+func (t *table) get(key uint64) uint8 {
+	entry := t.entries[key%tableSize]
+	if uint32(entry>>8) != uint32(key) {
+		return 0
+	}
+	return uint8(entry)
+}
+
+func (t *table) reset() {
+	clear(t.entries)
+}
@@ -0,0 +1,71 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// AnalyzedMove is the engine's verdict on one move of a completed game.
+// Loss is the share of the expected score, between 0 and 1, that the move
+// gave away compared with BestKind and BestColumn.
+type AnalyzedMove struct {
+	Ply        int     `json:"ply"`
+	Player     string  `json:"player"`
+	Kind       string  `json:"kind"`
+	Column     int     `json:"column"`
+	Class      string  `json:"class"`
+	Loss       float64 `json:"loss"`
+	BestKind   string  `json:"bestKind"`
+	BestColumn int     `json:"bestColumn"`
+}
+
+// GameAnalysis is the move-by-move analysis of a completed game, with an
+// accuracy between 0 and 100 for each player who moved.
+type GameAnalysis struct {
+	GameID     string             `json:"gameId"`
+	Moves      []AnalyzedMove     `json:"moves"`
+	Accuracy   map[string]float64 `json:"accuracy"`
+	AnalyzedAt time.Time          `json:"analyzedAt"`
+}
+
+// SaveAnalysis stores the analysis of a game, replacing any earlier one.
+func (r *Repository) SaveAnalysis(analysis *GameAnalysis) error {
+	if analysis == nil || analysis.GameID == "" {
+		return errors.New("analysis game id is required")
+	}
+
+	data, err := json.Marshal(analysis)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.Exec(
+		`INSERT INTO game_analyses (game_id, analysis, analyzed_at) VALUES ($1, $2, $3)
+         ON CONFLICT (game_id) DO UPDATE SET analysis = EXCLUDED.analysis, analyzed_at = EXCLUDED.analyzed_at`,
+		analysis.GameID,
+		data,
+		analysis.AnalyzedAt,
+	)
+	return err
+}
+
+// GetAnalysis loads the stored analysis of a game. It returns ErrNotFound if
+// the game has not been analysed.
+func (r *Repository) GetAnalysis(gameID string) (*GameAnalysis, error) {
+	var data []byte
+	err := r.db.QueryRow(`SELECT analysis FROM game_analyses WHERE game_id = $1`, gameID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var analysis GameAnalysis
+	if err := json.Unmarshal(data, &analysis); err != nil {
+		return nil, err
+	}
+	return &analysis, nil
+}
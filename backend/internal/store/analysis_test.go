@@ -0,0 +1,59 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSaveAndGetAnalysis(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	analysis := &GameAnalysis{
+		GameID:     "game-123",
+		Moves:      []AnalyzedMove{{Ply: 1, Player: "alice", Kind: "DROP", Column: 3, Class: "BEST", BestKind: "DROP", BestColumn: 3}},
+		Accuracy:   map[string]float64{"alice": 100},
+		AnalyzedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	data := []byte(`{"gameId":"game-123","moves":[{"ply":1,"player":"alice","kind":"DROP","column":3,"class":"BEST","loss":0,"bestKind":"DROP","bestColumn":3}],"accuracy":{"alice":100},"analyzedAt":"2024-01-02T03:04:05Z"}`)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO game_analyses (game_id, analysis, analyzed_at) VALUES ($1, $2, $3)")).
+		WithArgs("game-123", data, analysis.AnalyzedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT analysis FROM game_analyses WHERE game_id = $1")).
+		WithArgs("game-123").
+		WillReturnRows(sqlmock.NewRows([]string{"analysis"}).AddRow(data))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT analysis FROM game_analyses WHERE game_id = $1")).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	if err := repo.SaveAnalysis(analysis); err != nil {
+		t.Fatalf("SaveAnalysis failed: %v", err)
+	}
+
+	loaded, err := repo.GetAnalysis("game-123")
+	if err != nil {
+		t.Fatalf("GetAnalysis failed: %v", err)
+	}
+	if len(loaded.Moves) != 1 || loaded.Moves[0].Class != "BEST" || loaded.Accuracy["alice"] != 100 {
+		t.Fatalf("unexpected analysis: %+v", loaded)
+	}
+
+	if _, err := repo.GetAnalysis("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
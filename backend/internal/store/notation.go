@@ -0,0 +1,36 @@
+package store
+
+import (
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// MovesToNotation renders completed moves as a game move string such as "4453".
+// Moves recorded before move kinds were stored are treated as drops, and
+// moves removed by takebacks are left out.
+func MovesToNotation(rules game.Rules, moves []CompletedMove) (string, error) {
+	active := game.ActiveMoves(GameMoves(moves))
+	plies := make([]game.Ply, len(active))
+	for i, mv := range active {
+		plies[i] = game.Ply{Kind: mv.Kind, Column: mv.Column}
+	}
+	return game.EncodeMoves(rules, plies)
+}
+
+// MovesFromNotation expands a move string into completed moves, alternating
+// between player1 and player2 starting with player1.
+func MovesFromNotation(rules game.Rules, notation, player1, player2 string) ([]CompletedMove, error) {
+	plies, err := game.ParseMoves(rules, notation)
+	if err != nil {
+		return nil, err
+	}
+
+	moves := make([]CompletedMove, len(plies))
+	for i, ply := range plies {
+		player := player1
+		if i%2 == 1 {
+			player = player2
+		}
+		moves[i] = CompletedMove{Player: player, Kind: string(ply.Kind), Column: ply.Column, MoveNumber: i + 1}
+	}
+	return moves, nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func TestCompletedMovesNotationRoundTrip(t *testing.T) {
+	rules := game.DefaultRules()
+
+	moves, err := MovesFromNotation(rules, "4453", "alice", "bob")
+	if err != nil {
+		t.Fatalf("MovesFromNotation failed: %v", err)
+	}
+	if len(moves) != 4 || moves[1].Player != "bob" || moves[1].Column != 3 || moves[3].MoveNumber != 4 {
+		t.Fatalf("unexpected moves %+v", moves)
+	}
+
+	payload, err := json.Marshal(moves)
+	if err != nil {
+		t.Fatalf("marshal moves: %v", err)
+	}
+	var decoded []CompletedMove
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		t.Fatalf("unmarshal moves: %v", err)
+	}
+
+	notation, err := MovesToNotation(rules, decoded)
+	if err != nil {
+		t.Fatalf("MovesToNotation failed: %v", err)
+	}
+	if notation != "4453" {
+		t.Fatalf("expected 4453, got %q", notation)
+	}
+}
+
+func TestMovesToNotationLegacyAndInvalid(t *testing.T) {
+	rules := game.DefaultRules()
+
+	legacy := []CompletedMove{{Player: "alice", Column: 0, MoveNumber: 1}, {Player: "bob", Column: 6, MoveNumber: 2}}
+	notation, err := MovesToNotation(rules, legacy)
+	if err != nil || notation != "17" {
+		t.Fatalf("expected 17 for moves without kind, got %q err %v", notation, err)
+	}
+
+	if _, err := MovesToNotation(rules, []CompletedMove{{Player: "alice", Column: 9, MoveNumber: 1}}); err == nil {
+		t.Fatalf("expected out-of-range column to be rejected")
+	}
+	if _, err := MovesFromNotation(rules, "12121213", "alice", "bob"); err == nil {
+		t.Fatalf("expected moves after a win to be rejected")
+	}
+}
+
+func TestMovesToNotationSkipsTakenBackMoves(t *testing.T) {
+	moves := []CompletedMove{
+		{Player: "alice", Kind: "DROP", Column: 3, MoveNumber: 1},
+		{Player: "bob", Kind: "DROP", Column: 0, MoveNumber: 2},
+		{Player: "bob", Kind: "TAKEBACK", Undone: 1, MoveNumber: 3},
+		{Player: "bob", Kind: "DROP", Column: 3, MoveNumber: 4},
+	}
+
+	notation, err := MovesToNotation(game.DefaultRules(), moves)
+	if err != nil || notation != "44" {
+		t.Fatalf("expected 44, got %q err %v", notation, err)
+	}
+}
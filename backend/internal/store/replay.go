@@ -0,0 +1,79 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+// Replay rebuilds the final game state from the persisted move log.
+func (g *CompletedGame) Replay() (*game.Game, error) {
+	rules := g.Rules
+	if rules == (game.Rules{}) {
+		rules = game.DefaultRules()
+	}
+
+	return game.ReplayBetween(rules, g.Player1, g.Player2, GameMoves(g.Moves))
+}
+
+// GameMoves converts persisted moves back into game moves. Moves recorded
+// before move kinds were stored are treated as drops.
+func GameMoves(moves []CompletedMove) []game.Move {
+	out := make([]game.Move, len(moves))
+	for i, mv := range moves {
+		kind := game.MoveKind(mv.Kind)
+		if kind == "" {
+			kind = game.DROP
+		}
+		out[i] = game.Move{Player: mv.Player, Kind: kind, Column: mv.Column, MoveNumber: mv.MoveNumber, PlayedAt: mv.PlayedAt, Undone: mv.Undone}
+	}
+	return out
+}
+
+// Verify checks that the recorded result agrees with a replay of the move log.
+// Mismatches wrap game.ErrStateMismatch.
+func (g *CompletedGame) Verify() error {
+	replayed, err := g.Replay()
+	if err != nil {
+		return fmt.Errorf("%w: %v", game.ErrStateMismatch, err)
+	}
+
+	for _, mv := range g.Moves {
+		if mv.Player != g.Player1 && mv.Player != g.Player2 {
+			return fmt.Errorf("%w: move %d played by %s", game.ErrStateMismatch, mv.MoveNumber, mv.Player)
+		}
+	}
+
+	switch {
+	case g.Winner != nil && *g.Winner != "":
+		if replayed.Winner == nil || *replayed.Winner != *g.Winner {
+			return fmt.Errorf("%w: recorded winner %s is not supported by the moves", game.ErrStateMismatch, *g.Winner)
+		}
+		if len(g.WinningCells) > 0 && !sameCells(g.WinningCells, replayed.WinningCells) {
+			return fmt.Errorf("%w: winning cells differ", game.ErrStateMismatch)
+		}
+	case g.IsDraw:
+		if replayed.EndedAt == nil || replayed.Winner != nil {
+			return fmt.Errorf("%w: recorded draw is not supported by the moves", game.ErrStateMismatch)
+		}
+	case replayed.EndedAt != nil && replayed.Termination != game.TerminationAbort:
+		return fmt.Errorf("%w: moves finish the game but no result was recorded", game.ErrStateMismatch)
+	}
+
+	if g.Termination != "" && game.Termination(g.Termination) != replayed.Termination {
+		return fmt.Errorf("%w: recorded termination %s, moves say %s", game.ErrStateMismatch, g.Termination, replayed.Termination)
+	}
+	return nil
+}
+
+func sameCells(a, b []game.Cell) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
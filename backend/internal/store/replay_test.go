@@ -0,0 +1,77 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/example/connect-four/backend/internal/game"
+)
+
+func TestCompletedGameVerify(t *testing.T) {
+	rules := game.DefaultRules()
+	moves, err := MovesFromNotation(rules, "1212121", "alice", "bob")
+	if err != nil {
+		t.Fatalf("MovesFromNotation failed: %v", err)
+	}
+
+	record := CompletedGame{ID: "game-1", Player1: "alice", Player2: "bob", Rules: rules, Winner: strPtr("alice"), Moves: moves}
+	if err := record.Verify(); err != nil {
+		t.Fatalf("expected record to verify: %v", err)
+	}
+
+	replayed, err := record.Replay()
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	record.WinningCells = replayed.WinningCells
+	if err := record.Verify(); err != nil {
+		t.Fatalf("expected record with winning cells to verify: %v", err)
+	}
+
+	tampered := record
+	tampered.Winner = strPtr("bob")
+	if err := tampered.Verify(); !errors.Is(err, game.ErrStateMismatch) {
+		t.Fatalf("expected ErrStateMismatch for wrong winner, got %v", err)
+	}
+
+	draw := record
+	draw.Winner = nil
+	draw.IsDraw = true
+	if err := draw.Verify(); !errors.Is(err, game.ErrStateMismatch) {
+		t.Fatalf("expected ErrStateMismatch for false draw, got %v", err)
+	}
+
+	impostor := record
+	impostor.Player2 = "carol"
+	if err := impostor.Verify(); !errors.Is(err, game.ErrStateMismatch) {
+		t.Fatalf("expected ErrStateMismatch for unknown mover, got %v", err)
+	}
+}
+
+func TestCompletedGameVerifyTermination(t *testing.T) {
+	rules := game.DefaultRules()
+	moves, err := MovesFromNotation(rules, "44", "alice", "bob")
+	if err != nil {
+		t.Fatalf("MovesFromNotation failed: %v", err)
+	}
+	moves = append(moves, CompletedMove{Player: "alice", Kind: string(game.RESIGN), MoveNumber: 3})
+
+	record := CompletedGame{ID: "game-1", Player1: "alice", Player2: "bob", Rules: rules, Winner: strPtr("bob"), Termination: "RESIGNATION", Moves: moves}
+	if err := record.Verify(); err != nil {
+		t.Fatalf("expected resignation to verify: %v", err)
+	}
+
+	mislabelled := record
+	mislabelled.Termination = "CONNECT"
+	if err := mislabelled.Verify(); !errors.Is(err, game.ErrStateMismatch) {
+		t.Fatalf("expected ErrStateMismatch for wrong termination, got %v", err)
+	}
+
+	aborted := CompletedGame{ID: "game-2", Player1: "alice", Player2: "bob", Rules: rules, Termination: "ABORTED", Moves: []CompletedMove{
+		{Player: "alice", Kind: "DROP", Column: 3, MoveNumber: 1},
+		{Player: "bob", Kind: string(game.ABORT), MoveNumber: 2},
+	}}
+	if err := aborted.Verify(); err != nil {
+		t.Fatalf("expected aborted game to verify: %v", err)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/rating"
 )
 
 // Repository exposes persistence helpers for leaderboards and completed games.
@@ -20,23 +23,42 @@ type LeaderboardEntry struct {
 
 // CompletedMove describes a single move in a completed game.
 type CompletedMove struct {
-	Player     string `json:"player"`
-	Column     int    `json:"column"`
-	MoveNumber int    `json:"moveNumber"`
+	Player     string    `json:"player"`
+	Kind       string    `json:"kind"`
+	Column     int       `json:"column"`
+	MoveNumber int       `json:"moveNumber"`
+	PlayedAt   time.Time `json:"playedAt"`
+	Undone     int       `json:"undone,omitempty"`
+}
+
+// CompletedHint records a hint a player asked for during the game.
+type CompletedHint struct {
+	Player      string    `json:"player"`
+	MoveNumber  int       `json:"moveNumber"`
+	RequestedAt time.Time `json:"requestedAt"`
 }
 
 // CompletedGame captures the data required to persist a finished match.
 type CompletedGame struct {
-	ID        string
-	Player1   string
-	Player2   string
-	Winner    *string
-	IsDraw    bool
-	Moves     []CompletedMove
-	StartedAt time.Time
-	EndedAt   time.Time
+	ID           string
+	Player1      string
+	Player2      string
+	Rules        game.Rules
+	TimeControl  game.TimeControl
+	Winner       *string
+	WinningCells []game.Cell
+	IsDraw       bool
+	Termination  string
+	Moves        []CompletedMove
+	Rated        bool
+	Hints        []CompletedHint
+	StartedAt    time.Time
+	EndedAt      time.Time
 }
 
+// ErrNotFound is returned when a requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
 // NewRepository constructs a Repository using an existing sql.DB connection.
 func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
@@ -72,54 +94,252 @@ func (r *Repository) IncrementWin(username string) error {
 	return err
 }
 
+// GetBotLevel returns the bot level the player is matched against, or an
+// empty level if none has been stored.
+func (r *Repository) GetBotLevel(username string) (string, error) {
+	var level sql.NullString
+	err := r.db.QueryRow(`SELECT bot_level FROM players WHERE username = $1`, username).Scan(&level)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return level.String, err
+}
+
+// SetBotLevel stores the bot level the player is matched against, creating
+// the player if needed.
+func (r *Repository) SetBotLevel(username, level string) error {
+	if username == "" {
+		return errors.New("username is required")
+	}
+
+	_, err := r.db.Exec(
+		`INSERT INTO players (username, bot_level) VALUES ($1, $2)
+         ON CONFLICT (username) DO UPDATE SET bot_level = EXCLUDED.bot_level`,
+		username,
+		level,
+	)
+	return err
+}
+
+// GetRating returns the player's rating. It returns ErrNotFound for unknown
+// players.
+func (r *Repository) GetRating(username string) (int, error) {
+	var value int
+	err := r.db.QueryRow(`SELECT rating FROM players WHERE username = $1`, username).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return value, err
+}
+
+// RecordRatedResult updates both players' ratings after a rated game in which
+// player1 scored score: 1 for a win, a half for a draw and 0 for a loss.
+// Both players must exist.
+func (r *Repository) RecordRatedResult(player1, player2 string, score float64) error {
+	if player1 == "" || player2 == "" {
+		return errors.New("both players are required")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Lock the rows in a fixed order so concurrent results cannot deadlock.
+	rows, err := tx.Query(`SELECT username, rating FROM players WHERE username IN ($1, $2) ORDER BY username FOR UPDATE`, player1, player2)
+	if err != nil {
+		return err
+	}
+	ratings := make(map[string]int, 2)
+	for rows.Next() {
+		var (
+			username string
+			value    int
+		)
+		if err := rows.Scan(&username, &value); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		ratings[username] = value
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if len(ratings) != 2 {
+		return ErrNotFound
+	}
+
+	new1, new2 := rating.Update(ratings[player1], ratings[player2], score)
+	for _, update := range []struct {
+		username string
+		value    int
+	}{
+		{player1, new1},
+		{player2, new2},
+	} {
+		if _, err := tx.Exec(`UPDATE players SET rating = $2 WHERE username = $1`, update.username, update.value); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
 // SaveCompletedGame inserts a completed game record.
-func (r *Repository) SaveCompletedGame(game *CompletedGame) error {
-	if game == nil {
+func (r *Repository) SaveCompletedGame(record *CompletedGame) error {
+	if record == nil {
 		return errors.New("game is required")
 	}
-	if game.ID == "" {
+	if record.ID == "" {
 		return errors.New("game id is required")
 	}
-	if game.Player1 == "" || game.Player2 == "" {
+	if record.Player1 == "" || record.Player2 == "" {
 		return errors.New("player names are required")
 	}
 
-	movesJSON, err := json.Marshal(game.Moves)
+	movesJSON, err := json.Marshal(record.Moves)
 	if err != nil {
 		return err
 	}
 
+	// An untyped nil is sent as SQL NULL; a nil []byte would be sent as an
+	// empty string, which Postgres rejects as JSON. The same goes for hints.
 	winner := sql.NullString{}
-	if game.Winner != nil && *game.Winner != "" {
+	var winningCells any
+	if record.Winner != nil && *record.Winner != "" {
 		winner.Valid = true
-		winner.String = *game.Winner
+		winner.String = *record.Winner
+
+		if len(record.WinningCells) > 0 {
+			cells, err := json.Marshal(record.WinningCells)
+			if err != nil {
+				return err
+			}
+			winningCells = cells
+		}
 	}
 
-	startedAt := game.StartedAt
+	startedAt := record.StartedAt
 	if startedAt.IsZero() {
 		startedAt = time.Now().UTC()
 	}
 
-	endedAt := game.EndedAt
+	endedAt := record.EndedAt
 	if endedAt.IsZero() {
 		endedAt = time.Now().UTC()
 	}
 
+	rules := record.Rules
+	if rules == (game.Rules{}) {
+		rules = game.DefaultRules()
+	}
+
+	termination := sql.NullString{String: record.Termination, Valid: record.Termination != ""}
+
+	var hintsJSON any
+	if len(record.Hints) > 0 {
+		hints, err := json.Marshal(record.Hints)
+		if err != nil {
+			return err
+		}
+		hintsJSON = hints
+	}
+
 	_, err = r.db.Exec(
-		`INSERT INTO games (id, player1, player2, winner, is_draw, moves, started_at, ended_at)
-         VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
-		game.ID,
-		game.Player1,
-		game.Player2,
+		`INSERT INTO games (id, player1, player2, winner, is_draw, moves, started_at, ended_at, board_rows, board_columns, connect_length, pop_out, winning_cells, termination, base_time_ms, increment_ms, rated, hints)
+         VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`,
+		record.ID,
+		record.Player1,
+		record.Player2,
 		winner,
-		game.IsDraw,
+		record.IsDraw,
 		movesJSON,
 		startedAt,
 		endedAt,
+		rules.Rows,
+		rules.Columns,
+		rules.Connect,
+		rules.PopOut,
+		winningCells,
+		termination,
+		record.TimeControl.Base.Milliseconds(),
+		record.TimeControl.Increment.Milliseconds(),
+		record.Rated,
+		hintsJSON,
 	)
 	return err
 }
 
+// GetCompletedGame loads a completed game by id. It returns ErrNotFound if
+// there is none.
+func (r *Repository) GetCompletedGame(id string) (*CompletedGame, error) {
+	var (
+		record       = CompletedGame{ID: id}
+		winner       sql.NullString
+		termination  sql.NullString
+		movesJSON    []byte
+		winningCells []byte
+		hintsJSON    []byte
+		baseTime     int64
+		increment    int64
+	)
+
+	err := r.db.QueryRow(
+		`SELECT player1, player2, winner, is_draw, moves, started_at, ended_at, board_rows, board_columns, connect_length, pop_out, winning_cells, termination, base_time_ms, increment_ms, rated, hints
+         FROM games WHERE id = $1`,
+		id,
+	).Scan(
+		&record.Player1,
+		&record.Player2,
+		&winner,
+		&record.IsDraw,
+		&movesJSON,
+		&record.StartedAt,
+		&record.EndedAt,
+		&record.Rules.Rows,
+		&record.Rules.Columns,
+		&record.Rules.Connect,
+		&record.Rules.PopOut,
+		&winningCells,
+		&termination,
+		&baseTime,
+		&increment,
+		&record.Rated,
+		&hintsJSON,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if winner.Valid {
+		record.Winner = &winner.String
+	}
+	record.Termination = termination.String
+	record.TimeControl = game.TimeControl{Base: time.Duration(baseTime) * time.Millisecond, Increment: time.Duration(increment) * time.Millisecond}
+
+	if err := json.Unmarshal(movesJSON, &record.Moves); err != nil {
+		return nil, err
+	}
+	for _, field := range []struct {
+		data []byte
+		into any
+	}{
+		{winningCells, &record.WinningCells},
+		{hintsJSON, &record.Hints},
+	} {
+		if len(field.data) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(field.data, field.into); err != nil {
+			return nil, err
+		}
+	}
+	return &record, nil
+}
+
 // GetLeaderboard returns the top players ordered by wins.
 func (r *Repository) GetLeaderboard(limit int) ([]LeaderboardEntry, error) {
 	if limit <= 0 {
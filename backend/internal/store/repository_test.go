@@ -1,11 +1,15 @@
 package store
 
 import (
+	"database/sql"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/example/connect-four/backend/internal/game"
 )
 
 func TestEnsurePlayer(t *testing.T) {
@@ -74,16 +78,22 @@ func TestSaveCompletedGame(t *testing.T) {
 		Player1: "alice",
 		Player2: "bob",
 		Winner:  strPtr("alice"),
-		IsDraw:  false,
+		WinningCells: []game.Cell{
+			{Row: 5, Col: 0}, {Row: 5, Col: 1}, {Row: 5, Col: 2}, {Row: 5, Col: 3},
+		},
+		IsDraw:      false,
+		Termination: "CONNECT",
+		TimeControl: game.TimeControl{Base: 3 * time.Minute, Increment: 2 * time.Second},
 		Moves: []CompletedMove{
-			{Player: "alice", Column: 0, MoveNumber: 1},
-			{Player: "bob", Column: 1, MoveNumber: 2},
+			{Player: "alice", Kind: "DROP", Column: 0, MoveNumber: 1},
+			{Player: "bob", Kind: "DROP", Column: 1, MoveNumber: 2},
 		},
+		Hints:     []CompletedHint{{Player: "alice", MoveNumber: 1, RequestedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}},
 		StartedAt: time.Now().UTC().Add(-time.Hour),
 		EndedAt:   time.Now().UTC(),
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO games (id, player1, player2, winner, is_draw, moves, started_at, ended_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)")).
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO games (id, player1, player2, winner, is_draw, moves, started_at, ended_at, board_rows, board_columns, connect_length, pop_out, winning_cells, termination, base_time_ms, increment_ms, rated, hints) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)")).
 		WithArgs(
 			finished.ID,
 			finished.Player1,
@@ -93,6 +103,122 @@ func TestSaveCompletedGame(t *testing.T) {
 			sqlmock.AnyArg(),
 			finished.StartedAt,
 			finished.EndedAt,
+			game.Rows,
+			game.Columns,
+			game.ConnectLength,
+			false,
+			[]byte(`[{"row":5,"col":0},{"row":5,"col":1},{"row":5,"col":2},{"row":5,"col":3}]`),
+			sql.NullString{String: "CONNECT", Valid: true},
+			int64(180000),
+			int64(2000),
+			false,
+			[]byte(`[{"player":"alice","moveNumber":1,"requestedAt":"2024-01-02T03:04:05Z"}]`),
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.SaveCompletedGame(&finished); err != nil {
+		t.Fatalf("SaveCompletedGame failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSaveCompletedGameDraw(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	finished := CompletedGame{
+		ID:          "game-456",
+		Player1:     "alice",
+		Player2:     "bob",
+		IsDraw:      true,
+		Termination: "BOARD_FULL",
+		Moves: []CompletedMove{
+			{Player: "alice", Kind: "DROP", Column: 3, MoveNumber: 1},
+		},
+		StartedAt: time.Now().UTC().Add(-time.Hour),
+		EndedAt:   time.Now().UTC(),
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO games (id, player1, player2, winner, is_draw, moves, started_at, ended_at, board_rows, board_columns, connect_length, pop_out, winning_cells, termination, base_time_ms, increment_ms, rated, hints) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)")).
+		WithArgs(
+			finished.ID,
+			finished.Player1,
+			finished.Player2,
+			sql.NullString{},
+			true,
+			sqlmock.AnyArg(),
+			finished.StartedAt,
+			finished.EndedAt,
+			game.Rows,
+			game.Columns,
+			game.ConnectLength,
+			false,
+			nil,
+			sql.NullString{String: "BOARD_FULL", Valid: true},
+			int64(0),
+			int64(0),
+			false,
+			nil,
+		).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.SaveCompletedGame(&finished); err != nil {
+		t.Fatalf("SaveCompletedGame failed: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSaveCompletedGameWithoutHints(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	finished := CompletedGame{
+		ID:           "game-789",
+		Player1:      "alice",
+		Player2:      "bob",
+		Winner:       strPtr("bob"),
+		WinningCells: []game.Cell{{Row: 5, Col: 0}, {Row: 4, Col: 0}, {Row: 3, Col: 0}, {Row: 2, Col: 0}},
+		Termination:  "CONNECT",
+		StartedAt:    time.Now().UTC().Add(-time.Hour),
+		EndedAt:      time.Now().UTC(),
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO games (id, player1, player2, winner, is_draw, moves, started_at, ended_at, board_rows, board_columns, connect_length, pop_out, winning_cells, termination, base_time_ms, increment_ms, rated, hints) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)")).
+		WithArgs(
+			finished.ID,
+			finished.Player1,
+			finished.Player2,
+			sql.NullString{String: "bob", Valid: true},
+			false,
+			sqlmock.AnyArg(),
+			finished.StartedAt,
+			finished.EndedAt,
+			game.Rows,
+			game.Columns,
+			game.ConnectLength,
+			false,
+			[]byte(`[{"row":5,"col":0},{"row":4,"col":0},{"row":3,"col":0},{"row":2,"col":0}]`),
+			sql.NullString{String: "CONNECT", Valid: true},
+			int64(0),
+			int64(0),
+			false,
+			nil,
 		).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
@@ -136,6 +262,112 @@ func TestGetLeaderboard(t *testing.T) {
 	}
 }
 
+func TestBotLevelRoundTrip(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO players (username, bot_level) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET bot_level = EXCLUDED.bot_level")).
+		WithArgs("alice", "hard").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	query := regexp.QuoteMeta("SELECT bot_level FROM players WHERE username = $1")
+	mock.ExpectQuery(query).WithArgs("alice").WillReturnRows(sqlmock.NewRows([]string{"bot_level"}).AddRow("hard"))
+	mock.ExpectQuery(query).WithArgs("bob").WillReturnRows(sqlmock.NewRows([]string{"bot_level"}).AddRow(nil))
+	mock.ExpectQuery(query).WithArgs("carol").WillReturnError(sql.ErrNoRows)
+
+	if err := repo.SetBotLevel("alice", "hard"); err != nil {
+		t.Fatalf("SetBotLevel failed: %v", err)
+	}
+	if level, err := repo.GetBotLevel("alice"); err != nil || level != "hard" {
+		t.Fatalf("expected stored level hard, got %q %v", level, err)
+	}
+	for _, username := range []string{"bob", "carol"} {
+		if level, err := repo.GetBotLevel(username); err != nil || level != "" {
+			t.Fatalf("expected no level for %s, got %q %v", username, level, err)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecordRatedResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT username, rating FROM players WHERE username IN ($1, $2) ORDER BY username FOR UPDATE")).
+		WithArgs("bob", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"username", "rating"}).AddRow("alice", 1500).AddRow("bob", 1500))
+	update := regexp.QuoteMeta("UPDATE players SET rating = $2 WHERE username = $1")
+	mock.ExpectExec(update).WithArgs("bob", 1516).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(update).WithArgs("alice", 1484).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := repo.RecordRatedResult("bob", "alice", 1); err != nil {
+		t.Fatalf("RecordRatedResult failed: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT rating FROM players WHERE username = $1")).
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"rating"}).AddRow(1516))
+	if value, err := repo.GetRating("bob"); err != nil || value != 1516 {
+		t.Fatalf("expected rating 1516, got %d %v", value, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetCompletedGame(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock new: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	query := regexp.QuoteMeta("SELECT player1, player2, winner, is_draw, moves, started_at, ended_at, board_rows, board_columns, connect_length, pop_out, winning_cells, termination, base_time_ms, increment_ms, rated, hints FROM games WHERE id = $1")
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"player1", "player2", "winner", "is_draw", "moves", "started_at", "ended_at", "board_rows", "board_columns", "connect_length", "pop_out", "winning_cells", "termination", "base_time_ms", "increment_ms", "rated", "hints"}).
+		AddRow("alice", "bob", "alice", false, []byte(`[{"player":"alice","kind":"DROP","column":3,"moveNumber":1}]`), started, started.Add(time.Minute), 6, 7, 4, false, nil, "CONNECT", int64(180000), int64(0), true, nil)
+
+	mock.ExpectQuery(query).WithArgs("game-123").WillReturnRows(rows)
+	mock.ExpectQuery(query).WithArgs("missing").WillReturnError(sql.ErrNoRows)
+
+	record, err := repo.GetCompletedGame("game-123")
+	if err != nil {
+		t.Fatalf("GetCompletedGame failed: %v", err)
+	}
+	if record.Winner == nil || *record.Winner != "alice" || len(record.Moves) != 1 || record.Moves[0].Column != 3 {
+		t.Fatalf("unexpected game: %+v", record)
+	}
+	if record.Rules != game.DefaultRules() || record.TimeControl.Base != 3*time.Minute || !record.Rated {
+		t.Fatalf("unexpected rules or clock: %+v", record)
+	}
+
+	if _, err := repo.GetCompletedGame("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func strPtr(v string) *string {
 	return &v
 }
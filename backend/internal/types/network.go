@@ -1,21 +1,54 @@
 package types
 
+import "github.com/example/connect-four/backend/internal/game"
+
 // ClientMessage represents messages sent from the frontend to the server.
 type ClientMessage struct {
 	Type     string `json:"type"`
+	Action   string `json:"action,omitempty"`
 	Col      *int   `json:"col,omitempty"`
 	GameID   string `json:"gameId,omitempty"`
 	Username string `json:"username,omitempty"`
+	Accept   *bool  `json:"accept,omitempty"`
 }
 
-// ServerMessage mirrors the frontend contract.
+// ServerMessage mirrors the frontend contract. Times are in milliseconds;
+// TimeLeft holds player 1's then player 2's remaining time in timed games.
 type ServerMessage struct {
-	Type        string  `json:"type"`
-	GameID      string  `json:"gameId,omitempty"`
-	You         int     `json:"you,omitempty"`
-	Opponent    string  `json:"opponent,omitempty"`
-	Board       [][]int `json:"board,omitempty"`
-	CurrentTurn int     `json:"currentTurn,omitempty"`
-	Result      string  `json:"result,omitempty"`
-	Message     string  `json:"message,omitempty"`
+	Type         string      `json:"type"`
+	GameID       string      `json:"gameId,omitempty"`
+	You          int         `json:"you,omitempty"`
+	Opponent     string      `json:"opponent,omitempty"`
+	Board        [][]int     `json:"board,omitempty"`
+	Rows         int         `json:"rows,omitempty"`
+	Columns      int         `json:"columns,omitempty"`
+	Connect      int         `json:"connect,omitempty"`
+	PopOut       bool        `json:"popOut,omitempty"`
+	BaseTime     int64       `json:"baseTime,omitempty"`
+	Increment    int64       `json:"increment,omitempty"`
+	CurrentTurn  int         `json:"currentTurn,omitempty"`
+	TimeLeft     []int64     `json:"timeLeft,omitempty"`
+	Result       string      `json:"result,omitempty"`
+	Reason       string      `json:"reason,omitempty"`
+	WinningCells []game.Cell `json:"winningCells,omitempty"`
+	Message      string      `json:"message,omitempty"`
+	Rated        bool        `json:"rated,omitempty"`
+	BotLevel     string      `json:"botLevel,omitempty"`
+
+	// Evaluations and Suggestion answer a REQUEST_HINT; HintsLeft counts the
+	// player's remaining hints in the game.
+	Evaluations []MoveEvaluation `json:"evaluations,omitempty"`
+	Suggestion  *MoveEvaluation  `json:"suggestion,omitempty"`
+	HintsLeft   *int             `json:"hintsLeft,omitempty"`
+}
+
+// MoveEvaluation is the value of one move for the player making it: a WIN,
+// LOSS or DRAW with best play, with Plies to a won or lost finish, or only a
+// heuristic Score when the outcome is unclear.
+type MoveEvaluation struct {
+	Col     int    `json:"col"`
+	Action  string `json:"action"`
+	Outcome string `json:"outcome,omitempty"`
+	Plies   int    `json:"plies,omitempty"`
+	Score   int    `json:"score"`
 }
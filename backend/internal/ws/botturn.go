@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/types"
+)
+
+const (
+	defaultBotMoveTime = 2 * time.Second
+	// botClockShare is the fraction of its remaining clock the bot may spend
+	// on one move in timed games.
+	botClockShare = 20
+)
+
+// botTurn is the bot thinking about its move in a game against player, the
+// human on the other side. In timed games the bot's clock runs meanwhile.
+type botTurn struct {
+	player  string
+	botName string
+	timed   bool
+	cancel  context.CancelFunc
+}
+
+// startBotTurn lets the bot think about its move off the player's read loop
+// and tells the player with BOT_THINKING. Thinking already under way in the
+// game is cancelled.
+func (h *Handler) startBotTurn(ctx context.Context, gameState *game.Game) {
+	thinkCtx, cancel := context.WithTimeout(context.Background(), h.botMoveTime(gameState))
+	player, botName := gameState.Player1, gameState.Player2
+	if h.botSeat(gameState) == 1 {
+		player, botName = botName, player
+	}
+	turn := &botTurn{player: player, botName: botName, timed: !gameState.TimeControl.Untimed(), cancel: cancel}
+
+	h.botMu.Lock()
+	if h.botTurns == nil {
+		h.botTurns = make(map[string]*botTurn)
+	}
+	if previous, ok := h.botTurns[gameState.ID]; ok {
+		previous.cancel()
+	}
+	h.botTurns[gameState.ID] = turn
+	h.botMu.Unlock()
+
+	sendCtx, cancelSend := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelSend()
+	msg := types.ServerMessage{Type: "BOT_THINKING", GameID: gameState.ID}
+	h.sendToPlayers(sendCtx, gameState, msg, &msg)
+
+	go h.handleBotTurn(thinkCtx, gameState.ID, turn)
+}
+
+// botMoveTime is the thinking budget for the bot's next move in gameState.
+func (h *Handler) botMoveTime(gameState *game.Game) time.Duration {
+	budget := h.BotMoveTime
+	if gameState.TimeControl.Untimed() {
+		return budget
+	}
+	if left, ok := h.GameMgr.TimeLeft(gameState.ID, time.Now().UTC()); ok {
+		budget = min(budget, left[h.botSeat(gameState)-1]/botClockShare+gameState.TimeControl.Increment/2)
+	}
+	return budget
+}
+
+func (h *Handler) handleBotTurn(ctx context.Context, gameID string, turn *botTurn) {
+	defer h.finishBotTurn(gameID, turn)
+
+	botGame, result, _, err := h.Bot.TakeTurn(ctx, gameID)
+	if errors.Is(err, bot.ErrNoMove) {
+		log.Printf("ws: bot forfeits gameID=%s err=%v", gameID, err)
+		botGame, result, err = h.GameMgr.Forfeit(gameID, turn.botName)
+	}
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			log.Printf("ws: bot move error gameID=%s err=%v", gameID, err)
+		}
+		return
+	}
+
+	ctx = context.Background()
+	h.sendBoardUpdate(ctx, botGame)
+	h.handleGameOutcome(ctx, botGame, result)
+}
+
+// finishBotTurn releases turn once the bot has moved or given up.
+func (h *Handler) finishBotTurn(gameID string, turn *botTurn) {
+	turn.cancel()
+
+	h.botMu.Lock()
+	if h.botTurns[gameID] == turn {
+		delete(h.botTurns, gameID)
+	}
+	h.botMu.Unlock()
+}
+
+// cancelBotTurn stops the bot thinking in a game, if it is.
+func (h *Handler) cancelBotTurn(gameID string) {
+	h.botMu.Lock()
+	turn, ok := h.botTurns[gameID]
+	delete(h.botTurns, gameID)
+	h.botMu.Unlock()
+
+	if ok {
+		turn.cancel()
+	}
+}
+
+// cancelBotTurnsFor stops the bot thinking in every game against player, for
+// when the player disconnects. A RECONNECT resumes the bot's turn. In timed
+// games the bot finishes its move instead, since its clock would go on
+// running with nobody to restart it.
+func (h *Handler) cancelBotTurnsFor(player string) {
+	h.botMu.Lock()
+	defer h.botMu.Unlock()
+
+	for gameID, turn := range h.botTurns {
+		if turn.player == player && !turn.timed {
+			turn.cancel()
+			delete(h.botTurns, gameID)
+		}
+	}
+}
+
+// openBotGame lets the bot make the first move of a new game in which it
+// plays first, right after the player has been sent GAME_START.
+func (h *Handler) openBotGame(ctx context.Context, gameState *game.Game) {
+	if h.Bot != nil && gameState.CurrentTurn == h.botSeat(gameState) {
+		h.startBotTurn(ctx, gameState)
+	}
+}
+
+// resumeBotTurn starts the bot thinking again in a game left waiting for its
+// move, such as after the player reconnects.
+func (h *Handler) resumeBotTurn(ctx context.Context, gameID, player string) {
+	if h.Bot == nil || h.GameMgr == nil {
+		return
+	}
+	gameState, ok := h.GameMgr.GetGame(gameID)
+	if !ok || gameState.EndedAt != nil || gameState.CurrentTurn != h.botSeat(gameState) {
+		return
+	}
+	if player != gameState.Player1 && player != gameState.Player2 {
+		return
+	}
+
+	h.botMu.Lock()
+	_, thinking := h.botTurns[gameID]
+	h.botMu.Unlock()
+
+	if !thinking {
+		h.startBotTurn(ctx, gameState)
+	}
+}
+
+// recordBotResult moves the player along the bot ladder after a finished game
+// against the bot. An empty winner is a draw.
+func (h *Handler) recordBotResult(gameState *game.Game, winner string) {
+	seat := h.botSeat(gameState)
+	if h.Ladder == nil || seat == 0 {
+		return
+	}
+
+	player, botName := gameState.Player1, gameState.Player2
+	if seat == 1 {
+		player, botName = botName, player
+	}
+	score := 0.5
+	switch winner {
+	case player:
+		score = 1
+	case botName:
+		score = 0
+	}
+
+	level := h.Ladder.Record(player, botName, score)
+	log.Printf("ws: bot level for %s is now %s after game %s", player, level, gameState.ID)
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,7 +33,33 @@ type Handler struct {
 	GameMgr    *game.GameManager
 	Matchmaker *matchmaking.Matchmaker
 	Bot        *bot.Bot
+	Bots       *bot.Registry
 	Store      ResultStore
+
+	// UndoTimeout is how long an opponent has to answer a takeback request.
+	UndoTimeout time.Duration
+	// BotTakebackLimit caps the takebacks a player is granted against the bot.
+	BotTakebackLimit int
+	// BotMoveTime is how long the bot may think about a move. Timed games
+	// also keep it to a share of the bot's remaining clock.
+	BotMoveTime time.Duration
+	// HintsPerGame is how many hints each player may ask for in an unrated
+	// game, and HintTime how long the engine searches for each.
+	HintsPerGame int
+	HintTime     time.Duration
+	// Analyzer, if set, reviews each finished game once it is saved.
+	Analyzer AnalysisQueue
+	// Ladder, if set, adapts the bot's level to each player's results.
+	Ladder *bot.Ladder
+	// Ratings, if set, updates both players' ratings after each rated game.
+	Ratings RatingRecorder
+
+	offersMu     sync.Mutex
+	pendingUndos map[string]*undoRequest
+	drawOffers   map[string]string
+
+	botMu    sync.Mutex
+	botTurns map[string]*botTurn
 }
 
 // ResultStore defines the persistence operations required by the handler.
@@ -40,9 +69,48 @@ type ResultStore interface {
 	SaveCompletedGame(game *store.CompletedGame) error
 }
 
-// NewHandler constructs a Handler.
+// RatingRecorder updates players' ratings with the result of a rated game.
+// score is Player1's: 1 for a win, a half for a draw and 0 for a loss.
+type RatingRecorder interface {
+	RecordRatedResult(player1, player2 string, score float64) error
+}
+
+// AnalysisQueue schedules completed games for background analysis.
+type AnalysisQueue interface {
+	Enqueue(record *store.CompletedGame)
+}
+
+// NewHandler constructs a Handler. Bots are recognised through botEngine's
+// registry, or the default registry without an engine.
 func NewHandler(manager *Manager, gameMgr *game.GameManager, matchmaker *matchmaking.Matchmaker, botEngine *bot.Bot, store ResultStore) *Handler {
-	return &Handler{Manager: manager, GameMgr: gameMgr, Matchmaker: matchmaker, Bot: botEngine, Store: store}
+	bots := bot.DefaultRegistry()
+	if botEngine != nil && botEngine.Registry() != nil {
+		bots = botEngine.Registry()
+	}
+
+	h := &Handler{
+		Manager:          manager,
+		GameMgr:          gameMgr,
+		Matchmaker:       matchmaker,
+		Bot:              botEngine,
+		Bots:             bots,
+		Store:            store,
+		UndoTimeout:      defaultUndoTimeout,
+		BotTakebackLimit: defaultBotTakebackLimit,
+		BotMoveTime:      defaultBotMoveTime,
+		HintsPerGame:     defaultHintsPerGame,
+		HintTime:         defaultHintTime,
+		pendingUndos:     make(map[string]*undoRequest),
+		drawOffers:       make(map[string]string),
+		botTurns:         make(map[string]*botTurn),
+	}
+	if gameMgr != nil {
+		gameMgr.NotifyTimeouts(h.handleTimeout)
+	}
+	if matchmaker != nil {
+		matchmaker.NotifyBotGames(h.openBotGame)
+	}
+	return h
 }
 
 // RegisterRoutes wires the websocket endpoint.
@@ -56,6 +124,42 @@ func (h *Handler) accept(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "username query parameter is required"})
 		return
 	}
+	if h.isBot(username) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "username is reserved for a bot"})
+		return
+	}
+
+	rules, err := rulesFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	clock, err := timeControlFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	botName, err := h.botFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	opener, err := matchmaking.ParseOpener(c.Query("opener"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rated := false
+	if raw := c.Query("rated"); raw != "" {
+		if rated, err = strconv.ParseBool(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rated must be true or false"})
+			return
+		}
+	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -77,15 +181,120 @@ func (h *Handler) accept(c *gin.Context) {
 	}
 
 	if h.Matchmaker != nil {
-		h.Matchmaker.Enqueue(username)
+		h.Matchmaker.Enqueue(username, matchmaking.Preferences{Rules: rules, TimeControl: clock, Bot: botName, Opener: opener, Rated: rated})
 	}
 
 	go h.listen(ctx, cancel, client)
 }
 
+// rulesFromQuery reads optional rows, columns, connect and variant parameters,
+// falling back to the classic board.
+func rulesFromQuery(c *gin.Context) (game.Rules, error) {
+	rules := game.DefaultRules()
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{"rows", &rules.Rows},
+		{"columns", &rules.Columns},
+		{"connect", &rules.Connect},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return game.Rules{}, fmt.Errorf("%s must be an integer", param.name)
+		}
+		*param.value = parsed
+	}
+
+	switch c.Query("variant") {
+	case "", "classic":
+	case "popout":
+		rules.PopOut = true
+	default:
+		return game.Rules{}, errors.New("variant must be classic or popout")
+	}
+
+	if err := rules.Validate(); err != nil {
+		return game.Rules{}, err
+	}
+	return rules, nil
+}
+
+// timeControlFromQuery reads optional base and increment parameters, both in
+// seconds. Without a base time the game is untimed.
+func timeControlFromQuery(c *gin.Context) (game.TimeControl, error) {
+	var clock game.TimeControl
+	for _, param := range []struct {
+		name  string
+		value *time.Duration
+	}{
+		{"base", &clock.Base},
+		{"increment", &clock.Increment},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		seconds, err := strconv.Atoi(raw)
+		if err != nil {
+			return game.TimeControl{}, fmt.Errorf("%s must be a whole number of seconds", param.name)
+		}
+		*param.value = time.Duration(seconds) * time.Second
+	}
+
+	if err := clock.Validate(); err != nil {
+		return game.TimeControl{}, err
+	}
+	return clock, nil
+}
+
+// botFromQuery reads the optional bot to fall back to, either by name or by
+// level. An empty result leaves the choice to the matchmaker.
+func (h *Handler) botFromQuery(c *gin.Context) (string, error) {
+	name, levelName := c.Query("bot"), c.Query("level")
+	switch {
+	case name != "" && levelName != "":
+		return "", errors.New("choose either a bot or a level")
+	case name != "":
+		if !h.isBot(name) {
+			return "", fmt.Errorf("unknown bot %q", name)
+		}
+		return name, nil
+	case levelName != "":
+		level, err := bot.ParseLevel(levelName)
+		if err != nil {
+			return "", err
+		}
+		return bot.NameFor(level), nil
+	}
+	return "", nil
+}
+
+// isBot reports whether username belongs to a registered bot.
+func (h *Handler) isBot(username string) bool {
+	return h.Bots != nil && h.Bots.IsBot(username)
+}
+
+// botSeat returns which player, 1 or 2, the bot is in gameState, or 0 if
+// neither player is a bot.
+func (h *Handler) botSeat(gameState *game.Game) int {
+	switch {
+	case h.isBot(gameState.Player1):
+		return 1
+	case h.isBot(gameState.Player2):
+		return 2
+	}
+	return 0
+}
+
 func (h *Handler) listen(ctx context.Context, cancel context.CancelFunc, conn *Connection) {
 	defer func() {
 		cancel()
+		h.cancelBotTurnsFor(conn.Username)
 		h.Manager.Unregister(conn)
 		_ = conn.Socket.Close()
 	}()
@@ -127,6 +336,20 @@ func (h *Handler) handleMessage(ctx context.Context, conn *Connection, msg types
 		return h.handleMakeMove(ctx, conn, msg)
 	case "RECONNECT":
 		return h.handleReconnect(ctx, conn, msg)
+	case "UNDO_REQUEST":
+		return h.handleUndoRequest(ctx, conn, msg)
+	case "UNDO_RESPONSE":
+		return h.handleUndoResponse(ctx, conn, msg)
+	case "RESIGN":
+		return h.handleEnd(ctx, conn, msg, (*game.GameManager).Resign)
+	case "ABORT":
+		return h.handleEnd(ctx, conn, msg, (*game.GameManager).Abort)
+	case "OFFER_DRAW":
+		return h.handleOfferDraw(ctx, conn, msg)
+	case "ACCEPT_DRAW", "DECLINE_DRAW":
+		return h.handleDrawResponse(ctx, conn, msg)
+	case "REQUEST_HINT":
+		return h.handleHintRequest(ctx, conn, msg)
 	default:
 		return errors.New("unsupported message type")
 	}
@@ -140,7 +363,7 @@ func (h *Handler) handleMakeMove(ctx context.Context, conn *Connection, msg type
 		return errors.New("MAKE_MOVE missing gameId")
 	}
 
-	log.Printf("ws: MAKE_MOVE id=%s username=%s gameId=%s col=%d", conn.ID, conn.Username, msg.GameID, *msg.Col)
+	log.Printf("ws: MAKE_MOVE id=%s username=%s gameId=%s action=%s col=%d", conn.ID, conn.Username, msg.GameID, msg.Action, *msg.Col)
 
 	if h.GameMgr == nil {
 		return errors.New("game manager unavailable")
@@ -150,16 +373,23 @@ func (h *Handler) handleMakeMove(ctx context.Context, conn *Connection, msg type
 		return errors.New("unknown game")
 	}
 
-	updatedGame, result, err := h.GameMgr.ApplyMove(msg.GameID, conn.Username, *msg.Col)
+	kind := game.DROP
+	if msg.Action != "" {
+		kind = game.MoveKind(msg.Action)
+	}
+
+	updatedGame, result, err := h.GameMgr.ApplyMove(msg.GameID, conn.Username, kind, *msg.Col)
 	if err != nil {
 		return h.sendInfo(ctx, conn, err.Error())
 	}
 
+	h.cancelUndo(ctx, updatedGame)
+	h.withdrawDrawOffer(updatedGame.ID)
 	h.sendBoardUpdate(ctx, updatedGame)
-	h.handleGameOutcome(ctx, updatedGame, conn.Username, result)
+	h.handleGameOutcome(ctx, updatedGame, result)
 
-	if result == game.CONTINUE && updatedGame.Player2 == "BOT" && h.Bot != nil && updatedGame.CurrentTurn == 2 {
-		h.handleBotTurn(ctx, updatedGame)
+	if result == game.CONTINUE && h.Bot != nil && updatedGame.CurrentTurn == h.botSeat(updatedGame) {
+		h.startBotTurn(ctx, updatedGame)
 	}
 
 	return nil
@@ -171,7 +401,13 @@ func (h *Handler) handleReconnect(ctx context.Context, conn *Connection, msg typ
 	}
 
 	log.Printf("ws: RECONNECT request username=%s gameId=%s", msg.Username, msg.GameID)
-	return h.sendInfo(ctx, conn, "Reconnect acknowledged")
+	if err := h.sendInfo(ctx, conn, "Reconnect acknowledged"); err != nil {
+		return err
+	}
+	if msg.GameID != "" && msg.Username == conn.Username {
+		h.resumeBotTurn(ctx, msg.GameID, conn.Username)
+	}
+	return nil
 }
 
 func (h *Handler) sendInfo(ctx context.Context, conn *Connection, message string) error {
@@ -181,15 +417,24 @@ func (h *Handler) sendInfo(ctx context.Context, conn *Connection, message string
 	return h.Manager.Send(sendCtx, conn, types.ServerMessage{Type: "INFO", Message: message})
 }
 
-func (h *Handler) handleGameOutcome(ctx context.Context, gameState *game.Game, mover string, result game.MoveResult) {
+// handleGameOutcome announces a finished game. The winner is read from the game
+// because a PopOut pop can hand the win to the opponent of the mover. Games
+// ended without a winner are draws unless they were aborted. A bot still
+// thinking about the game stops.
+func (h *Handler) handleGameOutcome(ctx context.Context, gameState *game.Game, result game.MoveResult) {
 	sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	switch result {
+	case game.WIN, game.DRAW, game.ABORTED:
+		h.cancelBotTurn(gameState.ID)
+	}
+
 	switch result {
 	case game.WIN:
-		h.sendGameOver(sendCtx, gameState, mover, false)
-	case game.DRAW:
-		h.sendGameOver(sendCtx, gameState, "", true)
+		h.sendGameOver(sendCtx, gameState, *gameState.Winner, false)
+	case game.DRAW, game.ABORTED:
+		h.sendGameOver(sendCtx, gameState, "", result == game.DRAW)
 	}
 }
 
@@ -203,20 +448,34 @@ func (h *Handler) sendBoardUpdate(ctx context.Context, gameState *game.Game) {
 		Board:       gameState.Board,
 		CurrentTurn: gameState.CurrentTurn,
 	}
+	if !gameState.TimeControl.Untimed() {
+		if left, ok := h.GameMgr.TimeLeft(gameState.ID, time.Now().UTC()); ok {
+			msg.TimeLeft = []int64{left[0].Milliseconds(), left[1].Milliseconds()}
+		}
+	}
 
 	h.sendToPlayers(sendCtx, gameState, msg, &msg)
 }
 
 func (h *Handler) sendGameOver(ctx context.Context, gameState *game.Game, winner string, draw bool) {
-	if draw {
-		msg := types.ServerMessage{Type: "GAME_OVER", GameID: gameState.ID, Board: gameState.Board, Result: "DRAW"}
+	reason := string(gameState.Termination)
+
+	if winner == "" {
+		outcome := "ABORTED"
+		if draw {
+			outcome = "DRAW"
+		}
+		msg := types.ServerMessage{Type: "GAME_OVER", GameID: gameState.ID, Board: gameState.Board, Result: outcome, Reason: reason}
 		h.sendToPlayers(ctx, gameState, msg, &msg)
-		h.schedulePersistence(gameState, true)
+		if draw {
+			h.recordBotResult(gameState, "")
+		}
+		h.schedulePersistence(gameState, draw)
 		return
 	}
 
-	msgP1 := types.ServerMessage{Type: "GAME_OVER", GameID: gameState.ID, Board: gameState.Board, Result: "LOSS"}
-	msgP2 := types.ServerMessage{Type: "GAME_OVER", GameID: gameState.ID, Board: gameState.Board, Result: "LOSS"}
+	msgP1 := types.ServerMessage{Type: "GAME_OVER", GameID: gameState.ID, Board: gameState.Board, Result: "LOSS", Reason: reason, WinningCells: gameState.WinningCells}
+	msgP2 := types.ServerMessage{Type: "GAME_OVER", GameID: gameState.ID, Board: gameState.Board, Result: "LOSS", Reason: reason, WinningCells: gameState.WinningCells}
 
 	if winner == gameState.Player1 {
 		msgP1.Result = "WIN"
@@ -224,28 +483,27 @@ func (h *Handler) sendGameOver(ctx context.Context, gameState *game.Game, winner
 		msgP2.Result = "WIN"
 	}
 
-	if gameState.Player2 == "BOT" {
-		// Only notify the human player.
-		h.sendToPlayers(ctx, gameState, msgP1, nil)
-		h.schedulePersistence(gameState, false)
-		return
-	}
-
 	h.sendToPlayers(ctx, gameState, msgP1, &msgP2)
+	h.recordBotResult(gameState, winner)
 	h.schedulePersistence(gameState, false)
 }
 
+// sendToPlayers sends msgP1 to player 1 and, unless it is nil, msgP2 to
+// player 2. Bots are skipped.
 func (h *Handler) sendToPlayers(ctx context.Context, gameState *game.Game, msgP1 types.ServerMessage, msgP2 *types.ServerMessage) {
-	if err := h.Manager.SendToUsername(ctx, gameState.Player1, msgP1); err != nil {
-		log.Printf("ws: failed to send to %s: %v", gameState.Player1, err)
-	}
-
-	if gameState.Player2 == "BOT" || msgP2 == nil {
-		return
-	}
-
-	if err := h.Manager.SendToUsername(ctx, gameState.Player2, *msgP2); err != nil {
-		log.Printf("ws: failed to send to %s: %v", gameState.Player2, err)
+	for _, entry := range []struct {
+		username string
+		msg      *types.ServerMessage
+	}{
+		{gameState.Player1, &msgP1},
+		{gameState.Player2, msgP2},
+	} {
+		if entry.msg == nil || h.isBot(entry.username) {
+			continue
+		}
+		if err := h.Manager.SendToUsername(ctx, entry.username, *entry.msg); err != nil {
+			log.Printf("ws: failed to send to %s: %v", entry.username, err)
+		}
 	}
 }
 
@@ -255,12 +513,16 @@ func (h *Handler) schedulePersistence(gameState *game.Game, draw bool) {
 	}
 
 	record := store.CompletedGame{
-		ID:        gameState.ID,
-		Player1:   gameState.Player1,
-		Player2:   gameState.Player2,
-		IsDraw:    draw,
-		Moves:     make([]store.CompletedMove, len(gameState.Moves)),
-		StartedAt: gameState.CreatedAt,
+		ID:          gameState.ID,
+		Player1:     gameState.Player1,
+		Player2:     gameState.Player2,
+		Rules:       gameState.Rules,
+		TimeControl: gameState.TimeControl,
+		IsDraw:      draw,
+		Termination: string(gameState.Termination),
+		Moves:       make([]store.CompletedMove, len(gameState.Moves)),
+		Rated:       gameState.Rated,
+		StartedAt:   gameState.CreatedAt,
 	}
 
 	if gameState.EndedAt != nil {
@@ -274,10 +536,14 @@ func (h *Handler) schedulePersistence(gameState *game.Game, draw bool) {
 	if gameState.Winner != nil {
 		winner := *gameState.Winner
 		record.Winner = &winner
+		record.WinningCells = append([]game.Cell(nil), gameState.WinningCells...)
 	}
 
 	for i, mv := range gameState.Moves {
-		record.Moves[i] = store.CompletedMove{Player: mv.Player, Column: mv.Column, MoveNumber: mv.MoveNumber}
+		record.Moves[i] = store.CompletedMove{Player: mv.Player, Kind: string(mv.Kind), Column: mv.Column, MoveNumber: mv.MoveNumber, PlayedAt: mv.PlayedAt, Undone: mv.Undone}
+	}
+	for _, hint := range gameState.Hints {
+		record.Hints = append(record.Hints, store.CompletedHint{Player: hint.Player, MoveNumber: hint.MoveNumber, RequestedAt: hint.RequestedAt})
 	}
 
 	recordCopy := record
@@ -289,6 +555,14 @@ func (h *Handler) persistResult(record store.CompletedGame) {
 		return
 	}
 
+	// Aborted games are kept on record but leave the leaderboard untouched.
+	if record.Termination == string(game.TerminationAbort) {
+		if err := h.Store.SaveCompletedGame(&record); err != nil {
+			log.Printf("ws: save completed game %s failed: %v", record.ID, err)
+		}
+		return
+	}
+
 	if record.Player1 != "" {
 		if err := h.Store.EnsurePlayer(record.Player1); err != nil {
 			log.Printf("ws: ensure player %s failed: %v", record.Player1, err)
@@ -307,31 +581,32 @@ func (h *Handler) persistResult(record store.CompletedGame) {
 		}
 	}
 
-	if err := h.Store.SaveCompletedGame(&record); err != nil {
-		log.Printf("ws: save completed game %s failed: %v", record.ID, err)
+	if record.Rated && h.Ratings != nil {
+		score := 0.5
+		if record.Winner != nil {
+			score = 0
+			if *record.Winner == record.Player1 {
+				score = 1
+			}
+		}
+		if err := h.Ratings.RecordRatedResult(record.Player1, record.Player2, score); err != nil {
+			log.Printf("ws: record rated result %s failed: %v", record.ID, err)
+		}
 	}
-}
 
-func (h *Handler) handleBotTurn(ctx context.Context, currentGame *game.Game) {
-	if h.Bot == nil {
+	if err := h.Store.SaveCompletedGame(&record); err != nil {
+		log.Printf("ws: save completed game %s failed: %v", record.ID, err)
 		return
 	}
-
-	botGame, result, _, err := h.Bot.TakeTurn(currentGame.ID)
-	if err != nil {
-		log.Printf("ws: bot move error gameID=%s err=%v", currentGame.ID, err)
-		return
+	if h.Analyzer != nil {
+		h.Analyzer.Enqueue(&record)
 	}
+}
 
-	sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	h.sendBoardUpdate(sendCtx, botGame)
-
-	switch result {
-	case game.WIN:
-		h.sendGameOver(sendCtx, botGame, botGame.Player2, false)
-	case game.DRAW:
-		h.sendGameOver(sendCtx, botGame, "", true)
-	}
+// handleTimeout announces a game lost on time while nobody was moving.
+func (h *Handler) handleTimeout(gameState *game.Game) {
+	ctx := context.Background()
+	h.cancelUndo(ctx, gameState)
+	h.withdrawDrawOffer(gameState.ID)
+	h.handleGameOutcome(ctx, gameState, game.WIN)
 }
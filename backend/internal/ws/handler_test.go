@@ -3,6 +3,7 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
@@ -68,6 +69,24 @@ func (m *mockResultStore) waitForSave(timeout time.Duration) bool {
 		return false
 	}
 }
+
+// analysisRecorder collects the ids of games queued for analysis.
+type analysisRecorder chan string
+
+func (q analysisRecorder) Enqueue(record *store.CompletedGame) {
+	q <- record.ID
+}
+
+func createGame(t *testing.T, gm *game.GameManager, player1, player2 string) *game.Game {
+	t.Helper()
+
+	g, err := gm.CreateGame(player1, player2, game.DefaultRules(), game.TimeControl{})
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	return g
+}
+
 func TestWebSocketMakeMoveBoardUpdate(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -77,7 +96,7 @@ func TestWebSocketMakeMoveBoardUpdate(t *testing.T) {
 	handler := NewHandler(manager, gameManager, nil, nil, nil)
 	handler.RegisterRoutes(r)
 
-	created := gameManager.CreateGame("tester", "opponent")
+	created := createGame(t, gameManager, "tester", "opponent")
 
 	ts := httptest.NewServer(r)
 	t.Cleanup(ts.Close)
@@ -142,23 +161,23 @@ func TestWebSocketMakeMoveGameOver(t *testing.T) {
 	handler := NewHandler(manager, gameManager, nil, nil, nil)
 	handler.RegisterRoutes(r)
 
-	created := gameManager.CreateGame("tester", "opponent")
-	if _, _, err := gameManager.ApplyMove(created.ID, "tester", 0); err != nil {
+	created := createGame(t, gameManager, "tester", "opponent")
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 0); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", 1); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", game.DROP, 1); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "tester", 0); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 0); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", 1); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", game.DROP, 1); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "tester", 0); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 0); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", 1); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", game.DROP, 1); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
 
@@ -214,6 +233,14 @@ func TestWebSocketMakeMoveGameOver(t *testing.T) {
 	if gameOver.Result != "WIN" {
 		t.Fatalf("expected WIN result, got %s", gameOver.Result)
 	}
+	if len(gameOver.WinningCells) != 4 {
+		t.Fatalf("expected 4 winning cells, got %v", gameOver.WinningCells)
+	}
+	for _, cell := range gameOver.WinningCells {
+		if cell.Col != 0 {
+			t.Fatalf("expected winning line in column 0, got %v", gameOver.WinningCells)
+		}
+	}
 }
 
 func TestWebSocketBotAutoResponds(t *testing.T) {
@@ -222,11 +249,11 @@ func TestWebSocketBotAutoResponds(t *testing.T) {
 	r := gin.New()
 	manager := NewManager()
 	gameManager := game.NewManager()
-	botEngine := bot.New(gameManager)
+	botEngine := bot.New(gameManager, bot.DefaultRegistry())
 	handler := NewHandler(manager, gameManager, nil, botEngine, nil)
 	handler.RegisterRoutes(r)
 
-	created := gameManager.CreateGame("tester", "BOT")
+	created := createGame(t, gameManager, "tester", bot.NameFor(bot.Expert))
 
 	ts := httptest.NewServer(r)
 	t.Cleanup(ts.Close)
@@ -266,6 +293,10 @@ func TestWebSocketBotAutoResponds(t *testing.T) {
 		t.Fatalf("expected BOARD_UPDATE, got %s", first.Type)
 	}
 
+	if thinking := readWithDeadline(); thinking.Type != "BOT_THINKING" || thinking.GameID != created.ID {
+		t.Fatalf("expected BOT_THINKING, got %+v", thinking)
+	}
+
 	second := readWithDeadline()
 	if second.Type != "BOARD_UPDATE" {
 		t.Fatalf("expected second BOARD_UPDATE, got %s", second.Type)
@@ -278,6 +309,46 @@ func TestWebSocketBotAutoResponds(t *testing.T) {
 	}
 }
 
+func TestWebSocketBotOpensGame(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, bot.New(gameManager, bot.DefaultRegistry()), nil)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, bot.NameFor(bot.Expert), "tester")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	conn := dialPlayer(t, ts, "tester")
+	handler.openBotGame(context.Background(), created)
+
+	if thinking := readServerMessage(t, conn); thinking.Type != "BOT_THINKING" || thinking.GameID != created.ID {
+		t.Fatalf("expected BOT_THINKING, got %+v", thinking)
+	}
+	opening := readServerMessage(t, conn)
+	if opening.Type != "BOARD_UPDATE" || opening.CurrentTurn != 2 || opening.Board[game.Rows-1][3] != 1 {
+		t.Fatalf("expected the bot to open in the centre, got %+v", opening)
+	}
+
+	if err := conn.WriteJSON(map[string]any{"type": "MAKE_MOVE", "gameId": created.ID, "col": 3}); err != nil {
+		t.Fatalf("write MAKE_MOVE: %v", err)
+	}
+	if update := readServerMessage(t, conn); update.Type != "BOARD_UPDATE" || update.CurrentTurn != 1 {
+		t.Fatalf("expected the bot to be on move, got %+v", update)
+	}
+	if thinking := readServerMessage(t, conn); thinking.Type != "BOT_THINKING" {
+		t.Fatalf("expected BOT_THINKING, got %+v", thinking)
+	}
+	if reply := readServerMessage(t, conn); reply.Type != "BOARD_UPDATE" || reply.CurrentTurn != 2 {
+		t.Fatalf("expected the bot to reply, got %+v", reply)
+	}
+}
+
 func TestGameOverPersistsResults(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -285,26 +356,28 @@ func TestGameOverPersistsResults(t *testing.T) {
 	manager := NewManager()
 	gameManager := game.NewManager()
 	mockStore := newMockResultStore()
+	queued := make(analysisRecorder, 1)
 	handler := NewHandler(manager, gameManager, nil, nil, mockStore)
+	handler.Analyzer = queued
 	handler.RegisterRoutes(r)
 
-	created := gameManager.CreateGame("tester", "opponent")
-	if _, _, err := gameManager.ApplyMove(created.ID, "tester", 0); err != nil {
+	created := createGame(t, gameManager, "tester", "opponent")
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 0); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", 1); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", game.DROP, 1); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "tester", 0); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 0); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", 1); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", game.DROP, 1); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "tester", 0); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 0); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
-	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", 1); err != nil {
+	if _, _, err := gameManager.ApplyMove(created.ID, "opponent", game.DROP, 1); err != nil {
 		t.Fatalf("setup move: %v", err)
 	}
 
@@ -369,6 +442,18 @@ func TestGameOverPersistsResults(t *testing.T) {
 	if len(saved.Moves) == 0 {
 		t.Fatalf("expected moves recorded")
 	}
+	if len(saved.WinningCells) != 4 {
+		t.Fatalf("expected winning cells persisted, got %v", saved.WinningCells)
+	}
+
+	select {
+	case id := <-queued:
+		if id != created.ID {
+			t.Fatalf("expected game %s queued for analysis, got %s", created.ID, id)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the saved game to be queued for analysis")
+	}
 
 	if len(ensures) < 2 {
 		t.Fatalf("expected EnsurePlayer called for both players, got %v", ensures)
@@ -391,3 +476,697 @@ func TestGameOverPersistsResults(t *testing.T) {
 		t.Fatalf("expected increment for tester, got %v", increments)
 	}
 }
+
+func TestWebSocketRejectsInvalidRules(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	handler := NewHandler(NewManager(), game.NewManager(), nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws?username=tester&rows=2&columns=3", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+}
+
+func dialPlayer(t *testing.T, ts *httptest.Server, username string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws?username=" + username
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s failed: %v", username, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if welcome := readServerMessage(t, conn); welcome.Type != "INFO" {
+		t.Fatalf("expected INFO welcome, got %s", welcome.Type)
+	}
+	return conn
+}
+
+func readServerMessage(t *testing.T, conn *websocket.Conn) types.ServerMessage {
+	t.Helper()
+
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("set read deadline: %v", err)
+	}
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	var msg types.ServerMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("decode message: %v", err)
+	}
+	return msg
+}
+
+func TestWebSocketTakebackWithConsent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 3); err != nil {
+		t.Fatalf("setup move: %v", err)
+	}
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+	opponent := dialPlayer(t, ts, "opponent")
+
+	if err := tester.WriteJSON(map[string]any{"type": "UNDO_REQUEST", "gameId": created.ID}); err != nil {
+		t.Fatalf("write UNDO_REQUEST: %v", err)
+	}
+	prompt := readServerMessage(t, opponent)
+	if prompt.Type != "UNDO_REQUEST" || prompt.Opponent != "tester" {
+		t.Fatalf("expected takeback prompt from tester, got %+v", prompt)
+	}
+
+	if err := opponent.WriteJSON(map[string]any{"type": "UNDO_RESPONSE", "gameId": created.ID, "accept": true}); err != nil {
+		t.Fatalf("write UNDO_RESPONSE: %v", err)
+	}
+	for _, conn := range []*websocket.Conn{tester, opponent} {
+		result := readServerMessage(t, conn)
+		if result.Type != "UNDO_RESULT" || result.Result != "ACCEPTED" {
+			t.Fatalf("expected accepted takeback, got %+v", result)
+		}
+		update := readServerMessage(t, conn)
+		if update.Type != "BOARD_UPDATE" || update.CurrentTurn != 1 || update.Board[game.Rows-1][3] != 0 {
+			t.Fatalf("expected empty board with tester to move, got %+v", update)
+		}
+	}
+
+	state, _ := gameManager.GetGame(created.ID)
+	if state.TakebacksBy("tester") != 1 {
+		t.Fatalf("expected takeback in move history, got %+v", state.Moves)
+	}
+}
+
+func TestWebSocketTakebackRequestExpires(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.UndoTimeout = 50 * time.Millisecond
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+	if _, _, err := gameManager.ApplyMove(created.ID, "tester", game.DROP, 3); err != nil {
+		t.Fatalf("setup move: %v", err)
+	}
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+
+	if err := tester.WriteJSON(map[string]any{"type": "UNDO_REQUEST", "gameId": created.ID}); err != nil {
+		t.Fatalf("write UNDO_REQUEST: %v", err)
+	}
+	result := readServerMessage(t, tester)
+	if result.Type != "UNDO_RESULT" || result.Result != "EXPIRED" {
+		t.Fatalf("expected expired takeback, got %+v", result)
+	}
+
+	state, _ := gameManager.GetGame(created.ID)
+	if state.Board[game.Rows-1][3] != 1 || state.TakebacksBy("tester") != 0 {
+		t.Fatalf("expected board to be unchanged after expiry")
+	}
+}
+
+func TestWebSocketBotTakebackLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, bot.New(gameManager, bot.DefaultRegistry()), nil)
+	handler.BotTakebackLimit = 1
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "BOT")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	conn := dialPlayer(t, ts, "tester")
+
+	for attempt, want := range []string{"ACCEPTED", "DECLINED"} {
+		if err := conn.WriteJSON(map[string]any{"type": "MAKE_MOVE", "gameId": created.ID, "col": 0}); err != nil {
+			t.Fatalf("write MAKE_MOVE: %v", err)
+		}
+		_ = readServerMessage(t, conn) // own move
+		_ = readServerMessage(t, conn) // bot thinking
+		_ = readServerMessage(t, conn) // bot reply
+
+		if err := conn.WriteJSON(map[string]any{"type": "UNDO_REQUEST", "gameId": created.ID}); err != nil {
+			t.Fatalf("write UNDO_REQUEST: %v", err)
+		}
+		result := readServerMessage(t, conn)
+		if result.Type != "UNDO_RESULT" || result.Result != want {
+			t.Fatalf("attempt %d: expected %s, got %+v", attempt+1, want, result)
+		}
+		if want == "ACCEPTED" {
+			update := readServerMessage(t, conn)
+			if update.Type != "BOARD_UPDATE" || update.Board[game.Rows-1][0] != 0 || update.Board[game.Rows-1][3] != 0 {
+				t.Fatalf("expected both plies taken back, got %+v", update)
+			}
+		}
+	}
+}
+
+func TestWebSocketBotResultMovesLadder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.Ladder = bot.NewLadder(nil)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", bot.NameFor(bot.DefaultLevel))
+	for _, mv := range []struct {
+		player string
+		col    int
+	}{{"tester", 0}, {bot.NameFor(bot.DefaultLevel), 6}} {
+		if _, _, err := gameManager.ApplyMove(created.ID, mv.player, game.DROP, mv.col); err != nil {
+			t.Fatalf("move by %s: %v", mv.player, err)
+		}
+	}
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	conn := dialPlayer(t, ts, "tester")
+	if err := conn.WriteJSON(map[string]any{"type": "RESIGN", "gameId": created.ID}); err != nil {
+		t.Fatalf("write RESIGN: %v", err)
+	}
+	if gameOver := readServerMessage(t, conn); gameOver.Type != "GAME_OVER" || gameOver.Result != "LOSS" {
+		t.Fatalf("expected a loss by resignation, got %+v", gameOver)
+	}
+
+	if level := handler.Ladder.Level("tester"); level != bot.Easy {
+		t.Fatalf("expected the loss to lower the bot to easy, got %s", level)
+	}
+}
+
+func TestWebSocketResignSendsReason(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	mockStore := newMockResultStore()
+	handler := NewHandler(manager, gameManager, nil, nil, mockStore)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+	opponent := dialPlayer(t, ts, "opponent")
+
+	if err := tester.WriteJSON(map[string]any{"type": "RESIGN", "gameId": created.ID}); err != nil {
+		t.Fatalf("write RESIGN: %v", err)
+	}
+	for conn, want := range map[*websocket.Conn]string{tester: "LOSS", opponent: "WIN"} {
+		gameOver := readServerMessage(t, conn)
+		if gameOver.Type != "GAME_OVER" || gameOver.Result != want || gameOver.Reason != "RESIGNATION" {
+			t.Fatalf("expected %s by resignation, got %+v", want, gameOver)
+		}
+	}
+
+	if !mockStore.waitForSave(2 * time.Second) {
+		t.Fatalf("persistence did not complete in time")
+	}
+	mockStore.mu.Lock()
+	defer mockStore.mu.Unlock()
+	if saved := mockStore.saved[0]; saved.Termination != "RESIGNATION" || saved.Winner == nil || *saved.Winner != "opponent" {
+		t.Fatalf("expected resignation persisted, got %+v", saved)
+	}
+}
+
+func TestWebSocketDrawOfferAccepted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+	opponent := dialPlayer(t, ts, "opponent")
+
+	if err := tester.WriteJSON(map[string]any{"type": "ACCEPT_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write ACCEPT_DRAW: %v", err)
+	}
+	if info := readServerMessage(t, tester); info.Type != "INFO" {
+		t.Fatalf("expected accepting a missing offer to be refused, got %+v", info)
+	}
+
+	if err := tester.WriteJSON(map[string]any{"type": "OFFER_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write OFFER_DRAW: %v", err)
+	}
+	if offer := readServerMessage(t, opponent); offer.Type != "DRAW_OFFER" || offer.Opponent != "tester" {
+		t.Fatalf("expected draw offer from tester, got %+v", offer)
+	}
+
+	if err := opponent.WriteJSON(map[string]any{"type": "ACCEPT_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write ACCEPT_DRAW: %v", err)
+	}
+	for _, conn := range []*websocket.Conn{tester, opponent} {
+		gameOver := readServerMessage(t, conn)
+		if gameOver.Type != "GAME_OVER" || gameOver.Result != "DRAW" || gameOver.Reason != "AGREEMENT" {
+			t.Fatalf("expected draw by agreement, got %+v", gameOver)
+		}
+	}
+}
+
+func TestWebSocketDrawOfferIgnoresOutsiders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+	opponent := dialPlayer(t, ts, "opponent")
+	outsider := dialPlayer(t, ts, "outsider")
+
+	if err := tester.WriteJSON(map[string]any{"type": "OFFER_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write OFFER_DRAW: %v", err)
+	}
+	if offer := readServerMessage(t, opponent); offer.Type != "DRAW_OFFER" {
+		t.Fatalf("expected draw offer, got %+v", offer)
+	}
+
+	if err := outsider.WriteJSON(map[string]any{"type": "DECLINE_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write DECLINE_DRAW: %v", err)
+	}
+	if info := readServerMessage(t, outsider); info.Type != "INFO" {
+		t.Fatalf("expected an outsider's answer to be refused, got %+v", info)
+	}
+
+	if err := opponent.WriteJSON(map[string]any{"type": "ACCEPT_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write ACCEPT_DRAW: %v", err)
+	}
+	if gameOver := readServerMessage(t, tester); gameOver.Type != "GAME_OVER" || gameOver.Reason != "AGREEMENT" {
+		t.Fatalf("expected the offer to survive the outsider, got %+v", gameOver)
+	}
+}
+
+func TestWebSocketDrawOfferRefusedAfterGameOver(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+	if _, _, err := gameManager.Resign(created.ID, "opponent"); err != nil {
+		t.Fatalf("resign: %v", err)
+	}
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+	if err := tester.WriteJSON(map[string]any{"type": "OFFER_DRAW", "gameId": created.ID}); err != nil {
+		t.Fatalf("write OFFER_DRAW: %v", err)
+	}
+	if info := readServerMessage(t, tester); info.Type != "INFO" {
+		t.Fatalf("expected an offer in a finished game to be refused, got %+v", info)
+	}
+
+	handler.offersMu.Lock()
+	defer handler.offersMu.Unlock()
+	if _, ok := handler.drawOffers[created.ID]; ok {
+		t.Fatalf("expected no offer stored for a finished game")
+	}
+}
+
+func TestWebSocketAbortLeavesLeaderboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	mockStore := newMockResultStore()
+	handler := NewHandler(manager, gameManager, nil, nil, mockStore)
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "opponent")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+
+	if err := tester.WriteJSON(map[string]any{"type": "ABORT", "gameId": created.ID}); err != nil {
+		t.Fatalf("write ABORT: %v", err)
+	}
+	gameOver := readServerMessage(t, tester)
+	if gameOver.Type != "GAME_OVER" || gameOver.Result != "ABORTED" || gameOver.Reason != "ABORTED" {
+		t.Fatalf("expected aborted game, got %+v", gameOver)
+	}
+
+	if !mockStore.waitForSave(2 * time.Second) {
+		t.Fatalf("persistence did not complete in time")
+	}
+	mockStore.mu.Lock()
+	defer mockStore.mu.Unlock()
+	if len(mockStore.ensures) != 0 || len(mockStore.increments) != 0 {
+		t.Fatalf("expected aborted game to skip the leaderboard, got ensures %v increments %v", mockStore.ensures, mockStore.increments)
+	}
+	if saved := mockStore.saved[0]; saved.Termination != "ABORTED" || saved.IsDraw {
+		t.Fatalf("expected aborted game record, got %+v", saved)
+	}
+}
+
+func TestWebSocketClockAndTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	tester := dialPlayer(t, ts, "tester")
+	opponent := dialPlayer(t, ts, "opponent")
+
+	created, err := gameManager.CreateGame("tester", "opponent", game.DefaultRules(), game.TimeControl{Base: 300 * time.Millisecond, Increment: time.Second})
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+
+	if err := tester.WriteJSON(map[string]any{"type": "MAKE_MOVE", "gameId": created.ID, "col": 3}); err != nil {
+		t.Fatalf("write MAKE_MOVE: %v", err)
+	}
+	update := readServerMessage(t, tester)
+	if update.Type != "BOARD_UPDATE" || len(update.TimeLeft) != 2 {
+		t.Fatalf("expected BOARD_UPDATE with both clocks, got %+v", update)
+	}
+	if update.TimeLeft[0] <= 300 || update.TimeLeft[1] > 300 {
+		t.Fatalf("expected tester to gain the increment and opponent to be on the base, got %v", update.TimeLeft)
+	}
+	_ = readServerMessage(t, opponent) // board update
+
+	// The opponent never moves and loses on time.
+	for conn, want := range map[*websocket.Conn]string{tester: "WIN", opponent: "LOSS"} {
+		gameOver := readServerMessage(t, conn)
+		if gameOver.Type != "GAME_OVER" || gameOver.Result != want || gameOver.Reason != "TIMEOUT" {
+			t.Fatalf("expected %s on time, got %+v", want, gameOver)
+		}
+	}
+}
+
+func TestWebSocketRejectsInvalidTimeControl(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	handler := NewHandler(NewManager(), game.NewManager(), nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	for _, query := range []string{"increment=2", "base=-60", "base=fast"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws?username=tester&"+query, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+func TestWebSocketRejectsInvalidBotChoice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	handler := NewHandler(NewManager(), game.NewManager(), nil, nil, nil)
+	handler.RegisterRoutes(r)
+
+	for _, query := range []string{
+		"username=tester&level=grandmaster",
+		"username=tester&bot=BOT_UNKNOWN",
+		"username=tester&bot=BOT_EASY&level=hard",
+		"username=BOT_MCTS",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws?"+query, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400, got %d", query, w.Code)
+		}
+	}
+}
+
+// stallingStrategy thinks until it is cancelled and reports that it was.
+type stallingStrategy struct {
+	cancelled chan struct{}
+}
+
+func (s stallingStrategy) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	<-ctx.Done()
+	close(s.cancelled)
+	return game.Ply{}, ctx.Err()
+}
+
+func TestWebSocketResignCancelsBotThinking(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	stalling := stallingStrategy{cancelled: make(chan struct{})}
+	registry := bot.NewRegistry()
+	if err := registry.Register(bot.Identity{Name: "BOT_SLOW", Strategy: stalling}); err != nil {
+		t.Fatalf("register bot: %v", err)
+	}
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, bot.New(gameManager, registry), nil)
+	handler.BotMoveTime = time.Minute
+	handler.RegisterRoutes(r)
+
+	created := createGame(t, gameManager, "tester", "BOT_SLOW")
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	conn := dialPlayer(t, ts, "tester")
+	if err := conn.WriteJSON(map[string]any{"type": "MAKE_MOVE", "gameId": created.ID, "col": 3}); err != nil {
+		t.Fatalf("write MAKE_MOVE: %v", err)
+	}
+	_ = readServerMessage(t, conn) // own move
+	if thinking := readServerMessage(t, conn); thinking.Type != "BOT_THINKING" {
+		t.Fatalf("expected BOT_THINKING, got %+v", thinking)
+	}
+
+	if err := conn.WriteJSON(map[string]any{"type": "RESIGN", "gameId": created.ID}); err != nil {
+		t.Fatalf("write RESIGN: %v", err)
+	}
+	if gameOver := readServerMessage(t, conn); gameOver.Type != "GAME_OVER" || gameOver.Result != "LOSS" {
+		t.Fatalf("expected GAME_OVER after resigning, got %+v", gameOver)
+	}
+
+	select {
+	case <-stalling.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the bot to stop thinking once the game ended")
+	}
+}
+
+// slowStrategy plays the first legal column after thinking for delay.
+type slowStrategy struct {
+	delay time.Duration
+}
+
+func (s slowStrategy) ChooseMove(ctx context.Context, pos game.Position, intn func(int) int) (game.Ply, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return game.Ply{}, ctx.Err()
+	}
+	for col := 0; col < game.Columns; col++ {
+		if pos.CanPlay(col) {
+			return game.Ply{Kind: game.DROP, Column: col}, nil
+		}
+	}
+	return game.Ply{}, bot.ErrNoMove
+}
+
+func TestWebSocketBotFinishesTimedMoveAfterDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	registry := bot.NewRegistry()
+	if err := registry.Register(bot.Identity{Name: "BOT_SLOW", Strategy: slowStrategy{delay: 200 * time.Millisecond}}); err != nil {
+		t.Fatalf("register bot: %v", err)
+	}
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, bot.New(gameManager, registry), nil)
+	handler.RegisterRoutes(r)
+
+	created, err := gameManager.CreateGame("tester", "BOT_SLOW", game.DefaultRules(), game.TimeControl{Base: time.Minute})
+	if err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	conn := dialPlayer(t, ts, "tester")
+	if err := conn.WriteJSON(map[string]any{"type": "MAKE_MOVE", "gameId": created.ID, "col": 3}); err != nil {
+		t.Fatalf("write MAKE_MOVE: %v", err)
+	}
+	_ = readServerMessage(t, conn) // own move
+	if thinking := readServerMessage(t, conn); thinking.Type != "BOT_THINKING" {
+		t.Fatalf("expected BOT_THINKING, got %+v", thinking)
+	}
+	_ = conn.Close()
+
+	// Cancelling the bot here would leave its clock running until it lost
+	// on time, so it must still move.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pos, ok := gameManager.PositionOf(created.ID)
+		if !ok {
+			t.Fatalf("game disappeared")
+		}
+		if pos.Moves() == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the bot to finish its move after the player disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if player, _, _ := gameManager.PlayerToMove(created.ID); player != "tester" {
+		t.Fatalf("expected the player to be on move, got %q", player)
+	}
+}
+
+func TestWebSocketHints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	manager := NewManager()
+	gameManager := game.NewManager()
+	handler := NewHandler(manager, gameManager, nil, nil, nil)
+	handler.HintsPerGame = 1
+	handler.HintTime = 100 * time.Millisecond
+	handler.RegisterRoutes(r)
+
+	casual := createGame(t, gameManager, "tester", "opponent")
+	rated := createGame(t, gameManager, "tester", "rival")
+	if err := gameManager.SetRated(rated.ID, true); err != nil {
+		t.Fatalf("set rated: %v", err)
+	}
+
+	ts := httptest.NewServer(r)
+	t.Cleanup(ts.Close)
+	t.Cleanup(func() { manager.Shutdown(context.Background()) })
+
+	conn := dialPlayer(t, ts, "tester")
+
+	if err := conn.WriteJSON(map[string]any{"type": "REQUEST_HINT", "gameId": casual.ID}); err != nil {
+		t.Fatalf("write REQUEST_HINT: %v", err)
+	}
+	hint := readServerMessage(t, conn)
+	if hint.Type != "HINT" || len(hint.Evaluations) != game.Columns || hint.Suggestion == nil {
+		t.Fatalf("expected an evaluation of every column, got %+v", hint)
+	}
+	if hint.HintsLeft == nil || *hint.HintsLeft != 0 {
+		t.Fatalf("expected no hints left, got %v", hint.HintsLeft)
+	}
+	if casual.HintsBy("tester") != 1 {
+		t.Fatalf("expected the hint to be recorded on the game")
+	}
+
+	for _, gameID := range []string{casual.ID, rated.ID} {
+		if err := conn.WriteJSON(map[string]any{"type": "REQUEST_HINT", "gameId": gameID}); err != nil {
+			t.Fatalf("write REQUEST_HINT: %v", err)
+		}
+		if refusal := readServerMessage(t, conn); refusal.Type != "INFO" {
+			t.Fatalf("expected the hint to be refused, got %+v", refusal)
+		}
+	}
+}
+
+// ratingRecorder collects the scores reported for rated games.
+type ratingRecorder struct {
+	mu     sync.Mutex
+	scores []float64
+}
+
+func (r *ratingRecorder) RecordRatedResult(player1, player2 string, score float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scores = append(r.scores, score)
+	return nil
+}
+
+func TestPersistResultUpdatesRatingsForRatedGames(t *testing.T) {
+	results := newMockResultStore()
+	results.wg.Add(3)
+	handler := NewHandler(NewManager(), game.NewManager(), nil, nil, results)
+	ratings := &ratingRecorder{}
+	handler.Ratings = ratings
+
+	winner := "opponent"
+	handler.persistResult(store.CompletedGame{ID: "casual", Player1: "tester", Player2: "opponent", Winner: &winner})
+	handler.persistResult(store.CompletedGame{ID: "loss", Player1: "tester", Player2: "opponent", Winner: &winner, Rated: true})
+	handler.persistResult(store.CompletedGame{ID: "draw", Player1: "tester", Player2: "opponent", IsDraw: true, Rated: true})
+	handler.persistResult(store.CompletedGame{ID: "abort", Player1: "tester", Player2: "opponent", Rated: true, Termination: string(game.TerminationAbort)})
+
+	if len(ratings.scores) != 2 || ratings.scores[0] != 0 || ratings.scores[1] != 0.5 {
+		t.Fatalf("expected a loss and a draw for player one, got %v", ratings.scores)
+	}
+}
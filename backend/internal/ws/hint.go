@@ -0,0 +1,86 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/bot"
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/types"
+)
+
+const (
+	defaultHintsPerGame = 3
+	defaultHintTime     = time.Second
+	// hintDepth caps the hint search; HintTime usually stops it sooner.
+	hintDepth = 16
+)
+
+// handleHintRequest evaluates every move for the requesting player and
+// suggests one. The search runs off the read loop and stops when the player
+// disconnects. The hint is charged up front, so that requests made while a
+// search runs still respect the allowance, and refunded if none can be given.
+func (h *Handler) handleHintRequest(ctx context.Context, conn *Connection, msg types.ClientMessage) error {
+	if msg.GameID == "" {
+		return errors.New("REQUEST_HINT missing gameId")
+	}
+	if h.GameMgr == nil {
+		return errors.New("game manager unavailable")
+	}
+
+	log.Printf("ws: REQUEST_HINT id=%s username=%s gameId=%s", conn.ID, conn.Username, msg.GameID)
+
+	hint, left, err := h.GameMgr.UseHint(msg.GameID, conn.Username, h.HintsPerGame)
+	if err != nil {
+		return err
+	}
+	pos, ok := h.GameMgr.PositionOf(msg.GameID)
+	if !ok {
+		h.GameMgr.RefundHint(msg.GameID, hint)
+		return errors.New("unknown game")
+	}
+
+	go h.sendHint(ctx, conn, msg.GameID, pos, hint, left)
+	return nil
+}
+
+func (h *Handler) sendHint(ctx context.Context, conn *Connection, gameID string, pos game.Position, hint game.Hint, left int) {
+	searchCtx, cancel := context.WithTimeout(ctx, h.HintTime)
+	analysis, err := bot.Analyze(searchCtx, pos, hintDepth)
+	cancel()
+	if err != nil {
+		h.GameMgr.RefundHint(gameID, hint)
+		h.sendInfo(ctx, conn, err.Error())
+		return
+	}
+
+	msg := types.ServerMessage{
+		Type:        "HINT",
+		GameID:      gameID,
+		Evaluations: make([]types.MoveEvaluation, len(analysis.Moves)),
+		HintsLeft:   &left,
+	}
+	for i, eval := range analysis.Moves {
+		msg.Evaluations[i] = moveEvaluation(eval)
+	}
+	suggestion := moveEvaluation(analysis.Best)
+	msg.Suggestion = &suggestion
+
+	sendCtx, cancelSend := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelSend()
+	if err := h.Manager.Send(sendCtx, conn, msg); err != nil {
+		log.Printf("ws: send hint failed id=%s err=%v", conn.ID, err)
+	}
+}
+
+func moveEvaluation(eval bot.MoveEval) types.MoveEvaluation {
+	return types.MoveEvaluation{
+		Col:     eval.Ply.Column,
+		Action:  string(eval.Ply.Kind),
+		Outcome: string(eval.Outcome),
+		Plies:   eval.Plies,
+		Score:   eval.Score,
+	}
+}
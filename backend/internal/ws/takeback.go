@@ -0,0 +1,179 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/types"
+)
+
+const (
+	defaultUndoTimeout      = 15 * time.Second
+	defaultBotTakebackLimit = 3
+)
+
+// undoRequest is a takeback waiting for the opponent's answer.
+type undoRequest struct {
+	requester string
+	timer     *time.Timer
+}
+
+// handleUndoRequest asks the opponent to allow a takeback. Bot games grant it
+// straight away until the player runs out of takebacks.
+func (h *Handler) handleUndoRequest(ctx context.Context, conn *Connection, msg types.ClientMessage) error {
+	if msg.GameID == "" {
+		return errors.New("UNDO_REQUEST missing gameId")
+	}
+	if h.GameMgr == nil {
+		return errors.New("game manager unavailable")
+	}
+
+	gameState, ok := h.GameMgr.GetGame(msg.GameID)
+	if !ok {
+		return errors.New("unknown game")
+	}
+	if err := h.GameMgr.CanUndo(msg.GameID, conn.Username); err != nil {
+		return err
+	}
+
+	log.Printf("ws: UNDO_REQUEST id=%s username=%s gameId=%s", conn.ID, conn.Username, msg.GameID)
+
+	if h.botSeat(gameState) != 0 {
+		return h.grantBotTakeback(ctx, conn, gameState)
+	}
+
+	opponent := gameState.Player1
+	if conn.Username == gameState.Player1 {
+		opponent = gameState.Player2
+	}
+
+	h.offersMu.Lock()
+	if h.pendingUndos == nil {
+		h.pendingUndos = make(map[string]*undoRequest)
+	}
+	if _, pending := h.pendingUndos[msg.GameID]; pending {
+		h.offersMu.Unlock()
+		return errors.New("a takeback request is already pending")
+	}
+	req := &undoRequest{requester: conn.Username}
+	req.timer = time.AfterFunc(h.UndoTimeout, func() { h.expireUndo(gameState, req) })
+	h.pendingUndos[msg.GameID] = req
+	h.offersMu.Unlock()
+
+	sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	prompt := types.ServerMessage{Type: "UNDO_REQUEST", GameID: msg.GameID, Opponent: conn.Username}
+	if err := h.Manager.SendToUsername(sendCtx, opponent, prompt); err != nil {
+		log.Printf("ws: failed to send to %s: %v", opponent, err)
+	}
+	return nil
+}
+
+// handleUndoResponse applies or rejects the pending takeback in the responder's game.
+func (h *Handler) handleUndoResponse(ctx context.Context, conn *Connection, msg types.ClientMessage) error {
+	if msg.GameID == "" {
+		return errors.New("UNDO_RESPONSE missing gameId")
+	}
+	if msg.Accept == nil {
+		return errors.New("UNDO_RESPONSE missing accept")
+	}
+	if h.GameMgr == nil {
+		return errors.New("game manager unavailable")
+	}
+
+	gameState, ok := h.GameMgr.GetGame(msg.GameID)
+	if !ok {
+		return errors.New("unknown game")
+	}
+	if conn.Username != gameState.Player1 && conn.Username != gameState.Player2 {
+		return errors.New("player not part of this game")
+	}
+
+	h.offersMu.Lock()
+	req, ok := h.pendingUndos[msg.GameID]
+	if !ok || req.requester == conn.Username {
+		h.offersMu.Unlock()
+		return errors.New("no takeback request to answer")
+	}
+	req.timer.Stop()
+	delete(h.pendingUndos, msg.GameID)
+	h.offersMu.Unlock()
+
+	log.Printf("ws: UNDO_RESPONSE id=%s username=%s gameId=%s accept=%t", conn.ID, conn.Username, msg.GameID, *msg.Accept)
+
+	if !*msg.Accept {
+		h.sendUndoResult(ctx, gameState, "DECLINED", conn.Username+" declined the takeback")
+		return nil
+	}
+
+	updated, _, err := h.GameMgr.Undo(msg.GameID, req.requester)
+	if err != nil {
+		h.sendUndoResult(ctx, gameState, "DECLINED", err.Error())
+		return nil
+	}
+
+	h.sendUndoResult(ctx, updated, "ACCEPTED", "")
+	h.sendBoardUpdate(ctx, updated)
+	return nil
+}
+
+func (h *Handler) grantBotTakeback(ctx context.Context, conn *Connection, gameState *game.Game) error {
+	if gameState.TakebacksBy(conn.Username) >= h.BotTakebackLimit {
+		h.sendUndoResult(ctx, gameState, "DECLINED", "takeback limit reached")
+		return nil
+	}
+
+	h.cancelBotTurn(gameState.ID)
+	updated, _, err := h.GameMgr.Undo(gameState.ID, conn.Username)
+	if err != nil {
+		h.resumeBotTurn(ctx, gameState.ID, conn.Username)
+		return err
+	}
+
+	h.sendUndoResult(ctx, updated, "ACCEPTED", "")
+	h.sendBoardUpdate(ctx, updated)
+	if h.Bot != nil && updated.CurrentTurn == h.botSeat(updated) {
+		h.startBotTurn(ctx, updated)
+	}
+	return nil
+}
+
+// cancelUndo drops a pending takeback once a move has been played over it.
+func (h *Handler) cancelUndo(ctx context.Context, gameState *game.Game) {
+	h.offersMu.Lock()
+	req, ok := h.pendingUndos[gameState.ID]
+	if ok {
+		req.timer.Stop()
+		delete(h.pendingUndos, gameState.ID)
+	}
+	h.offersMu.Unlock()
+
+	if ok {
+		h.sendUndoResult(ctx, gameState, "CANCELLED", "a move was played")
+	}
+}
+
+// expireUndo declines req if the opponent has not answered in time.
+func (h *Handler) expireUndo(gameState *game.Game, req *undoRequest) {
+	h.offersMu.Lock()
+	if h.pendingUndos[gameState.ID] != req {
+		h.offersMu.Unlock()
+		return
+	}
+	delete(h.pendingUndos, gameState.ID)
+	h.offersMu.Unlock()
+
+	h.sendUndoResult(context.Background(), gameState, "EXPIRED", "the takeback request timed out")
+}
+
+func (h *Handler) sendUndoResult(ctx context.Context, gameState *game.Game, result, message string) {
+	sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	msg := types.ServerMessage{Type: "UNDO_RESULT", GameID: gameState.ID, Result: result, Message: message}
+	h.sendToPlayers(sendCtx, gameState, msg, &msg)
+}
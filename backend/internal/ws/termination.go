@@ -0,0 +1,141 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/example/connect-four/backend/internal/game"
+	"github.com/example/connect-four/backend/internal/types"
+)
+
+// endFunc finishes a game on behalf of a player, such as GameManager.Resign.
+type endFunc func(gm *game.GameManager, gameID, player string) (*game.Game, game.MoveResult, error)
+
+// handleEnd resigns or aborts the sender's game and announces the result.
+func (h *Handler) handleEnd(ctx context.Context, conn *Connection, msg types.ClientMessage, end endFunc) error {
+	if msg.GameID == "" {
+		return errors.New(msg.Type + " missing gameId")
+	}
+	if h.GameMgr == nil {
+		return errors.New("game manager unavailable")
+	}
+
+	log.Printf("ws: %s id=%s username=%s gameId=%s", msg.Type, conn.ID, conn.Username, msg.GameID)
+
+	updatedGame, result, err := end(h.GameMgr, msg.GameID, conn.Username)
+	if err != nil {
+		return err
+	}
+
+	h.cancelUndo(ctx, updatedGame)
+	h.withdrawDrawOffer(updatedGame.ID)
+	h.handleGameOutcome(ctx, updatedGame, result)
+	return nil
+}
+
+// handleOfferDraw passes a draw offer to the opponent. The bot always declines.
+func (h *Handler) handleOfferDraw(ctx context.Context, conn *Connection, msg types.ClientMessage) error {
+	if msg.GameID == "" {
+		return errors.New("OFFER_DRAW missing gameId")
+	}
+	if h.GameMgr == nil {
+		return errors.New("game manager unavailable")
+	}
+
+	gameState, ok := h.GameMgr.GetGame(msg.GameID)
+	if !ok {
+		return errors.New("unknown game")
+	}
+	if conn.Username != gameState.Player1 && conn.Username != gameState.Player2 {
+		return errors.New("player not part of this game")
+	}
+	if gameState.EndedAt != nil {
+		return errors.New("game already finished")
+	}
+
+	log.Printf("ws: OFFER_DRAW id=%s username=%s gameId=%s", conn.ID, conn.Username, msg.GameID)
+
+	sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opponent := gameState.Player1
+	if conn.Username == gameState.Player1 {
+		opponent = gameState.Player2
+	}
+	if h.isBot(opponent) {
+		return h.Manager.Send(sendCtx, conn, types.ServerMessage{Type: "DRAW_DECLINED", GameID: msg.GameID, Opponent: opponent})
+	}
+
+	h.offersMu.Lock()
+	if h.drawOffers == nil {
+		h.drawOffers = make(map[string]string)
+	}
+	h.drawOffers[msg.GameID] = conn.Username
+	h.offersMu.Unlock()
+
+	if err := h.Manager.SendToUsername(sendCtx, opponent, types.ServerMessage{Type: "DRAW_OFFER", GameID: msg.GameID, Opponent: conn.Username}); err != nil {
+		log.Printf("ws: failed to send to %s: %v", opponent, err)
+	}
+	return nil
+}
+
+// handleDrawResponse accepts or declines the opponent's standing draw offer.
+// The offer stands until it is declined or the draw is agreed.
+func (h *Handler) handleDrawResponse(ctx context.Context, conn *Connection, msg types.ClientMessage) error {
+	if msg.GameID == "" {
+		return errors.New(msg.Type + " missing gameId")
+	}
+	if h.GameMgr == nil {
+		return errors.New("game manager unavailable")
+	}
+
+	gameState, ok := h.GameMgr.GetGame(msg.GameID)
+	if !ok {
+		return errors.New("unknown game")
+	}
+	if conn.Username != gameState.Player1 && conn.Username != gameState.Player2 {
+		return errors.New("player not part of this game")
+	}
+
+	h.offersMu.Lock()
+	offerer, ok := h.drawOffers[msg.GameID]
+	if !ok || offerer == conn.Username {
+		h.offersMu.Unlock()
+		return errors.New("no draw offer to answer")
+	}
+	if msg.Type == "DECLINE_DRAW" {
+		delete(h.drawOffers, msg.GameID)
+	}
+	h.offersMu.Unlock()
+
+	log.Printf("ws: %s id=%s username=%s gameId=%s", msg.Type, conn.ID, conn.Username, msg.GameID)
+
+	if msg.Type == "DECLINE_DRAW" {
+		sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+
+		if err := h.Manager.SendToUsername(sendCtx, offerer, types.ServerMessage{Type: "DRAW_DECLINED", GameID: msg.GameID, Opponent: conn.Username}); err != nil {
+			log.Printf("ws: failed to send to %s: %v", offerer, err)
+		}
+		return nil
+	}
+
+	updatedGame, result, err := h.GameMgr.AgreeDraw(msg.GameID, conn.Username)
+	if err != nil {
+		return err
+	}
+
+	h.withdrawDrawOffer(msg.GameID)
+	h.cancelUndo(ctx, updatedGame)
+	h.handleGameOutcome(ctx, updatedGame, result)
+	return nil
+}
+
+// withdrawDrawOffer drops a standing draw offer once the game moves on.
+func (h *Handler) withdrawDrawOffer(gameID string) {
+	h.offersMu.Lock()
+	delete(h.drawOffers, gameID)
+	h.offersMu.Unlock()
+}
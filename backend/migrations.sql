@@ -13,3 +13,23 @@ CREATE TABLE IF NOT EXISTS games (
     started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     ended_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
+
+ALTER TABLE games ADD COLUMN IF NOT EXISTS board_rows INTEGER NOT NULL DEFAULT 6;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS board_columns INTEGER NOT NULL DEFAULT 7;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS connect_length INTEGER NOT NULL DEFAULT 4;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS pop_out BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS winning_cells JSONB NULL;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS termination TEXT NULL;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS base_time_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS increment_ms BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS rated BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS hints JSONB NULL;
+
+CREATE TABLE IF NOT EXISTS game_analyses (
+    game_id TEXT PRIMARY KEY REFERENCES games (id) ON DELETE CASCADE,
+    analysis JSONB NOT NULL,
+    analyzed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE players ADD COLUMN IF NOT EXISTS bot_level TEXT NULL;
+ALTER TABLE players ADD COLUMN IF NOT EXISTS rating INTEGER NOT NULL DEFAULT 1500;
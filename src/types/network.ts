@@ -1,9 +1,68 @@
 export type ClientMessage =
-  | { type: 'MAKE_MOVE'; col: number; gameId: string }
-  | { type: 'RECONNECT'; username: string; gameId?: string };
+  | { type: 'MAKE_MOVE'; col: number; gameId: string; action?: 'DROP' | 'POP' }
+  | { type: 'RECONNECT'; username: string; gameId?: string }
+  | { type: 'UNDO_REQUEST'; gameId: string }
+  | { type: 'UNDO_RESPONSE'; gameId: string; accept: boolean }
+  | { type: 'RESIGN' | 'ABORT' | 'OFFER_DRAW' | 'ACCEPT_DRAW' | 'DECLINE_DRAW' | 'REQUEST_HINT'; gameId: string };
+
+export interface MoveEvaluation {
+  col: number;
+  action: 'DROP' | 'POP';
+  outcome?: 'WIN' | 'LOSS' | 'DRAW';
+  plies?: number;
+  score: number;
+}
 
 export type ServerMessage =
-  | { type: 'GAME_START'; gameId: string; you: 1 | 2; opponent: string }
-  | { type: 'BOARD_UPDATE'; board: number[][]; currentTurn: 1 | 2 }
-  | { type: 'GAME_OVER'; result: 'WIN' | 'LOSS' | 'DRAW'; board: number[][] }
+  | {
+      type: 'GAME_START';
+      gameId: string;
+      you: 1 | 2;
+      opponent: string;
+      rows?: number;
+      columns?: number;
+      connect?: number;
+      popOut?: boolean;
+      baseTime?: number;
+      increment?: number;
+      rated?: boolean;
+      botLevel?: 'easy' | 'medium' | 'hard' | 'expert' | 'mcts';
+    }
+  | {
+      type: 'BOARD_UPDATE';
+      board: number[][];
+      currentTurn: 1 | 2;
+      timeLeft?: [number, number];
+    }
+  | {
+      type: 'GAME_OVER';
+      result: 'WIN' | 'LOSS' | 'DRAW' | 'ABORTED';
+      reason?:
+        | 'CONNECT'
+        | 'BOARD_FULL'
+        | 'REPETITION'
+        | 'RESIGNATION'
+        | 'AGREEMENT'
+        | 'ABORTED'
+        | 'TIMEOUT'
+        | 'FORFEIT';
+      board: number[][];
+      winningCells?: { row: number; col: number }[];
+    }
+  | { type: 'UNDO_REQUEST'; gameId: string; opponent: string }
+  | {
+      type: 'UNDO_RESULT';
+      gameId: string;
+      result: 'ACCEPTED' | 'DECLINED' | 'EXPIRED' | 'CANCELLED';
+      message?: string;
+    }
+  | { type: 'DRAW_OFFER' | 'DRAW_DECLINED'; gameId: string; opponent: string }
+  | { type: 'BOT_THINKING'; gameId: string }
+  | {
+      type: 'HINT';
+      gameId: string;
+      evaluations: MoveEvaluation[];
+      suggestion: MoveEvaluation;
+      hintsLeft: number;
+    }
   | { type: 'INFO'; message: string };